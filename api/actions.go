@@ -0,0 +1,221 @@
+// api/actions.go
+
+package api
+
+import (
+  "database/sql"
+  "log"
+  "net/http"
+  "strconv"
+  "strings"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+)
+
+// actionItemResponse is an action item as returned by the API. The due date is a YYYY-MM-DD day.
+type actionItemResponse struct {
+  ID          int64      `json:"id"`
+  RecordingID int64      `json:"recording_id"`
+  Owner       string     `json:"owner,omitempty"`
+  Description string     `json:"description"`
+  DuePhrase   string     `json:"due_phrase,omitempty"`
+  DueDate     string     `json:"due_date,omitempty"`
+  SourceMs    int64      `json:"source_ms"`
+  Status      string     `json:"status"`
+  RecordedAt  time.Time  `json:"recorded_at"`
+  CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// decisionResponse is a decision as returned by the API.
+type decisionResponse struct {
+  ID          int64     `json:"id"`
+  RecordingID int64     `json:"recording_id"`
+  Description string    `json:"description"`
+  SourceMs    int64     `json:"source_ms"`
+  RecordedAt  time.Time `json:"recorded_at"`
+}
+
+// ActionItemsHandler lists the user's action items across all notes for
+// GET /action-items?status=&owner=&recording_id=&due_before=&limit=&offset=, and completes or
+// reopens one for POST /action-items/{id}/complete and POST /action-items/{id}/reopen.
+// status is open (the default), completed or all; due_before is an RFC 3339 time or YYYY-MM-DD day.
+func ActionItemsHandler(w http.ResponseWriter, r *http.Request) {
+  user, ok := requestUser(w, r)
+  if !ok {
+    return
+  }
+
+  rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/action-items"), "/")
+  if rest == "" {
+    listActionItems(w, r, user.ID)
+    return
+  }
+
+  idPart, action, _ := strings.Cut(rest, "/")
+  var status string
+  switch action {
+  case "complete":
+    status = sqlite.ActionItemCompleted
+  case "reopen":
+    status = sqlite.ActionItemOpen
+  default:
+    http.NotFound(w, r)
+    return
+  }
+  if r.Method != http.MethodPost {
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+  id, err := strconv.ParseInt(idPart, 10, 64)
+  if err != nil {
+    http.Error(w, "Invalid action item ID", http.StatusBadRequest)
+    return
+  }
+
+  if err := sqlite.SetActionItemStatus(user.ID, id, status); err != nil {
+    if err == sql.ErrNoRows {
+      http.NotFound(w, r)
+      return
+    }
+    log.Printf("Failed to update action item %d: %v", id, err)
+    http.Error(w, "Failed to update action item", http.StatusInternalServerError)
+    return
+  }
+
+  item, err := sqlite.GetActionItemByID(user.ID, id)
+  if err != nil {
+    log.Printf("Failed to get action item %d: %v", id, err)
+    http.Error(w, "Failed to get action item", http.StatusInternalServerError)
+    return
+  }
+  writeJSON(w, http.StatusOK, newActionItemResponse(item))
+}
+
+// listActionItems serves GET /action-items.
+func listActionItems(w http.ResponseWriter, r *http.Request, userID int64) {
+  if r.Method != http.MethodGet {
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+
+  params := r.URL.Query()
+  query := sqlite.ActionItemQuery{Owner: strings.TrimSpace(params.Get("owner"))}
+  switch params.Get("status") {
+  case "", sqlite.ActionItemOpen:
+    query.Status = sqlite.ActionItemOpen
+  case sqlite.ActionItemCompleted:
+    query.Status = sqlite.ActionItemCompleted
+  case "all":
+  default:
+    http.Error(w, "Invalid status parameter: expected open, completed or all", http.StatusBadRequest)
+    return
+  }
+
+  var err error
+  if value := params.Get("recording_id"); value != "" {
+    query.RecordingID, err = strconv.ParseInt(value, 10, 64)
+    if err != nil {
+      http.Error(w, "Invalid recording_id parameter", http.StatusBadRequest)
+      return
+    }
+  }
+  if query.DueBefore, err = parseDateParam(params.Get("due_before"), true); err != nil {
+    http.Error(w, "Invalid due_before parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+  if query.Limit, err = parseIntParam(params.Get("limit"), 50, 1, 200); err != nil {
+    http.Error(w, "Invalid limit parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+  if query.Offset, err = parseIntParam(params.Get("offset"), 0, 0, -1); err != nil {
+    http.Error(w, "Invalid offset parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  items, err := sqlite.GetActionItems(userID, query)
+  if err != nil {
+    log.Printf("Failed to get action items: %v", err)
+    http.Error(w, "Failed to get action items", http.StatusInternalServerError)
+    return
+  }
+
+  response := make([]actionItemResponse, 0, len(items))
+  for _, item := range items {
+    response = append(response, newActionItemResponse(item))
+  }
+  writeJSON(w, http.StatusOK, map[string]interface{}{"action_items": response})
+}
+
+// DecisionsHandler lists the decisions made in the user's notes, newest first, for
+// GET /decisions?recording_id=&limit=&offset=.
+func DecisionsHandler(w http.ResponseWriter, r *http.Request) {
+  if r.Method != http.MethodGet {
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+
+  user, ok := requestUser(w, r)
+  if !ok {
+    return
+  }
+
+  params := r.URL.Query()
+  var recordingID int64
+  var err error
+  if value := params.Get("recording_id"); value != "" {
+    recordingID, err = strconv.ParseInt(value, 10, 64)
+    if err != nil {
+      http.Error(w, "Invalid recording_id parameter", http.StatusBadRequest)
+      return
+    }
+  }
+  limit, err := parseIntParam(params.Get("limit"), 50, 1, 200)
+  if err != nil {
+    http.Error(w, "Invalid limit parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+  offset, err := parseIntParam(params.Get("offset"), 0, 0, -1)
+  if err != nil {
+    http.Error(w, "Invalid offset parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  decisions, err := sqlite.GetDecisions(user.ID, recordingID, limit, offset)
+  if err != nil {
+    log.Printf("Failed to get decisions: %v", err)
+    http.Error(w, "Failed to get decisions", http.StatusInternalServerError)
+    return
+  }
+
+  response := make([]decisionResponse, 0, len(decisions))
+  for _, decision := range decisions {
+    response = append(response, decisionResponse{
+      ID:          decision.ID,
+      RecordingID: decision.RecordingID,
+      Description: decision.Description,
+      SourceMs:    decision.SourceMs,
+      RecordedAt:  decision.RecordedAt,
+    })
+  }
+  writeJSON(w, http.StatusOK, map[string]interface{}{"decisions": response})
+}
+
+// newActionItemResponse converts a stored action item.
+func newActionItemResponse(item sqlite.ActionItem) actionItemResponse {
+  response := actionItemResponse{
+    ID:          item.ID,
+    RecordingID: item.RecordingID,
+    Owner:       item.Owner,
+    Description: item.Description,
+    DuePhrase:   item.DuePhrase,
+    SourceMs:    item.SourceMs,
+    Status:      item.Status,
+    RecordedAt:  item.RecordedAt,
+    CompletedAt: item.CompletedAt,
+  }
+  if item.DueDate != nil {
+    response.DueDate = item.DueDate.Format("2006-01-02")
+  }
+  return response
+}
@@ -0,0 +1,61 @@
+// api/ask.go
+
+package api
+
+import (
+  "encoding/json"
+  "log"
+  "net/http"
+  "strings"
+  "unicode/utf8"
+
+  "voice-notetaking-app/service/ask"
+)
+
+// maxQuestionLength caps the length of a question in characters.
+const maxQuestionLength = 1000
+
+// askRequest is the JSON body of POST /ask.
+type askRequest struct {
+  Question string `json:"question"`
+}
+
+// AskHandler answers a question about the user's notes for POST /ask, citing the recordings
+// and timestamps each statement of the answer comes from.
+func AskHandler(answerer *ask.Answerer) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+      http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+      return
+    }
+
+    user, ok := requestUser(w, r)
+    if !ok {
+      return
+    }
+
+    var request askRequest
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&request); err != nil {
+      http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+      return
+    }
+    question := strings.TrimSpace(request.Question)
+    if question == "" {
+      http.Error(w, "Missing question", http.StatusBadRequest)
+      return
+    }
+    if utf8.RuneCountInString(question) > maxQuestionLength {
+      http.Error(w, "Question is too long", http.StatusBadRequest)
+      return
+    }
+
+    answer, err := answerer.Ask(r.Context(), user.ID, question)
+    if err != nil {
+      log.Printf("Failed to answer question: %v", err)
+      http.Error(w, "Failed to answer question", http.StatusInternalServerError)
+      return
+    }
+
+    writeJSON(w, http.StatusOK, answer)
+  }
+}
@@ -0,0 +1,153 @@
+// api/auth.go
+
+package api
+
+import (
+  "database/sql"
+  "errors"
+  "log"
+  "net/http"
+  "strings"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/service/auth"
+)
+
+// APIKeyHeader may carry an API key as an alternative to the Authorization header.
+const APIKeyHeader = "X-API-Key"
+
+// errUnauthenticated is returned when a request carries no usable credentials.
+var errUnauthenticated = errors.New("missing or invalid credentials")
+
+// Authenticator resolves the API key or signed bearer token of each request into its user.
+type Authenticator struct {
+  Tokens   *auth.Signer
+  TokenTTL time.Duration
+
+  // Public lists the paths served without authentication, such as health checks.
+  Public []string
+}
+
+// Middleware rejects unauthenticated requests to every path except the public ones,
+// and stores the user of authenticated requests in the request context.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    for _, path := range a.Public {
+      if r.URL.Path == path {
+        next.ServeHTTP(w, r)
+        return
+      }
+    }
+
+    user, err := a.authenticate(r)
+    if err != nil {
+      if err != errUnauthenticated && err != auth.ErrInvalidToken && err != auth.ErrTokenExpired {
+        log.Printf("Failed to authenticate request: %v", err)
+        http.Error(w, "Failed to authenticate request", http.StatusInternalServerError)
+        return
+      }
+      message := "Unauthorized"
+      if err == auth.ErrTokenExpired {
+        message = "Token expired"
+      }
+      w.Header().Set("WWW-Authenticate", `Bearer realm="voice-notetaking-app"`)
+      http.Error(w, message, http.StatusUnauthorized)
+      return
+    }
+
+    next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+  })
+}
+
+// TokenHandler issues a signed bearer token for POST /auth/token. Tokens can only be
+// requested with an API key, so a leaked token cannot be used to renew itself.
+func (a *Authenticator) TokenHandler(w http.ResponseWriter, r *http.Request) {
+  if r.Method != http.MethodPost {
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+
+  user, ok := requestUser(w, r)
+  if !ok {
+    return
+  }
+  if !auth.IsAPIKey(credential(r)) {
+    http.Error(w, "Tokens must be requested with an API key", http.StatusForbidden)
+    return
+  }
+
+  token, claims, err := a.Tokens.Issue(user.ID, a.TokenTTL)
+  if err != nil {
+    log.Printf("Failed to issue token: %v", err)
+    http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+    return
+  }
+
+  writeJSON(w, http.StatusOK, map[string]interface{}{
+    "token":      token,
+    "token_type": "Bearer",
+    "expires_at": time.Unix(claims.ExpiresAt, 0).UTC(),
+  })
+}
+
+// authenticate resolves the credential of the request into its user.
+func (a *Authenticator) authenticate(r *http.Request) (sqlite.User, error) {
+  cred := credential(r)
+  if cred == "" {
+    return sqlite.User{}, errUnauthenticated
+  }
+
+  var userID int64
+  if auth.IsAPIKey(cred) {
+    key, err := sqlite.GetActiveAPIKeyByHash(auth.HashAPIKey(cred))
+    if err != nil {
+      if err == sql.ErrNoRows {
+        return sqlite.User{}, errUnauthenticated
+      }
+      return sqlite.User{}, err
+    }
+    if err := sqlite.TouchAPIKey(key.ID); err != nil {
+      log.Printf("Failed to record use of API key %d: %v", key.ID, err)
+    }
+    userID = key.UserID
+  } else {
+    claims, err := a.Tokens.Verify(cred)
+    if err != nil {
+      return sqlite.User{}, err
+    }
+    userID = claims.UserID
+  }
+
+  user, err := sqlite.GetUserByID(userID)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return sqlite.User{}, errUnauthenticated
+    }
+    return sqlite.User{}, err
+  }
+
+  return user, nil
+}
+
+// credential returns the bearer credential or API key sent with the request.
+func credential(r *http.Request) string {
+  if header := r.Header.Get("Authorization"); header != "" {
+    scheme, value, ok := strings.Cut(header, " ")
+    if !ok || !strings.EqualFold(scheme, "Bearer") {
+      return ""
+    }
+    return strings.TrimSpace(value)
+  }
+  return strings.TrimSpace(r.Header.Get(APIKeyHeader))
+}
+
+// HealthHandler reports whether the server and its database are up for GET /healthz.
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+  if err := sqlite.Ping(); err != nil {
+    log.Printf("Health check failed: %v", err)
+    writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable"})
+    return
+  }
+  writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
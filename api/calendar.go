@@ -0,0 +1,216 @@
+// api/calendar.go
+
+package api
+
+import (
+  "bytes"
+  "crypto/sha256"
+  "database/sql"
+  "encoding/hex"
+  "fmt"
+  "log"
+  "net/http"
+  "net/url"
+  "strings"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/pkg/timecode"
+  "voice-notetaking-app/service/auth"
+  "voice-notetaking-app/service/calendar"
+)
+
+// maxCalendarItems caps the number of action items written to a calendar.
+const maxCalendarItems = 5000
+
+// calendarRefreshInterval is how often subscribed calendar apps are asked to reload the feed.
+const calendarRefreshInterval = time.Hour
+
+// calendarUIDDomain ends the UID of every event and task, making them globally unique.
+const calendarUIDDomain = "voice-notetaking-app"
+
+// CalendarFeedHandler serves the user's action items as a subscribable iCalendar feed for
+// GET /calendar.ics?token=&include=. The path is public so calendar apps can read it; the
+// feed token issued by POST /calendar/feed identifies the user instead. include is events
+// (dated action items as all-day events), tasks (all action items as to-dos) or all, the default.
+func CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+  if r.Method != http.MethodGet {
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+
+  token := r.URL.Query().Get("token")
+  if !strings.HasPrefix(token, auth.FeedTokenPrefix) {
+    http.Error(w, "Unauthorized", http.StatusUnauthorized)
+    return
+  }
+  user, err := sqlite.GetUserByCalendarFeedToken(auth.HashAPIKey(token))
+  if err != nil {
+    if err == sql.ErrNoRows {
+      http.Error(w, "Unauthorized", http.StatusUnauthorized)
+      return
+    }
+    log.Printf("Failed to get calendar feed user: %v", err)
+    http.Error(w, "Failed to authenticate request", http.StatusInternalServerError)
+    return
+  }
+
+  writeCalendar(w, r, user, false)
+}
+
+// CalendarHandler serves the calendar of the user's action items:
+//
+//	GET    /calendar/download  download the calendar as an .ics file (?include= as for /calendar.ics)
+//	POST   /calendar/feed      issue a feed token and return the /calendar.ics URL to subscribe to,
+//	                           revoking the previous one
+//	DELETE /calendar/feed      revoke the feed token
+func CalendarHandler(w http.ResponseWriter, r *http.Request) {
+  user, ok := requestUser(w, r)
+  if !ok {
+    return
+  }
+
+  switch r.URL.Path {
+  case "/calendar/download":
+    if r.Method != http.MethodGet {
+      http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+      return
+    }
+    writeCalendar(w, r, user, true)
+  case "/calendar/feed":
+    switch r.Method {
+    case http.MethodPost:
+      token, err := auth.GenerateFeedToken()
+      if err != nil {
+        log.Printf("Failed to generate feed token: %v", err)
+        http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+        return
+      }
+      if err := sqlite.SetCalendarFeedToken(user.ID, auth.HashAPIKey(token)); err != nil {
+        log.Printf("Failed to store feed token: %v", err)
+        http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+        return
+      }
+
+      scheme := "http"
+      if r.TLS != nil {
+        scheme = "https"
+      }
+      feedURL := url.URL{Scheme: scheme, Host: r.Host, Path: "/calendar.ics", RawQuery: url.Values{"token": {token}}.Encode()}
+      writeJSON(w, http.StatusCreated, map[string]string{"url": feedURL.String(), "token": token})
+    case http.MethodDelete:
+      if err := sqlite.DeleteCalendarFeedToken(user.ID); err != nil {
+        if err == sql.ErrNoRows {
+          http.NotFound(w, r)
+          return
+        }
+        log.Printf("Failed to revoke feed token: %v", err)
+        http.Error(w, "Failed to revoke calendar feed", http.StatusInternalServerError)
+        return
+      }
+      w.WriteHeader(http.StatusNoContent)
+    default:
+      http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+  default:
+    http.NotFound(w, r)
+  }
+}
+
+// writeCalendar writes the user's calendar, as a file download when attachment is set.
+func writeCalendar(w http.ResponseWriter, r *http.Request, user sqlite.User, attachment bool) {
+  include := r.URL.Query().Get("include")
+  if include == "" {
+    include = "all"
+  }
+  if include != "all" && include != "events" && include != "tasks" {
+    http.Error(w, "Invalid include parameter: expected events, tasks or all", http.StatusBadRequest)
+    return
+  }
+
+  items, err := sqlite.GetActionItems(user.ID, sqlite.ActionItemQuery{Limit: maxCalendarItems})
+  if err != nil {
+    log.Printf("Failed to get action items: %v", err)
+    http.Error(w, "Failed to get action items", http.StatusInternalServerError)
+    return
+  }
+
+  cal := actionItemCalendar(user, items, include != "tasks", include != "events")
+  var body bytes.Buffer
+  if _, err := cal.WriteTo(&body); err != nil {
+    log.Printf("Failed to write calendar: %v", err)
+    http.Error(w, "Failed to write calendar", http.StatusInternalServerError)
+    return
+  }
+
+  w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+  if attachment {
+    w.Header().Set("Content-Disposition", `attachment; filename="action-items.ics"`)
+  }
+  w.WriteHeader(http.StatusOK)
+  w.Write(body.Bytes())
+}
+
+// actionItemCalendar builds a calendar with an all-day event on the due date of each dated action
+// item and a to-do for every action item. UIDs are derived from the item's ID, so re-importing or
+// refreshing the calendar updates entries, even edited ones, instead of duplicating them.
+func actionItemCalendar(user sqlite.User, items []sqlite.ActionItem, events, tasks bool) *calendar.Calendar {
+  cal := &calendar.Calendar{
+    Name:            "Action items of " + user.Name,
+    RefreshInterval: calendarRefreshInterval,
+  }
+
+  for _, item := range items {
+    uid := actionItemUID(item)
+    description := actionItemDescription(item)
+    lastModified := item.UpdatedAt
+
+    if events && item.DueDate != nil {
+      summary := item.Description
+      if item.Status == sqlite.ActionItemCompleted {
+        summary = "✓ " + summary
+      }
+      cal.Events = append(cal.Events, calendar.Event{
+        UID:          uid + "-event@" + calendarUIDDomain,
+        Summary:      summary,
+        Description:  description,
+        Date:         *item.DueDate,
+        LastModified: lastModified,
+      })
+    }
+
+    if tasks {
+      cal.Todos = append(cal.Todos, calendar.Todo{
+        UID:          uid + "-todo@" + calendarUIDDomain,
+        Summary:      item.Description,
+        Description:  description,
+        Due:          item.DueDate,
+        Completed:    item.CompletedAt,
+        Created:      item.CreatedAt,
+        LastModified: lastModified,
+      })
+    }
+  }
+
+  return cal
+}
+
+// actionItemUID identifies an action item by its user and ID, which stay the same when the item is
+// edited. They are hashed into an opaque ID of fixed length, which anyone can compute from them.
+func actionItemUID(item sqlite.ActionItem) string {
+  sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%d", item.UserID, item.ID)))
+  return hex.EncodeToString(sum[:12])
+}
+
+// actionItemDescription describes who owns an action item, when it is due as said and where it was mentioned.
+func actionItemDescription(item sqlite.ActionItem) string {
+  var lines []string
+  if item.Owner != "" {
+    lines = append(lines, "Owner: "+item.Owner)
+  }
+  if item.DuePhrase != "" {
+    lines = append(lines, "Due: "+item.DuePhrase)
+  }
+  lines = append(lines, fmt.Sprintf("From recording %d at %s, recorded %s", item.RecordingID, timecode.Format(item.SourceMs), item.RecordedAt.UTC().Format("Mon Jan 2 2006 15:04 MST")))
+  return strings.Join(lines, "\n")
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+  "testing"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+)
+
+func TestActionItemUID(t *testing.T) {
+  item := sqlite.ActionItem{ID: 4, UserID: 1, Description: "Send the budget"}
+  uid := actionItemUID(item)
+
+  // Editing an action item keeps its UID, so calendars update the event instead of adding another
+  edited := item
+  edited.Description = "Send the revised budget"
+  edited.Status = "done"
+  if got := actionItemUID(edited); got != uid {
+    t.Errorf("UID changed from %s to %s when the action item was edited", uid, got)
+  }
+
+  // Equal descriptions of different action items do not share a UID
+  for _, other := range []sqlite.ActionItem{
+    {ID: 5, UserID: 1, Description: item.Description},
+    {ID: 4, UserID: 2, Description: item.Description},
+  } {
+    if actionItemUID(other) == uid {
+      t.Errorf("action item %d of user %d has the UID of action item 4 of user 1", other.ID, other.UserID)
+    }
+  }
+}
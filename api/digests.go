@@ -0,0 +1,346 @@
+// api/digests.go
+
+package api
+
+import (
+  "database/sql"
+  "encoding/json"
+  "log"
+  "net/http"
+  "strconv"
+  "strings"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/service/digest"
+)
+
+// digestScheduleRequest is the JSON body of POST /digests/schedules.
+type digestScheduleRequest struct {
+  Name     string `json:"name"`
+  Cron     string `json:"cron"`
+  Timezone string `json:"timezone"`
+  Period   string `json:"period"`
+  Sink     string `json:"sink"`
+  Target   string `json:"target"`
+}
+
+// digestScheduleUpdate is the JSON body of PATCH /digests/schedules/{id}.
+type digestScheduleUpdate struct {
+  Enabled *bool `json:"enabled"`
+}
+
+// digestScheduleResponse is a digest schedule as returned by the API.
+type digestScheduleResponse struct {
+  ID        int64      `json:"id"`
+  Name      string     `json:"name"`
+  Cron      string     `json:"cron"`
+  Timezone  string     `json:"timezone"`
+  Period    string     `json:"period"`
+  Sink      string     `json:"sink"`
+  Target    string     `json:"target,omitempty"`
+  Enabled   bool       `json:"enabled"`
+  NextRunAt *time.Time `json:"next_run_at,omitempty"`
+  LastRunAt *time.Time `json:"last_run_at,omitempty"`
+  CreatedAt time.Time  `json:"created_at"`
+}
+
+// digestResponse is a stored digest as returned by the API.
+type digestResponse struct {
+  ID             int64      `json:"id"`
+  ScheduleID     *int64     `json:"schedule_id,omitempty"`
+  Name           string     `json:"name"`
+  From           time.Time  `json:"from"`
+  To             time.Time  `json:"to"`
+  RecordingCount int        `json:"recordings"`
+  Status         string     `json:"status"`
+  Error          string     `json:"error,omitempty"`
+  Summary        string     `json:"summary,omitempty"`
+  Insight        string     `json:"insight,omitempty"`
+  CreatedAt      time.Time  `json:"created_at"`
+  DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// DigestsHandler manages the user's digest schedules and the digests they generated:
+//
+//	GET    /digests                      list the latest digests, without their text (?limit=)
+//	GET    /digests/{id}                 get a digest
+//	GET    /digests/schedules            list schedules
+//	POST   /digests/schedules            create a schedule
+//	GET    /digests/schedules/{id}       get a schedule
+//	PATCH  /digests/schedules/{id}       pause or resume a schedule with {"enabled": bool}
+//	DELETE /digests/schedules/{id}       delete a schedule, keeping its digests
+//	POST   /digests/schedules/{id}/run   generate and deliver a digest for the day or week before now
+func DigestsHandler(scheduler *digest.Scheduler) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    user, ok := requestUser(w, r)
+    if !ok {
+      return
+    }
+
+    rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/digests"), "/")
+    parts := strings.Split(rest, "/")
+    switch {
+    case rest == "":
+      listDigests(w, r, user.ID)
+    case parts[0] != "schedules" && len(parts) == 1:
+      getDigest(w, r, user.ID, parts[0])
+    case parts[0] == "schedules" && len(parts) == 1:
+      digestSchedules(w, r, scheduler, user.ID)
+    case parts[0] == "schedules" && len(parts) == 2:
+      digestSchedule(w, r, scheduler, user.ID, parts[1])
+    case parts[0] == "schedules" && len(parts) == 3 && parts[2] == "run":
+      runDigestSchedule(w, r, scheduler, user.ID, parts[1])
+    default:
+      http.NotFound(w, r)
+    }
+  }
+}
+
+// listDigests serves GET /digests.
+func listDigests(w http.ResponseWriter, r *http.Request, userID int64) {
+  if r.Method != http.MethodGet {
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+  limit, err := parseIntParam(r.URL.Query().Get("limit"), 20, 1, 100)
+  if err != nil {
+    http.Error(w, "Invalid limit parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  digests, err := sqlite.GetDigestsByUserID(userID, limit)
+  if err != nil {
+    log.Printf("Failed to get digests: %v", err)
+    http.Error(w, "Failed to get digests", http.StatusInternalServerError)
+    return
+  }
+
+  response := make([]digestResponse, 0, len(digests))
+  for _, stored := range digests {
+    item := newDigestResponse(stored)
+    item.Summary, item.Insight = "", ""
+    response = append(response, item)
+  }
+  writeJSON(w, http.StatusOK, map[string]interface{}{"digests": response})
+}
+
+// getDigest serves GET /digests/{id}.
+func getDigest(w http.ResponseWriter, r *http.Request, userID int64, idPart string) {
+  if r.Method != http.MethodGet {
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+  id, err := strconv.ParseInt(idPart, 10, 64)
+  if err != nil {
+    http.Error(w, "Invalid digest ID", http.StatusBadRequest)
+    return
+  }
+
+  stored, err := sqlite.GetDigestByID(userID, id)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      http.NotFound(w, r)
+      return
+    }
+    log.Printf("Failed to get digest %d: %v", id, err)
+    http.Error(w, "Failed to get digest", http.StatusInternalServerError)
+    return
+  }
+
+  writeJSON(w, http.StatusOK, newDigestResponse(stored))
+}
+
+// digestSchedules serves GET and POST /digests/schedules.
+func digestSchedules(w http.ResponseWriter, r *http.Request, scheduler *digest.Scheduler, userID int64) {
+  switch r.Method {
+  case http.MethodGet:
+    schedules, err := sqlite.GetDigestSchedulesByUserID(userID)
+    if err != nil {
+      log.Printf("Failed to get digest schedules: %v", err)
+      http.Error(w, "Failed to get digest schedules", http.StatusInternalServerError)
+      return
+    }
+    response := make([]digestScheduleResponse, 0, len(schedules))
+    for _, schedule := range schedules {
+      response = append(response, newDigestScheduleResponse(schedule))
+    }
+    writeJSON(w, http.StatusOK, map[string]interface{}{"schedules": response})
+  case http.MethodPost:
+    var request digestScheduleRequest
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&request); err != nil {
+      http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+      return
+    }
+
+    schedule := sqlite.DigestSchedule{
+      UserID:   userID,
+      Name:     request.Name,
+      Cron:     request.Cron,
+      Timezone: request.Timezone,
+      Period:   request.Period,
+      Sink:     request.Sink,
+      Target:   request.Target,
+      Enabled:  true,
+    }
+    if err := scheduler.Prepare(&schedule, time.Now()); err != nil {
+      http.Error(w, "Invalid schedule: "+err.Error(), http.StatusBadRequest)
+      return
+    }
+
+    id, err := sqlite.InsertDigestSchedule(schedule)
+    if err != nil {
+      log.Printf("Failed to insert digest schedule: %v", err)
+      http.Error(w, "Failed to create digest schedule", http.StatusInternalServerError)
+      return
+    }
+    schedule, err = sqlite.GetDigestScheduleByID(userID, id)
+    if err != nil {
+      log.Printf("Failed to get digest schedule %d: %v", id, err)
+      http.Error(w, "Failed to create digest schedule", http.StatusInternalServerError)
+      return
+    }
+    writeJSON(w, http.StatusCreated, newDigestScheduleResponse(schedule))
+  default:
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+  }
+}
+
+// digestSchedule serves GET, PATCH and DELETE /digests/schedules/{id}.
+func digestSchedule(w http.ResponseWriter, r *http.Request, scheduler *digest.Scheduler, userID int64, idPart string) {
+  schedule, ok := lookupDigestSchedule(w, r, userID, idPart)
+  if !ok {
+    return
+  }
+
+  switch r.Method {
+  case http.MethodGet:
+    writeJSON(w, http.StatusOK, newDigestScheduleResponse(schedule))
+  case http.MethodPatch:
+    var update digestScheduleUpdate
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&update); err != nil {
+      http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+      return
+    }
+    if update.Enabled == nil {
+      http.Error(w, "Missing enabled", http.StatusBadRequest)
+      return
+    }
+
+    // A resumed schedule continues from now rather than catching up on the runs it missed
+    next := schedule.NextRunAt
+    if *update.Enabled && !schedule.Enabled {
+      var err error
+      next, err = digest.NextRun(schedule, time.Now())
+      if err != nil {
+        http.Error(w, "Invalid schedule: "+err.Error(), http.StatusBadRequest)
+        return
+      }
+    }
+    if err := sqlite.SetDigestScheduleEnabled(userID, schedule.ID, *update.Enabled, next); err != nil {
+      log.Printf("Failed to update digest schedule %d: %v", schedule.ID, err)
+      http.Error(w, "Failed to update digest schedule", http.StatusInternalServerError)
+      return
+    }
+    schedule.Enabled, schedule.NextRunAt = *update.Enabled, next
+    writeJSON(w, http.StatusOK, newDigestScheduleResponse(schedule))
+  case http.MethodDelete:
+    if err := sqlite.DeleteDigestSchedule(userID, schedule.ID); err != nil {
+      log.Printf("Failed to delete digest schedule %d: %v", schedule.ID, err)
+      http.Error(w, "Failed to delete digest schedule", http.StatusInternalServerError)
+      return
+    }
+    w.WriteHeader(http.StatusNoContent)
+  default:
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+  }
+}
+
+// runDigestSchedule serves POST /digests/schedules/{id}/run. The digest is returned even when
+// its delivery failed, with the error recorded on it.
+func runDigestSchedule(w http.ResponseWriter, r *http.Request, scheduler *digest.Scheduler, userID int64, idPart string) {
+  if r.Method != http.MethodPost {
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+  schedule, ok := lookupDigestSchedule(w, r, userID, idPart)
+  if !ok {
+    return
+  }
+
+  stored, err := scheduler.Run(r.Context(), schedule, time.Now())
+  if err != nil {
+    log.Printf("Failed to run digest schedule %d: %v", schedule.ID, err)
+    if stored.ID == 0 {
+      http.Error(w, "Failed to generate digest", http.StatusInternalServerError)
+      return
+    }
+  }
+
+  // Reload the digest for the times set by the database
+  if reloaded, err := sqlite.GetDigestByID(userID, stored.ID); err == nil {
+    stored = reloaded
+  }
+  writeJSON(w, http.StatusOK, newDigestResponse(stored))
+}
+
+// lookupDigestSchedule loads the schedule named in the path, writing an error response if it does not exist.
+func lookupDigestSchedule(w http.ResponseWriter, r *http.Request, userID int64, idPart string) (sqlite.DigestSchedule, bool) {
+  id, err := strconv.ParseInt(idPart, 10, 64)
+  if err != nil {
+    http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+    return sqlite.DigestSchedule{}, false
+  }
+
+  schedule, err := sqlite.GetDigestScheduleByID(userID, id)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      http.NotFound(w, r)
+      return sqlite.DigestSchedule{}, false
+    }
+    log.Printf("Failed to get digest schedule %d: %v", id, err)
+    http.Error(w, "Failed to get digest schedule", http.StatusInternalServerError)
+    return sqlite.DigestSchedule{}, false
+  }
+
+  return schedule, true
+}
+
+// newDigestScheduleResponse converts a stored schedule, leaving out the next run of a paused one.
+func newDigestScheduleResponse(schedule sqlite.DigestSchedule) digestScheduleResponse {
+  response := digestScheduleResponse{
+    ID:        schedule.ID,
+    Name:      schedule.Name,
+    Cron:      schedule.Cron,
+    Timezone:  schedule.Timezone,
+    Period:    schedule.Period,
+    Sink:      schedule.Sink,
+    Target:    schedule.Target,
+    Enabled:   schedule.Enabled,
+    LastRunAt: schedule.LastRunAt,
+    CreatedAt: schedule.CreatedAt,
+  }
+  if schedule.Enabled {
+    next := schedule.NextRunAt.UTC()
+    response.NextRunAt = &next
+  }
+  return response
+}
+
+// newDigestResponse converts a stored digest.
+func newDigestResponse(stored sqlite.Digest) digestResponse {
+  return digestResponse{
+    ID:             stored.ID,
+    ScheduleID:     stored.ScheduleID,
+    Name:           stored.Name,
+    From:           stored.PeriodStart.UTC(),
+    To:             stored.PeriodEnd.UTC(),
+    RecordingCount: stored.RecordingCount,
+    Status:         stored.Status,
+    Error:          stored.Error,
+    Summary:        stored.Summary,
+    Insight:        stored.Insight,
+    CreatedAt:      stored.CreatedAt,
+    DeliveredAt:    stored.DeliveredAt,
+  }
+}
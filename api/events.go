@@ -0,0 +1,127 @@
+// api/events.go
+
+package api
+
+import (
+  "database/sql"
+  "fmt"
+  "log"
+  "net/http"
+  "strconv"
+  "strings"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/service/jobs"
+)
+
+// keepAliveInterval is how often a comment is sent on an idle event stream to keep proxies from closing it.
+const keepAliveInterval = 15 * time.Second
+
+// JobEventsHandler streams the progress events of a job as Server-Sent Events for GET /jobs/{id}/events.
+// Clients that reconnect with a Last-Event-ID header receive only the events they missed.
+func JobEventsHandler(manager *jobs.Manager) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+      http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+      return
+    }
+
+    jobID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/events")
+    if jobID == "" || strings.Contains(jobID, "/") {
+      http.NotFound(w, r)
+      return
+    }
+
+    user, ok := requestUser(w, r)
+    if !ok {
+      return
+    }
+
+    if _, err := sqlite.GetUserJobByID(user.ID, jobID); err != nil {
+      if err == sql.ErrNoRows {
+        http.NotFound(w, r)
+        return
+      }
+      log.Printf("Failed to get job: %v", err)
+      http.Error(w, "Failed to get job", http.StatusInternalServerError)
+      return
+    }
+
+    lastEventID, err := parseLastEventID(r)
+    if err != nil {
+      http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+      return
+    }
+
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+      http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+      return
+    }
+
+    // Subscribe before reading stored events so nothing published in between is missed
+    notifications, unsubscribe := manager.Subscribe(jobID)
+    defer unsubscribe()
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("Connection", "keep-alive")
+    w.WriteHeader(http.StatusOK)
+    fmt.Fprintf(w, "retry: %d\n\n", 3000)
+    flusher.Flush()
+
+    keepAlive := time.NewTicker(keepAliveInterval)
+    defer keepAlive.Stop()
+
+    for {
+      events, err := sqlite.GetJobEventsAfter(jobID, lastEventID)
+      if err != nil {
+        log.Printf("Failed to get job events: %v", err)
+        return
+      }
+
+      for _, event := range events {
+        if err := writeEvent(w, event); err != nil {
+          return
+        }
+        lastEventID = event.ID
+        if jobs.IsTerminalEvent(event.Type) {
+          flusher.Flush()
+          return
+        }
+      }
+      flusher.Flush()
+
+      select {
+      case <-r.Context().Done():
+        return
+      case <-notifications:
+      case <-keepAlive.C:
+        if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+          return
+        }
+        flusher.Flush()
+      }
+    }
+  }
+}
+
+// parseLastEventID reads the ID of the last event the client received, from the
+// Last-Event-ID header or, for clients that cannot set headers, the lastEventId query parameter.
+func parseLastEventID(r *http.Request) (int64, error) {
+  value := r.Header.Get("Last-Event-ID")
+  if value == "" {
+    value = r.URL.Query().Get("lastEventId")
+  }
+  if value == "" {
+    return 0, nil
+  }
+  return strconv.ParseInt(value, 10, 64)
+}
+
+// writeEvent writes a single job event in the text/event-stream format.
+func writeEvent(w http.ResponseWriter, event sqlite.JobEvent) error {
+  _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+  return err
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+  "net/http"
+  "net/http/httptest"
+  "path/filepath"
+  "reflect"
+  "regexp"
+  "strconv"
+  "testing"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/service/jobs"
+)
+
+var eventIDPattern = regexp.MustCompile(`(?m)^id: (\d+)$`)
+
+func TestJobEventsHandlerReplay(t *testing.T) {
+  if err := sqlite.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+    t.Fatal(err)
+  }
+  userID, err := sqlite.InsertUser("Ana", "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  otherID, err := sqlite.InsertUser("Ben", "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  if err := sqlite.InsertJob("job", userID, "job.mp3", jobs.StatusCompleted, nil, jobs.StagePending); err != nil {
+    t.Fatal(err)
+  }
+  var ids []string
+  for _, eventType := range []string{jobs.EventQueued, jobs.EventStarted, "stage", jobs.EventCompleted} {
+    id, err := sqlite.InsertJobEvent("job", eventType, "{}")
+    if err != nil {
+      t.Fatal(err)
+    }
+    ids = append(ids, strconv.FormatInt(id, 10))
+  }
+
+  handler := JobEventsHandler(jobs.NewManager(nil, 1, 1, nil))
+  tests := []struct {
+    name   string
+    userID int64
+    header string
+    query  string
+    status int
+    want   []string
+  }{
+    {"all events", userID, "", "", http.StatusOK, ids},
+    {"after header", userID, ids[1], "", http.StatusOK, ids[2:]},
+    {"after query", userID, "", "?lastEventId=" + ids[2], http.StatusOK, ids[3:]},
+    {"header before query", userID, ids[2], "?lastEventId=" + ids[0], http.StatusOK, ids[3:]},
+    {"invalid header", userID, "last", "", http.StatusBadRequest, nil},
+    {"other user", otherID, "", "", http.StatusNotFound, nil},
+  }
+  for _, tt := range tests {
+    r := httptest.NewRequest(http.MethodGet, "/jobs/job/events"+tt.query, nil)
+    r = r.WithContext(WithUser(r.Context(), sqlite.User{ID: tt.userID}))
+    if tt.header != "" {
+      r.Header.Set("Last-Event-ID", tt.header)
+    }
+    w := httptest.NewRecorder()
+
+    // The stream ends by itself once the terminal event is sent
+    handler(w, r)
+    if w.Code != tt.status {
+      t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+      continue
+    }
+    var got []string
+    for _, match := range eventIDPattern.FindAllStringSubmatch(w.Body.String(), -1) {
+      got = append(got, match[1])
+    }
+    if !reflect.DeepEqual(got, tt.want) {
+      t.Errorf("%s: event IDs = %v, want %v", tt.name, got, tt.want)
+    }
+  }
+}
@@ -0,0 +1,342 @@
+// api/graph.go
+
+package api
+
+import (
+  "bytes"
+  "database/sql"
+  "errors"
+  "log"
+  "net/http"
+  "strconv"
+  "strings"
+
+  "voice-notetaking-app/service/graph"
+)
+
+// maxGraphImportSize is the largest graph file accepted for import.
+const maxGraphImportSize = 50 << 20
+
+// graphExcerptLength is how many characters of a node's text are included when listing nodes.
+const graphExcerptLength = 200
+
+// graphNodeResponse is a node of the knowledge graph as returned by the API.
+type graphNodeResponse struct {
+  ID          int64    `json:"id"`
+  RecordingID int64    `json:"recording_id"`
+  Text        string   `json:"text"`
+  Concepts    []string `json:"concepts"`
+}
+
+// graphNeighborResponse is a node connected to another, with the weight of the edge and the concepts they share.
+type graphNeighborResponse struct {
+  graphNodeResponse
+  Weight         float64  `json:"weight"`
+  SharedConcepts []string `json:"shared_concepts"`
+}
+
+// graphLinkResponse is an edge followed by a path.
+type graphLinkResponse struct {
+  SourceID       int64    `json:"source_id"`
+  TargetID       int64    `json:"target_id"`
+  Weight         float64  `json:"weight"`
+  SharedConcepts []string `json:"shared_concepts"`
+}
+
+// GraphHandler queries the user's knowledge graph, where each node is a processed note:
+//   - GET /graph/nodes/{id} returns a node with its full text, and DELETE /graph/nodes/{id} deletes it with its edges
+//   - GET /graph/nodes/{id}/neighbors?limit=&min_weight= lists the nodes sharing an edge with it, strongest first
+//   - GET /graph/concepts/{concept} lists the nodes linked by the concept
+//   - GET /graph/path?from=&to=&mode=shortest|strongest finds a path between two nodes
+//   - GET /graph/export?format=graphml|gexf|dot|jgf&concept=&min_weight= downloads the graph for other tools
+//   - POST /graph/import?format=jgf|graphml&dry_run= merges the graph file in the body, or with dry_run=true
+//     only reports what would be merged and the conflicts found. The format is detected when omitted.
+//
+// Shortest paths have the fewest edges; strongest paths have the highest product of edge weights.
+func GraphHandler(graphs *graph.UserGraphs) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    user, ok := requestUser(w, r)
+    if !ok {
+      return
+    }
+
+    userGraph, err := graphs.Get(user.ID)
+    if err != nil {
+      log.Printf("Failed to load graph of user %d: %v", user.ID, err)
+      http.Error(w, "Failed to load graph", http.StatusInternalServerError)
+      return
+    }
+
+    parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/graph"), "/"), "/")
+    if r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == "nodes" {
+      deleteGraphNode(w, r, userGraph, parts[1])
+      return
+    }
+    if len(parts) == 1 && parts[0] == "import" {
+      importGraph(w, r, userGraph)
+      return
+    }
+    if r.Method != http.MethodGet {
+      http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+      return
+    }
+
+    switch {
+    case len(parts) == 2 && parts[0] == "nodes":
+      getGraphNode(w, r, userGraph, parts[1])
+    case len(parts) == 3 && parts[0] == "nodes" && parts[2] == "neighbors":
+      listGraphNeighbors(w, r, userGraph, parts[1])
+    case len(parts) == 2 && parts[0] == "concepts" && parts[1] != "":
+      listGraphConceptNodes(w, userGraph, parts[1])
+    case len(parts) == 1 && parts[0] == "path":
+      findGraphPath(w, r, userGraph)
+    case len(parts) == 1 && parts[0] == "export":
+      exportGraph(w, r, userGraph)
+    default:
+      http.NotFound(w, r)
+    }
+  }
+}
+
+// getGraphNode serves GET /graph/nodes/{id}.
+func getGraphNode(w http.ResponseWriter, r *http.Request, userGraph *graph.Graph, idPart string) {
+  id, err := strconv.ParseInt(idPart, 10, 64)
+  if err != nil {
+    http.Error(w, "Invalid node ID", http.StatusBadRequest)
+    return
+  }
+
+  node, ok := userGraph.Node(id)
+  if !ok {
+    http.NotFound(w, r)
+    return
+  }
+  writeJSON(w, http.StatusOK, newGraphNodeResponse(node, false))
+}
+
+// deleteGraphNode serves DELETE /graph/nodes/{id}.
+func deleteGraphNode(w http.ResponseWriter, r *http.Request, userGraph *graph.Graph, idPart string) {
+  id, err := strconv.ParseInt(idPart, 10, 64)
+  if err != nil {
+    http.Error(w, "Invalid node ID", http.StatusBadRequest)
+    return
+  }
+
+  if err := graph.DeleteNode(userGraph, id); err != nil {
+    if err == sql.ErrNoRows {
+      http.NotFound(w, r)
+      return
+    }
+    log.Printf("Failed to delete node %d: %v", id, err)
+    http.Error(w, "Failed to delete node", http.StatusInternalServerError)
+    return
+  }
+  w.WriteHeader(http.StatusNoContent)
+}
+
+// listGraphNeighbors serves GET /graph/nodes/{id}/neighbors.
+func listGraphNeighbors(w http.ResponseWriter, r *http.Request, userGraph *graph.Graph, idPart string) {
+  id, err := strconv.ParseInt(idPart, 10, 64)
+  if err != nil {
+    http.Error(w, "Invalid node ID", http.StatusBadRequest)
+    return
+  }
+
+  params := r.URL.Query()
+  limit, err := parseIntParam(params.Get("limit"), 20, 1, 200)
+  if err != nil {
+    http.Error(w, "Invalid limit parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+  minWeight, err := parseWeightParam(params.Get("min_weight"))
+  if err != nil {
+    http.Error(w, "Invalid min_weight parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  neighbors, err := userGraph.Neighbors(id)
+  if err == graph.ErrNodeNotFound {
+    http.NotFound(w, r)
+    return
+  }
+
+  response := make([]graphNeighborResponse, 0, limit)
+  for _, neighbor := range neighbors {
+    if neighbor.Weight < minWeight || len(response) == limit {
+      break
+    }
+    response = append(response, graphNeighborResponse{
+      graphNodeResponse: newGraphNodeResponse(neighbor.Node, true),
+      Weight:            neighbor.Weight,
+      SharedConcepts:    nonNilStrings(neighbor.Concepts),
+    })
+  }
+  writeJSON(w, http.StatusOK, map[string]interface{}{"node_id": id, "neighbors": response})
+}
+
+// listGraphConceptNodes serves GET /graph/concepts/{concept}.
+func listGraphConceptNodes(w http.ResponseWriter, userGraph *graph.Graph, concept string) {
+  nodes := userGraph.NodesByConcept(concept)
+  response := make([]graphNodeResponse, 0, len(nodes))
+  for _, node := range nodes {
+    response = append(response, newGraphNodeResponse(node, true))
+  }
+  writeJSON(w, http.StatusOK, map[string]interface{}{"concept": concept, "nodes": response})
+}
+
+// findGraphPath serves GET /graph/path.
+func findGraphPath(w http.ResponseWriter, r *http.Request, userGraph *graph.Graph) {
+  params := r.URL.Query()
+  from, err := strconv.ParseInt(params.Get("from"), 10, 64)
+  if err != nil {
+    http.Error(w, "Invalid from parameter: expected a node ID", http.StatusBadRequest)
+    return
+  }
+  to, err := strconv.ParseInt(params.Get("to"), 10, 64)
+  if err != nil {
+    http.Error(w, "Invalid to parameter: expected a node ID", http.StatusBadRequest)
+    return
+  }
+  mode := params.Get("mode")
+  if mode == "" {
+    mode = graph.PathShortest
+  }
+  if mode != graph.PathShortest && mode != graph.PathStrongest {
+    http.Error(w, "Invalid mode parameter: expected shortest or strongest", http.StatusBadRequest)
+    return
+  }
+
+  path, found, err := userGraph.FindPath(from, to, mode)
+  if err == graph.ErrNodeNotFound {
+    http.NotFound(w, r)
+    return
+  }
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusBadRequest)
+    return
+  }
+  if !found {
+    writeJSON(w, http.StatusOK, map[string]interface{}{"from": from, "to": to, "mode": mode, "found": false})
+    return
+  }
+
+  nodes := make([]graphNodeResponse, 0, len(path.Nodes))
+  for _, node := range path.Nodes {
+    nodes = append(nodes, newGraphNodeResponse(node, true))
+  }
+  links := make([]graphLinkResponse, 0, len(path.Links))
+  for _, link := range path.Links {
+    links = append(links, graphLinkResponse{
+      SourceID:       link.SourceID,
+      TargetID:       link.TargetID,
+      Weight:         link.Weight,
+      SharedConcepts: nonNilStrings(link.Concepts),
+    })
+  }
+  writeJSON(w, http.StatusOK, map[string]interface{}{
+    "from":     from,
+    "to":       to,
+    "mode":     mode,
+    "found":    true,
+    "hops":     len(links),
+    "strength": path.Strength,
+    "nodes":    nodes,
+    "links":    links,
+  })
+}
+
+// exportGraph serves GET /graph/export.
+func exportGraph(w http.ResponseWriter, r *http.Request, userGraph *graph.Graph) {
+  params := r.URL.Query()
+  format, ok := graph.LookupFormat(params.Get("format"))
+  if !ok {
+    http.Error(w, "Invalid format parameter: expected one of "+strings.Join(graph.FormatNames(), ", "), http.StatusBadRequest)
+    return
+  }
+  minWeight, err := parseWeightParam(params.Get("min_weight"))
+  if err != nil {
+    http.Error(w, "Invalid min_weight parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+  filter := graph.Filter{Concept: strings.TrimSpace(params.Get("concept")), MinWeight: minWeight}
+
+  // Encode the whole file first, so an error can still be reported with a proper status
+  var body bytes.Buffer
+  if err := format.Export(&body, userGraph, filter); err != nil {
+    log.Printf("Failed to export graph of user %d: %v", userGraph.UserID, err)
+    http.Error(w, "Failed to export graph", http.StatusInternalServerError)
+    return
+  }
+
+  w.Header().Set("Content-Type", format.ContentType)
+  w.Header().Set("Content-Disposition", `attachment; filename="knowledge-graph`+format.Extension+`"`)
+  w.Write(body.Bytes())
+}
+
+// importGraph serves POST /graph/import.
+func importGraph(w http.ResponseWriter, r *http.Request, userGraph *graph.Graph) {
+  if r.Method != http.MethodPost {
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+
+  params := r.URL.Query()
+  dryRun := false
+  if value := params.Get("dry_run"); value != "" {
+    var err error
+    if dryRun, err = strconv.ParseBool(value); err != nil {
+      http.Error(w, "Invalid dry_run parameter: expected true or false", http.StatusBadRequest)
+      return
+    }
+  }
+
+  file, err := graph.ReadImportFile(http.MaxBytesReader(w, r.Body, maxGraphImportSize), params.Get("format"))
+  if err != nil {
+    http.Error(w, "Invalid graph file: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  report, err := graph.Import(userGraph, file, dryRun)
+  if err != nil {
+    log.Printf("Failed to import graph of user %d: %v", userGraph.UserID, err)
+    http.Error(w, "Failed to import graph", http.StatusInternalServerError)
+    return
+  }
+  writeJSON(w, http.StatusOK, report)
+}
+
+// parseWeightParam parses an edge weight between 0 and 1, defaulting to 0.
+func parseWeightParam(value string) (float64, error) {
+  if value == "" {
+    return 0, nil
+  }
+  weight, err := strconv.ParseFloat(value, 64)
+  if err != nil || weight < 0 || weight > 1 {
+    return 0, errors.New("expected a number between 0 and 1")
+  }
+  return weight, nil
+}
+
+// newGraphNodeResponse converts a node for the API, cutting its text down to an excerpt when listing nodes.
+func newGraphNodeResponse(node graph.Node, excerpt bool) graphNodeResponse {
+  text := node.Text
+  if excerpt {
+    if runes := []rune(text); len(runes) > graphExcerptLength {
+      text = strings.TrimSpace(string(runes[:graphExcerptLength])) + "…"
+    }
+  }
+  return graphNodeResponse{
+    ID:          node.ID,
+    RecordingID: node.RecordingID,
+    Text:        text,
+    Concepts:    nonNilStrings(node.Concepts),
+  }
+}
+
+// nonNilStrings returns an empty slice in place of nil, so it is encoded as an empty JSON array.
+func nonNilStrings(values []string) []string {
+  if values == nil {
+    return []string{}
+  }
+  return values
+}
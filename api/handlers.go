@@ -3,10 +3,16 @@
 package api
 
 import (
+  "database/sql"
   "encoding/json"
   "io"
+  "log"
   "net/http"
-  "github.com/yourusername/mare/service/speechtotext"
+  "strconv"
+  "strings"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/service/speechtotext"
 )
 
 // UploadVoiceNoteHandler handles voice note uploads.
@@ -34,16 +40,81 @@ func UploadVoiceNoteHandler(w http.ResponseWriter, r *http.Request) {
   }
 
   // Convert audio to text using AssemblyAI
-  transcription, err := speechtotext.UploadToAssemblyAI(fileBytes)
+  transcript, err := speechtotext.UploadToAssemblyAI(fileBytes)
   if err != nil {
     http.Error(w, "Failed to convert speech to text", http.StatusInternalServerError)
     return
   }
 
   // Return transcription as JSON response
-  response := map[string]string{
-    "transcription": transcription,
+  response := map[string]interface{}{
+    "transcription": transcript.Text,
+    "segments":      transcript.Segments,
+    "utterances":    transcript.Utterances,
+  }
+  writeJSON(w, http.StatusOK, response)
+}
+
+// RecordingTranscriptHandler returns the timed segments of a recording for GET /recordings/{id}/transcript.
+func RecordingTranscriptHandler(w http.ResponseWriter, r *http.Request) {
+  if r.Method != http.MethodGet {
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+
+  rest := strings.TrimPrefix(r.URL.Path, "/recordings/")
+  idPart, suffix, _ := strings.Cut(rest, "/")
+  if suffix != "transcript" {
+    http.NotFound(w, r)
+    return
   }
+  recordingID, err := strconv.ParseInt(idPart, 10, 64)
+  if err != nil {
+    http.Error(w, "Invalid recording ID", http.StatusBadRequest)
+    return
+  }
+
+  user, ok := requestUser(w, r)
+  if !ok {
+    return
+  }
+
+  if _, _, err := sqlite.GetRecordingByID(user.ID, recordingID); err != nil {
+    if err == sql.ErrNoRows {
+      http.NotFound(w, r)
+      return
+    }
+    log.Printf("Failed to get recording: %v", err)
+    http.Error(w, "Failed to get recording", http.StatusInternalServerError)
+    return
+  }
+
+  segments, err := sqlite.GetSegmentsByRecordingID(recordingID)
+  if err != nil {
+    log.Printf("Failed to get transcript segments: %v", err)
+    http.Error(w, "Failed to get transcript segments", http.StatusInternalServerError)
+    return
+  }
+
+  utterances, err := sqlite.GetUtterancesByRecordingID(recordingID)
+  if err != nil {
+    log.Printf("Failed to get utterances: %v", err)
+    http.Error(w, "Failed to get utterances", http.StatusInternalServerError)
+    return
+  }
+
+  writeJSON(w, http.StatusOK, map[string]interface{}{
+    "recording_id": recordingID,
+    "segments":     segments,
+    "utterances":   utterances,
+  })
+}
+
+// writeJSON writes the value as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
   w.Header().Set("Content-Type", "application/json")
-  json.NewEncoder(w).Encode(response)
+  w.WriteHeader(status)
+  if err := json.NewEncoder(w).Encode(value); err != nil {
+    log.Printf("Failed to encode JSON response: %v", err)
+  }
 }
@@ -0,0 +1,143 @@
+// api/insights.go
+
+package api
+
+import (
+  "crypto/sha256"
+  "database/sql"
+  "encoding/hex"
+  "fmt"
+  "log"
+  "net/http"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/service/insight"
+)
+
+// defaultInsightDays is the length of the range covered when from is not given.
+const defaultInsightDays = 7
+
+// maxInsightDays is the length of the longest range insights are generated over.
+const maxInsightDays = 366
+
+// InsightsHandler generates insights over the user's recordings in a time range for
+// GET /insights?from=&to=. Dates are RFC 3339 times or YYYY-MM-DD days; a day passed as to
+// includes the whole day. The range defaults to the last seven days including today and may
+// span at most a year.
+// Insights are cached per user and range, and regenerated when its recordings change.
+func InsightsHandler(generator *insight.Generator) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+      http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+      return
+    }
+
+    user, ok := requestUser(w, r)
+    if !ok {
+      return
+    }
+
+    params := r.URL.Query()
+    to, err := parseDateParam(params.Get("to"), true)
+    if err != nil {
+      http.Error(w, "Invalid to parameter: "+err.Error(), http.StatusBadRequest)
+      return
+    }
+    if to.IsZero() {
+      // Round up to the end of today so that repeated requests share a cache entry
+      to = time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+    }
+    from, err := parseDateParam(params.Get("from"), false)
+    if err != nil {
+      http.Error(w, "Invalid from parameter: "+err.Error(), http.StatusBadRequest)
+      return
+    }
+    if from.IsZero() {
+      from = to.AddDate(0, 0, -defaultInsightDays)
+    }
+    if !from.Before(to) {
+      http.Error(w, "from must be before to", http.StatusBadRequest)
+      return
+    }
+    if to.Sub(from) > maxInsightDays*24*time.Hour {
+      http.Error(w, fmt.Sprintf("from and to must be at most %d days apart", maxInsightDays), http.StatusBadRequest)
+      return
+    }
+
+    recordings, err := sqlite.GetRecordingsByTimeRange(user.ID, from, to)
+    if err != nil {
+      log.Printf("Failed to get recordings: %v", err)
+      http.Error(w, "Failed to get recordings", http.StatusInternalServerError)
+      return
+    }
+
+    response := map[string]interface{}{
+      "from":       from.UTC(),
+      "to":         to.UTC(),
+      "recordings": len(recordings),
+    }
+    if len(recordings) == 0 {
+      response["insight"] = ""
+      response["cached"] = false
+      writeJSON(w, http.StatusOK, response)
+      return
+    }
+
+    fingerprint := recordingsFingerprint(recordings)
+    cached, err := sqlite.GetCachedInsight(user.ID, from, to)
+    if err != nil && err != sql.ErrNoRows {
+      log.Printf("Failed to get cached insight: %v", err)
+    }
+    if err == nil && cached.Fingerprint == fingerprint {
+      response["insight"] = cached.Insight
+      response["cached"] = true
+      response["generated_at"] = cached.CreatedAt
+      writeJSON(w, http.StatusOK, response)
+      return
+    }
+
+    notes := make([]insight.Note, 0, len(recordings))
+    for _, recording := range recordings {
+      // Summaries are shorter and already condensed; fall back to the transcription without one
+      text := recording.Summary
+      if text == "" {
+        text = recording.Transcription
+      }
+      notes = append(notes, insight.Note{RecordedAt: recording.CreatedAt, Text: text})
+    }
+
+    text, err := generator.GenerateInsightByTime(r.Context(), notes, from, to)
+    if err != nil {
+      log.Printf("Failed to generate insight: %v", err)
+      http.Error(w, "Failed to generate insight", http.StatusBadGateway)
+      return
+    }
+
+    err = sqlite.SaveCachedInsight(sqlite.CachedInsight{
+      UserID:         user.ID,
+      From:           from,
+      To:             to,
+      Fingerprint:    fingerprint,
+      RecordingCount: len(recordings),
+      Insight:        text,
+    })
+    if err != nil {
+      log.Printf("Failed to cache insight: %v", err)
+    }
+
+    response["insight"] = text
+    response["cached"] = false
+    response["generated_at"] = time.Now().UTC()
+    writeJSON(w, http.StatusOK, response)
+  }
+}
+
+// recordingsFingerprint identifies a set of recordings and the last time each was updated.
+func recordingsFingerprint(recordings []sqlite.Recording) string {
+  hash := sha256.New()
+  for _, recording := range recordings {
+    fmt.Fprintf(hash, "%d:%d\n", recording.ID, recording.UpdatedAt.UnixNano())
+  }
+  return hex.EncodeToString(hash.Sum(nil))
+}
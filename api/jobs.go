@@ -0,0 +1,113 @@
+// api/jobs.go
+
+package api
+
+import (
+  "database/sql"
+  "encoding/json"
+  "log"
+  "net/http"
+  "strings"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/service/jobs"
+)
+
+// jobStatusResponse is the JSON body returned for GET /jobs/{id}.
+type jobStatusResponse struct {
+  ID          string             `json:"id"`
+  Status      string             `json:"status"`
+  Error       string             `json:"error,omitempty"`
+  RecordingID int64              `json:"recording_id,omitempty"`
+  Progress    jobProgress        `json:"progress"`
+  Stages      []jobStageResponse `json:"stages"`
+  CreatedAt   time.Time          `json:"created_at"`
+  UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// jobStageResponse is the state of a single stage, with its stored result embedded as JSON.
+type jobStageResponse struct {
+  Name       string          `json:"name"`
+  Status     string          `json:"status"`
+  Error      string          `json:"error,omitempty"`
+  Result     json.RawMessage `json:"result,omitempty"`
+  StartedAt  *time.Time      `json:"started_at,omitempty"`
+  FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// jobProgress counts the stages of a job that have finished, in any final state.
+type jobProgress struct {
+  Finished int `json:"finished"`
+  Total    int `json:"total"`
+}
+
+// JobsHandler serves GET /jobs/{id} and GET /jobs/{id}/events for the jobs of the manager.
+func JobsHandler(manager *jobs.Manager) http.HandlerFunc {
+  events := JobEventsHandler(manager)
+  return func(w http.ResponseWriter, r *http.Request) {
+    if strings.HasSuffix(r.URL.Path, "/events") {
+      events(w, r)
+      return
+    }
+    JobStatusHandler(w, r)
+  }
+}
+
+// JobStatusHandler reports the state of an upload job and each of its stages for GET /jobs/{id}.
+func JobStatusHandler(w http.ResponseWriter, r *http.Request) {
+  if r.Method != http.MethodGet {
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+
+  jobID := strings.TrimPrefix(r.URL.Path, "/jobs/")
+  if jobID == "" || strings.Contains(jobID, "/") {
+    http.NotFound(w, r)
+    return
+  }
+
+  user, ok := requestUser(w, r)
+  if !ok {
+    return
+  }
+
+  job, err := sqlite.GetUserJobByID(user.ID, jobID)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      http.NotFound(w, r)
+      return
+    }
+    log.Printf("Failed to get job: %v", err)
+    http.Error(w, "Failed to get job", http.StatusInternalServerError)
+    return
+  }
+
+  response := jobStatusResponse{
+    ID:          job.ID,
+    Status:      job.Status,
+    Error:       job.Error,
+    RecordingID: job.RecordingID,
+    CreatedAt:   job.CreatedAt,
+    UpdatedAt:   job.UpdatedAt,
+  }
+  response.Progress.Total = len(job.Stages)
+  for _, stage := range job.Stages {
+    if stage.FinishedAt != nil {
+      response.Progress.Finished++
+    }
+    stageResponse := jobStageResponse{
+      Name:       stage.Name,
+      Status:     stage.Status,
+      Error:      stage.Error,
+      StartedAt:  stage.StartedAt,
+      FinishedAt: stage.FinishedAt,
+    }
+    if stage.Result != "" {
+      stageResponse.Result = json.RawMessage(stage.Result)
+    }
+    response.Stages = append(response.Stages, stageResponse)
+  }
+
+  writeJSON(w, http.StatusOK, response)
+}
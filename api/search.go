@@ -0,0 +1,110 @@
+// api/search.go
+
+package api
+
+import (
+  "fmt"
+  "log"
+  "net/http"
+  "strconv"
+  "strings"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+)
+
+// maxSearchLimit caps the number of results returned by a single search request.
+const maxSearchLimit = 100
+
+// SearchHandler runs a ranked full-text search over the user's recordings for
+// GET /search?q=&from=&to=&tag=&limit=&offset=. Dates are RFC 3339 times or YYYY-MM-DD days;
+// a day passed as to includes the whole day.
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+  if r.Method != http.MethodGet {
+    http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+
+  user, ok := requestUser(w, r)
+  if !ok {
+    return
+  }
+
+  params := r.URL.Query()
+  query := sqlite.SearchQuery{
+    Text: strings.TrimSpace(params.Get("q")),
+    Tag:  strings.ToLower(strings.TrimSpace(params.Get("tag"))),
+  }
+  if query.Text == "" {
+    http.Error(w, "Missing q parameter", http.StatusBadRequest)
+    return
+  }
+
+  var err error
+  if query.From, err = parseDateParam(params.Get("from"), false); err != nil {
+    http.Error(w, "Invalid from parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+  if query.To, err = parseDateParam(params.Get("to"), true); err != nil {
+    http.Error(w, "Invalid to parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+  if query.Limit, err = parseIntParam(params.Get("limit"), 20, 1, maxSearchLimit); err != nil {
+    http.Error(w, "Invalid limit parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+  if query.Offset, err = parseIntParam(params.Get("offset"), 0, 0, -1); err != nil {
+    http.Error(w, "Invalid offset parameter: "+err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  results, err := sqlite.SearchRecordings(user.ID, query)
+  if err != nil {
+    log.Printf("Failed to search recordings: %v", err)
+    http.Error(w, "Failed to search recordings", http.StatusInternalServerError)
+    return
+  }
+  if results == nil {
+    results = []sqlite.SearchResult{}
+  }
+
+  writeJSON(w, http.StatusOK, map[string]interface{}{
+    "query":   query.Text,
+    "results": results,
+  })
+}
+
+// parseDateParam parses an RFC 3339 time or a YYYY-MM-DD day, returning the zero time for an
+// empty value. With endOfDay set, a day is turned into the start of the following day.
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+  if value == "" {
+    return time.Time{}, nil
+  }
+  if t, err := time.Parse(time.RFC3339, value); err == nil {
+    return t, nil
+  }
+  day, err := time.Parse("2006-01-02", value)
+  if err != nil {
+    return time.Time{}, fmt.Errorf("expected an RFC 3339 time or YYYY-MM-DD")
+  }
+  if endOfDay {
+    day = day.AddDate(0, 0, 1)
+  }
+  return day, nil
+}
+
+// parseIntParam parses an integer parameter within [min, max], where a negative max means no
+// upper bound, returning the fallback for an empty value.
+func parseIntParam(value string, fallback, min, max int) (int, error) {
+  if value == "" {
+    return fallback, nil
+  }
+  n, err := strconv.Atoi(value)
+  if err != nil || n < min || (max >= 0 && n > max) {
+    if max >= 0 {
+      return 0, fmt.Errorf("expected a number between %d and %d", min, max)
+    }
+    return 0, fmt.Errorf("expected a number of at least %d", min)
+  }
+  return n, nil
+}
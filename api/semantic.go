@@ -0,0 +1,66 @@
+// api/semantic.go
+
+package api
+
+import (
+  "log"
+  "net/http"
+  "strings"
+
+  "voice-notetaking-app/service/semantic"
+)
+
+// maxSemanticResults caps the number of passages returned by a single semantic search.
+const maxSemanticResults = 50
+
+// SemanticSearchHandler finds the passages of the user's recordings closest in meaning to the
+// query for GET /search/semantic?q=&k=&mode=. The mode is "approx" (default) for the HNSW index
+// or "exact" to compare the query with every passage.
+func SemanticSearchHandler(searcher *semantic.Searcher) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+      http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+      return
+    }
+
+    user, ok := requestUser(w, r)
+    if !ok {
+      return
+    }
+
+    params := r.URL.Query()
+    query := strings.TrimSpace(params.Get("q"))
+    if query == "" {
+      http.Error(w, "Missing q parameter", http.StatusBadRequest)
+      return
+    }
+    k, err := parseIntParam(params.Get("k"), 10, 1, maxSemanticResults)
+    if err != nil {
+      http.Error(w, "Invalid k parameter: "+err.Error(), http.StatusBadRequest)
+      return
+    }
+    mode := params.Get("mode")
+    switch mode {
+    case "":
+      mode = "approx"
+    case "approx", "exact":
+    default:
+      http.Error(w, "Invalid mode parameter: expected approx or exact", http.StatusBadRequest)
+      return
+    }
+
+    results, err := searcher.Search(r.Context(), user.ID, query, k, mode == "exact")
+    if err != nil {
+      log.Printf("Failed to run semantic search: %v", err)
+      http.Error(w, "Failed to run semantic search", http.StatusInternalServerError)
+      return
+    }
+
+    writeJSON(w, http.StatusOK, map[string]interface{}{
+      "query":   query,
+      "mode":    mode,
+      "model":   searcher.Embedder.Model(),
+      "results": results,
+    })
+  }
+}
@@ -0,0 +1,33 @@
+// api/users.go
+
+package api
+
+import (
+  "context"
+  "net/http"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+)
+
+// userContextKey is the context key under which the request's user is stored.
+type userContextKey struct{}
+
+// WithUser returns a copy of the context carrying the user.
+func WithUser(ctx context.Context, user sqlite.User) context.Context {
+  return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the user the request is made on behalf of, if any.
+func UserFromContext(ctx context.Context) (sqlite.User, bool) {
+  user, ok := ctx.Value(userContextKey{}).(sqlite.User)
+  return user, ok
+}
+
+// requestUser returns the user of the request, writing an error response if there is none.
+func requestUser(w http.ResponseWriter, r *http.Request) (sqlite.User, bool) {
+  user, ok := UserFromContext(r.Context())
+  if !ok {
+    http.Error(w, "Unauthorized", http.StatusUnauthorized)
+  }
+  return user, ok
+}
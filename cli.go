@@ -0,0 +1,444 @@
+package main
+
+import (
+  "bytes"
+  "flag"
+  "fmt"
+  "os"
+  "strconv"
+  "strings"
+  "text/tabwriter"
+  "time"
+
+  "voice-notetaking-app/config"
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/service/auth"
+  "voice-notetaking-app/service/graph"
+)
+
+// commands are the maintenance commands that can be run instead of the server, by name.
+var commands = map[string]func(cfg config.Config, args []string) error{
+  "migrate": runMigrate,
+  "users":   runUsers,
+  "keys":    runKeys,
+  "graph":   runGraph,
+}
+
+const migrateUsage = `Usage: voice-notetaking-app migrate [-db path] [command]
+
+Commands:
+  status           list every migration and whether it has been applied (default)
+  up [-to version] apply pending migrations, optionally stopping at version
+  down [steps]     revert the last steps applied migrations (default 1)
+`
+
+// runMigrate implements the migrate command, which reports and applies schema migrations.
+func runMigrate(cfg config.Config, args []string) error {
+  flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+  dbPath := flags.String("db", cfg.DBPath, "path to the SQLite database")
+  flags.Usage = func() {
+    fmt.Fprint(flags.Output(), migrateUsage)
+    flags.PrintDefaults()
+  }
+  if err := flags.Parse(args); err != nil {
+    return err
+  }
+
+  if err := sqlite.Open(*dbPath); err != nil {
+    return fmt.Errorf("failed to open database: %v", err)
+  }
+  defer sqlite.Close()
+
+  command, rest := "status", []string(nil)
+  if flags.NArg() > 0 {
+    command, rest = flags.Arg(0), flags.Args()[1:]
+  }
+
+  switch command {
+  case "status":
+    return printMigrationStatus()
+  case "up":
+    upFlags := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+    target := upFlags.Int("to", 0, "last migration version to apply (default: all)")
+    if err := upFlags.Parse(rest); err != nil {
+      return err
+    }
+    applied, err := sqlite.MigrateTo(*target)
+    for _, migration := range applied {
+      fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+    }
+    if err != nil {
+      return err
+    }
+    if len(applied) == 0 {
+      fmt.Println("No pending migrations")
+    }
+    return nil
+  case "down":
+    steps := 1
+    if len(rest) > 0 {
+      n, err := strconv.Atoi(rest[0])
+      if err != nil || n < 1 {
+        return fmt.Errorf("invalid number of steps %q", rest[0])
+      }
+      steps = n
+    }
+    reverted, err := sqlite.Rollback(steps)
+    for _, migration := range reverted {
+      fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+    }
+    if err != nil {
+      return err
+    }
+    if len(reverted) == 0 {
+      fmt.Println("No applied migrations")
+    }
+    return nil
+  default:
+    flags.Usage()
+    return fmt.Errorf("unknown command %q", command)
+  }
+}
+
+// printMigrationStatus prints a table of every known migration and its state.
+func printMigrationStatus() error {
+  statuses, err := sqlite.MigrationStatuses()
+  if err != nil {
+    return err
+  }
+
+  w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+  fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+  for _, status := range statuses {
+    state := "pending"
+    if status.Applied {
+      state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+      if status.Modified {
+        state += " (modified)"
+      }
+    }
+    fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, state)
+  }
+  return w.Flush()
+}
+
+const usersUsage = `Usage: voice-notetaking-app users [-db path] [command]
+
+Commands:
+  list                                             list every user (default)
+  add -name name [-email address] [-timezone zone]  create a user and print its ID
+  timezone id zone                                 set the timezone of a user, such as Europe/Paris,
+                                                   in which relative due dates of its notes are resolved
+`
+
+// runUsers implements the users command, which lists and creates the users owning recordings.
+func runUsers(cfg config.Config, args []string) error {
+  flags := flag.NewFlagSet("users", flag.ContinueOnError)
+  dbPath := flags.String("db", cfg.DBPath, "path to the SQLite database")
+  flags.Usage = func() {
+    fmt.Fprint(flags.Output(), usersUsage)
+    flags.PrintDefaults()
+  }
+  if err := flags.Parse(args); err != nil {
+    return err
+  }
+
+  if err := sqlite.Initialize(*dbPath); err != nil {
+    return fmt.Errorf("failed to initialize database: %v", err)
+  }
+  defer sqlite.Close()
+
+  command, rest := "list", []string(nil)
+  if flags.NArg() > 0 {
+    command, rest = flags.Arg(0), flags.Args()[1:]
+  }
+
+  switch command {
+  case "list":
+    users, err := sqlite.GetUsers()
+    if err != nil {
+      return err
+    }
+    w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "ID\tNAME\tEMAIL\tTIMEZONE\tCREATED")
+    for _, user := range users {
+      fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", user.ID, user.Name, user.Email, user.Timezone, user.CreatedAt.Format("2006-01-02 15:04:05"))
+    }
+    return w.Flush()
+  case "add":
+    addFlags := flag.NewFlagSet("users add", flag.ContinueOnError)
+    name := addFlags.String("name", "", "display name of the user")
+    email := addFlags.String("email", "", "email address of the user")
+    timezone := addFlags.String("timezone", "UTC", "IANA timezone of the user")
+    if err := addFlags.Parse(rest); err != nil {
+      return err
+    }
+    if *name == "" {
+      return fmt.Errorf("-name is required")
+    }
+    if _, err := time.LoadLocation(*timezone); err != nil {
+      return fmt.Errorf("unknown timezone %q", *timezone)
+    }
+    id, err := sqlite.InsertUser(*name, *email)
+    if err != nil {
+      return fmt.Errorf("failed to insert user: %v", err)
+    }
+    if err := sqlite.SetUserTimezone(id, *timezone); err != nil {
+      return fmt.Errorf("failed to set timezone of user: %v", err)
+    }
+    fmt.Printf("Created user %d\n", id)
+    return nil
+  case "timezone":
+    if len(rest) != 2 {
+      return fmt.Errorf("timezone needs the ID of the user and a timezone")
+    }
+    id, err := strconv.ParseInt(rest[0], 10, 64)
+    if err != nil {
+      return fmt.Errorf("invalid user ID %q", rest[0])
+    }
+    if _, err := time.LoadLocation(rest[1]); err != nil {
+      return fmt.Errorf("unknown timezone %q", rest[1])
+    }
+    if err := sqlite.SetUserTimezone(id, rest[1]); err != nil {
+      return fmt.Errorf("failed to set timezone of user %d: %v", id, err)
+    }
+    fmt.Printf("Set timezone of user %d to %s\n", id, rest[1])
+    return nil
+  default:
+    flags.Usage()
+    return fmt.Errorf("unknown command %q", command)
+  }
+}
+
+const keysUsage = `Usage: voice-notetaking-app keys [-db path] -user id [command]
+
+Commands:
+  list                 list the API keys of the user (default)
+  create [-name name]  create an API key and print it; it cannot be shown again
+  revoke id            revoke an API key
+`
+
+// runKeys implements the keys command, which manages the API keys of a user.
+func runKeys(cfg config.Config, args []string) error {
+  flags := flag.NewFlagSet("keys", flag.ContinueOnError)
+  dbPath := flags.String("db", cfg.DBPath, "path to the SQLite database")
+  userID := flags.Int64("user", 0, "ID of the user owning the keys")
+  flags.Usage = func() {
+    fmt.Fprint(flags.Output(), keysUsage)
+    flags.PrintDefaults()
+  }
+  if err := flags.Parse(args); err != nil {
+    return err
+  }
+  if *userID == 0 {
+    flags.Usage()
+    return fmt.Errorf("-user is required")
+  }
+
+  if err := sqlite.Initialize(*dbPath); err != nil {
+    return fmt.Errorf("failed to initialize database: %v", err)
+  }
+  defer sqlite.Close()
+
+  if _, err := sqlite.GetUserByID(*userID); err != nil {
+    return fmt.Errorf("failed to get user %d: %v", *userID, err)
+  }
+
+  command, rest := "list", []string(nil)
+  if flags.NArg() > 0 {
+    command, rest = flags.Arg(0), flags.Args()[1:]
+  }
+
+  switch command {
+  case "list":
+    keys, err := sqlite.GetAPIKeysByUserID(*userID)
+    if err != nil {
+      return err
+    }
+    w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "ID\tNAME\tKEY\tCREATED\tLAST USED\tSTATUS")
+    for _, key := range keys {
+      lastUsed, status := "never", "active"
+      if key.LastUsedAt != nil {
+        lastUsed = key.LastUsedAt.Format("2006-01-02 15:04:05")
+      }
+      if key.RevokedAt != nil {
+        status = "revoked " + key.RevokedAt.Format("2006-01-02 15:04:05")
+      }
+      fmt.Fprintf(w, "%d\t%s\t%s...\t%s\t%s\t%s\n", key.ID, key.Name, key.Hint, key.CreatedAt.Format("2006-01-02 15:04:05"), lastUsed, status)
+    }
+    return w.Flush()
+  case "create":
+    createFlags := flag.NewFlagSet("keys create", flag.ContinueOnError)
+    name := createFlags.String("name", "default", "label to recognise the key by")
+    if err := createFlags.Parse(rest); err != nil {
+      return err
+    }
+    key, err := auth.GenerateAPIKey()
+    if err != nil {
+      return err
+    }
+    id, err := sqlite.InsertAPIKey(*userID, *name, auth.KeyHint(key), auth.HashAPIKey(key))
+    if err != nil {
+      return fmt.Errorf("failed to insert API key: %v", err)
+    }
+    fmt.Printf("Created API key %d for user %d. Store it now, it cannot be shown again:\n%s\n", id, *userID, key)
+    return nil
+  case "revoke":
+    if len(rest) == 0 {
+      return fmt.Errorf("revoke needs the ID of the key")
+    }
+    id, err := strconv.ParseInt(rest[0], 10, 64)
+    if err != nil {
+      return fmt.Errorf("invalid key ID %q", rest[0])
+    }
+    if err := sqlite.RevokeAPIKey(*userID, id); err != nil {
+      return fmt.Errorf("failed to revoke API key %d: %v", id, err)
+    }
+    fmt.Printf("Revoked API key %d\n", id)
+    return nil
+  default:
+    flags.Usage()
+    return fmt.Errorf("unknown command %q", command)
+  }
+}
+
+const graphUsage = `Usage: voice-notetaking-app graph [-db path] [command]
+
+Commands:
+  export -user id -format graphml|gexf|dot|jgf [-concept name] [-min-weight w] [-o file]
+        write the knowledge graph of the user to the file, or to standard output
+  import -user id [-format jgf|graphml] [-dry-run] file
+        merge a graph file into the knowledge graph of the user, or with -dry-run only report
+        what would be merged and the conflicts found; a running server only sees the imported
+        nodes after a restart, so prefer POST /graph/import while it runs
+`
+
+// runGraph implements the graph command, which works on knowledge graphs outside of the server.
+func runGraph(cfg config.Config, args []string) error {
+  flags := flag.NewFlagSet("graph", flag.ContinueOnError)
+  dbPath := flags.String("db", cfg.DBPath, "path to the SQLite database")
+  flags.Usage = func() {
+    fmt.Fprint(flags.Output(), graphUsage)
+    flags.PrintDefaults()
+  }
+  if err := flags.Parse(args); err != nil {
+    return err
+  }
+  if flags.NArg() == 0 {
+    flags.Usage()
+    return fmt.Errorf("missing command")
+  }
+  command, rest := flags.Arg(0), flags.Args()[1:]
+
+  switch command {
+  case "export":
+    exportFlags := flag.NewFlagSet("graph export", flag.ContinueOnError)
+    userID := exportFlags.Int64("user", 0, "ID of the user owning the graph")
+    formatName := exportFlags.String("format", graph.FormatGraphML, "export format")
+    concept := exportFlags.String("concept", "", "only export the nodes having this concept")
+    minWeight := exportFlags.Float64("min-weight", 0, "only export the edges at least this strong")
+    output := exportFlags.String("o", "", "file to write the graph to (default: standard output)")
+    if err := exportFlags.Parse(rest); err != nil {
+      return err
+    }
+    if *userID == 0 {
+      return fmt.Errorf("-user is required")
+    }
+    format, ok := graph.LookupFormat(*formatName)
+    if !ok {
+      return fmt.Errorf("unknown format %q, expected one of %s", *formatName, strings.Join(graph.FormatNames(), ", "))
+    }
+
+    if err := sqlite.Initialize(*dbPath); err != nil {
+      return fmt.Errorf("failed to initialize database: %v", err)
+    }
+    defer sqlite.Close()
+
+    userGraph, err := graph.LoadGraph(*userID)
+    if err != nil {
+      return err
+    }
+    var body bytes.Buffer
+    if err := format.Export(&body, userGraph, graph.Filter{Concept: *concept, MinWeight: *minWeight}); err != nil {
+      return err
+    }
+    if *output == "" {
+      _, err = os.Stdout.Write(body.Bytes())
+      return err
+    }
+    if err := os.WriteFile(*output, body.Bytes(), 0644); err != nil {
+      return fmt.Errorf("failed to write %s: %v", *output, err)
+    }
+    fmt.Fprintf(os.Stderr, "Exported %s to %s\n", format.Name, *output)
+    return nil
+  case "import":
+    importFlags := flag.NewFlagSet("graph import", flag.ContinueOnError)
+    userID := importFlags.Int64("user", 0, "ID of the user owning the graph")
+    formatName := importFlags.String("format", "", "import format (default: detected from the file)")
+    dryRun := importFlags.Bool("dry-run", false, "report what would be imported without storing anything")
+    if err := importFlags.Parse(rest); err != nil {
+      return err
+    }
+    if *userID == 0 {
+      return fmt.Errorf("-user is required")
+    }
+    if importFlags.NArg() != 1 {
+      return fmt.Errorf("import needs the path of the graph file")
+    }
+
+    f, err := os.Open(importFlags.Arg(0))
+    if err != nil {
+      return err
+    }
+    defer f.Close()
+    file, err := graph.ReadImportFile(f, *formatName)
+    if err != nil {
+      return err
+    }
+
+    if err := sqlite.Initialize(*dbPath); err != nil {
+      return fmt.Errorf("failed to initialize database: %v", err)
+    }
+    defer sqlite.Close()
+
+    if _, err := sqlite.GetUserByID(*userID); err != nil {
+      return fmt.Errorf("failed to get user %d: %v", *userID, err)
+    }
+    userGraph, err := graph.LoadGraph(*userID)
+    if err != nil {
+      return err
+    }
+    report, err := graph.Import(userGraph, file, *dryRun)
+    if err != nil {
+      return err
+    }
+    printImportReport(report)
+    return nil
+  default:
+    flags.Usage()
+    return fmt.Errorf("unknown command %q", command)
+  }
+}
+
+// printImportReport prints what a graph import did, or would do in a dry run, and its conflicts.
+func printImportReport(report graph.ImportReport) {
+  if report.DryRun {
+    fmt.Println("Dry run, nothing was stored")
+  }
+  w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+  fmt.Fprintf(w, "Nodes\t%d created\t%d merged\n", report.NodesCreated, report.NodesMerged)
+  fmt.Fprintf(w, "Edges\t%d created\t%d skipped\n", report.EdgesCreated, report.EdgesSkipped)
+  fmt.Fprintf(w, "Vertices\t%d created\t%d skipped\n", report.VerticesCreated, report.VerticesSkipped)
+  w.Flush()
+
+  if len(report.Conflicts) > 0 {
+    fmt.Printf("\nConflicts: %d\n", len(report.Conflicts))
+    w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "KIND\tKEY\tMESSAGE")
+    for _, conflict := range report.Conflicts {
+      fmt.Fprintf(w, "%s\t%s\t%s\n", conflict.Kind, conflict.Key, conflict.Message)
+    }
+    w.Flush()
+  }
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+  "log"
+  "os"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// Config holds the runtime settings of the application.
+type Config struct {
+  DBPath string
+
+  AssemblyAIKey     string
+  AssemblyAIBaseURL string
+
+  // OpenAIBaseURL may point at any OpenAI-compatible server, such as a local stand-in or a self-hosted model.
+  OpenAIKey     string
+  OpenAIBaseURL string
+  LLMModel      string
+
+  // SummaryChunkTokens overrides the per-model chunk budget of map-reduce summarization when set.
+  SummaryChunkTokens   int
+  SummaryOverlapTokens int
+
+  RecordingsDir string
+  JobWorkers    int
+  JobQueueSize  int
+
+  // PipelineConcurrency is how many independent pipeline stages may run at once.
+  PipelineConcurrency int
+
+  // EmbeddingsProvider is "openai" for an OpenAI-compatible embeddings API or "local" for the
+  // offline hashing embedder. It defaults to openai when an OpenAI key is set.
+  EmbeddingsProvider   string
+  EmbeddingsModel      string
+  EmbeddingsDimensions int
+
+  // AuthTokenSecret signs bearer tokens. When unset a random secret is used,
+  // so issued tokens stop working when the server restarts.
+  AuthTokenSecret string
+  AuthTokenTTL    time.Duration
+
+  // DigestsDir is where the file sink writes digests. Digests can also be emailed
+  // when SMTPAddr is set, such as "smtp.example.com:587".
+  DigestsDir         string
+  DigestPollInterval time.Duration
+  SMTPAddr           string
+  SMTPFrom           string
+  SMTPUsername       string
+  SMTPPassword       string
+
+  // Webhooks may only target public addresses unless their host is in WebhookAllowedHosts,
+  // and emails only the address of the user unless its domain is in SMTPAllowedDomains.
+  WebhookAllowedHosts []string
+  SMTPAllowedDomains  []string
+}
+
+// Load reads the configuration from environment variables, falling back to defaults.
+func Load() Config {
+  cfg := Config{
+    DBPath: getEnv("DB_PATH", "./db/mydatabase.db"),
+
+    AssemblyAIKey:     os.Getenv("ASSEMBLY_AI_KEY"),
+    AssemblyAIBaseURL: getEnv("ASSEMBLY_AI_BASE_URL", "https://api.assemblyai.com"),
+
+    OpenAIKey:     os.Getenv("OPENAI_API_KEY"),
+    OpenAIBaseURL: getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+    LLMModel:      getEnv("LLM_MODEL", "gpt-3.5-turbo"),
+
+    SummaryChunkTokens:   getEnvInt("SUMMARY_CHUNK_TOKENS", 0),
+    SummaryOverlapTokens: getEnvInt("SUMMARY_OVERLAP_TOKENS", 0),
+
+    RecordingsDir: getEnv("RECORDINGS_DIR", "recordings"),
+    JobWorkers:    getEnvInt("JOB_WORKERS", 2),
+    JobQueueSize:  getEnvInt("JOB_QUEUE_SIZE", 100),
+
+    PipelineConcurrency: getEnvInt("PIPELINE_CONCURRENCY", 3),
+
+    EmbeddingsModel:      os.Getenv("EMBEDDINGS_MODEL"),
+    EmbeddingsDimensions: getEnvInt("EMBEDDINGS_DIMENSIONS", 512),
+
+    AuthTokenSecret: os.Getenv("AUTH_TOKEN_SECRET"),
+    AuthTokenTTL:    getEnvDuration("AUTH_TOKEN_TTL", 24*time.Hour),
+
+    DigestsDir:         getEnv("DIGESTS_DIR", "digests"),
+    DigestPollInterval: getEnvDuration("DIGEST_POLL_INTERVAL", time.Minute),
+    SMTPAddr:           os.Getenv("SMTP_ADDR"),
+    SMTPFrom:           getEnv("SMTP_FROM", "digests@localhost"),
+    SMTPUsername:       os.Getenv("SMTP_USERNAME"),
+    SMTPPassword:       os.Getenv("SMTP_PASSWORD"),
+
+    WebhookAllowedHosts: getEnvList("WEBHOOK_ALLOWED_HOSTS"),
+    SMTPAllowedDomains:  getEnvList("SMTP_ALLOWED_DOMAINS"),
+  }
+
+  embeddingsProvider := "local"
+  if cfg.OpenAIKey != "" {
+    embeddingsProvider = "openai"
+  }
+  cfg.EmbeddingsProvider = getEnv("EMBEDDINGS_PROVIDER", embeddingsProvider)
+
+  return cfg
+}
+
+// getEnv returns the value of the environment variable or the fallback if it is unset.
+func getEnv(key, fallback string) string {
+  if value, ok := os.LookupEnv(key); ok && value != "" {
+    return value
+  }
+  return fallback
+}
+
+// getEnvList returns the comma-separated values of the environment variable, if any.
+func getEnvList(key string) []string {
+  var values []string
+  for _, value := range strings.Split(os.Getenv(key), ",") {
+    if value = strings.TrimSpace(value); value != "" {
+      values = append(values, value)
+    }
+  }
+  return values
+}
+
+// getEnvInt returns the integer value of the environment variable or the fallback if it is unset or invalid.
+func getEnvInt(key string, fallback int) int {
+  value, ok := os.LookupEnv(key)
+  if !ok || value == "" {
+    return fallback
+  }
+  n, err := strconv.Atoi(value)
+  if err != nil {
+    log.Printf("Invalid value %q for %s, using %d", value, key, fallback)
+    return fallback
+  }
+  return n
+}
+
+// getEnvDuration returns the duration value of the environment variable, such as "90m",
+// or the fallback if it is unset or invalid.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+  value, ok := os.LookupEnv(key)
+  if !ok || value == "" {
+    return fallback
+  }
+  d, err := time.ParseDuration(value)
+  if err != nil || d <= 0 {
+    log.Printf("Invalid value %q for %s, using %s", value, key, fallback)
+    return fallback
+  }
+  return d
+}
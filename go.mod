@@ -3,6 +3,6 @@ module voice-notetaking-app
 go 1.19
 
 require (
-	github.com/mattn/go-sqlite3 v1.14.22 // indirect
-	github.com/sashabaranov/go-openai v1.23.0 // indirect
+	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/sashabaranov/go-openai v1.23.0
 )
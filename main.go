@@ -1,22 +1,39 @@
+// Command voice-notetaking-app serves the voice notes API and runs its admin commands.
+// Full-text search needs go-sqlite3 built with FTS5, so every build and run takes the
+// sqlite_fts5 tag, either as a flag or through GOFLAGS=-tags=sqlite_fts5:
+//
+//   go run -tags sqlite_fts5 .
+//   go run -tags sqlite_fts5 . migrate status
+//   go test -tags sqlite_fts5 ./...
 package main
 
 import (
-  "bufio"
-  "fmt"
+  "context"
   "log"
   "os"
   "io/ioutil"
   "strings"
   "net/http"
   "path/filepath"
-  "bytes"
   "encoding/json"
-
+  "time"
+
+  "voice-notetaking-app/api"
+  "voice-notetaking-app/config"
+  "voice-notetaking-app/service/actions"
+  "voice-notetaking-app/service/ask"
+  "voice-notetaking-app/service/auth"
+  "voice-notetaking-app/service/digest"
+  "voice-notetaking-app/service/embeddings"
+  "voice-notetaking-app/service/graph"
+  "voice-notetaking-app/service/semantic"
   "voice-notetaking-app/pkg/database/sqlite"
   "voice-notetaking-app/service/speechtotext"
-  "voice-notetaking-app/service/insight"
   "voice-notetaking-app/service/summarization"
   "voice-notetaking-app/service/tagging"
+  "voice-notetaking-app/service/insight"
+  "voice-notetaking-app/service/jobs"
+  "voice-notetaking-app/service/llm"
 
 
 
@@ -24,204 +41,21 @@ import (
 
 
 
-// Counter variables for generating unique IDs
-var (
-  nodeIDCounter   int64 = 1
-  edgeIDCounter   int64 = 1
-  vertexIDCounter int64 = 1
-)
-
-// Define the KnowledgeGraph struct
-type KnowledgeGraph struct {
-  Nodes    map[int64]*Node
-  Edges    map[int64]*Edge
-  Vertices map[int64]*Vertex
-}
-
-// Define the Node struct
-type Node struct {
-  ID       int64
-  Text     string
-  Concepts []string
-}
-
-// Define the Edge struct
-type Edge struct {
-  SourceID int64
-  TargetID int64
-  Weight   float64
-}
-
-// Define the Vertex struct
-type Vertex struct {
-  ID       int64
-  NodeID   int64
-  TargetID int64
-  Concept  string
-}
-
-// Define the Graph struct
-type Graph struct {
-  Nodes    []Node
-  Edges    []Edge
-  Vertices []Vertex
-}
-
-
-// Helper functions for generating unique IDs
-func generateNodeID() int64 {
-  nodeIDCounter++
-  return nodeIDCounter
-}
-
-func generateEdgeID() int64 {
-  edgeIDCounter++
-  return edgeIDCounter
-}
-
-func generateVertexID() int64 {
-  vertexIDCounter++
-  return vertexIDCounter
-}
-
-
-// CalculateWeight calculates the weight between two sets of concepts based on Jaccard similarity
-func calculateWeight(concepts1, concepts2 []string) float64 {
-    // Convert concept slices to sets for easier comparison
-    set1 := make(map[string]struct{})
-    set2 := make(map[string]struct{})
-
-    for _, concept := range concepts1 {
-        set1[concept] = struct{}{}
-    }
-
-    for _, concept := range concepts2 {
-        set2[concept] = struct{}{}
-    }
-
-    // Calculate Jaccard similarity
-    intersection := 0
-    for concept := range set1 {
-        if _, exists := set2[concept]; exists {
-            intersection++
-        }
-    }
-
-    union := len(set1) + len(set2) - intersection
-
-    // Prevent division by zero
-    if union == 0 {
-        return 0.0
-    }
-
-    return float64(intersection) / float64(union)
-}
-
-// Contains checks if a string exists in a slice
-func contains(slice []string, item string) bool {
-    for _, s := range slice {
-        if s == item {
-            return true
-        }
-    }
-    return false
-}
-
-// getAllConcepts retrieves all unique concepts from the graph
-func getAllConcepts(graph *Graph) []string {
-    uniqueConcepts := make(map[string]struct{})
-
-    for _, node := range graph.Nodes {
-        for _, concept := range node.Concepts {
-            uniqueConcepts[concept] = struct{}{}
-        }
-    }
-
-    concepts := make([]string, 0, len(uniqueConcepts))
-    for concept := range uniqueConcepts {
-        concepts = append(concepts, concept)
-    }
-
-    return concepts
-}
-
-
-
-
-// Define the AI server URL
-const AIEndpoint = "http://localhost:5000/ai-call" // Adjust the URL and port according to your Flask server configuration
-
-// Define the PromptData struct
-type PromptData struct {
-    Prompt   string   `json:"prompt"`
-    Concepts []string `json:"concepts"`
-}
-
-// ExtractNodesEdgesVertices uses AI to extract nodes, edges, and vertices based on the provided concepts
-func ExtractNodesEdgesVertices(graph *Graph, concepts []string) error {
-    // Prepare system prompt
-    prompt := "You are an AI assistant that is an expert at understanding context. You will take the provided concepts and extract various nodes, edges, and vertices for our knowledge graph based on sentiment. Our aim is to allow the nodes, edges, and vertices to be parsed for added context, so keep that in mind. Respond with the nodes and any potential edges or vertices you want to add to the knowledge graph. Do not respond with the words edge, nodes, vertices, just the words themselves."
-
-    // Create prompt data
-    promptData := PromptData{
-        Prompt:   prompt,
-        Concepts: concepts,
-    }
-
-    // Convert prompt data to JSON
-    promptJSON, err := json.Marshal(promptData)
-    if err != nil {
-        return fmt.Errorf("failed to marshal prompt data: %v", err)
-    }
-
-    // Make a POST request to the AI server
-    resp, err := http.Post(AIEndpoint, "application/json", bytes.NewBuffer(promptJSON))
-    if err != nil {
-        return fmt.Errorf("failed to make POST request: %v", err)
-    }
-    defer resp.Body.Close()
-
-    // Check the response status code
-    if resp.StatusCode != http.StatusOK {
-        return fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
-    }
+func main() {
+  cfg := config.Load()
 
-    // Decode the response JSON
-    var responseData map[string]interface{}
-    if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
-        return fmt.Errorf("failed to decode response JSON: %v", err)
+  // Run a maintenance command instead of starting the server
+  if len(os.Args) > 1 {
+    if command, ok := commands[os.Args[1]]; ok {
+      if err := command(cfg, os.Args[2:]); err != nil {
+        log.Fatalf("%s failed: %v", os.Args[1], err)
+      }
+      return
     }
-
-  
-  // Process response and update the graph
-  message_content := responseData["content"].(string)
-  
-  // Then parse the message content and update the graph accordingly
-  if err := ParseAndUpdateGraph(graph, message_content); err != nil {
-      return fmt.Errorf("failed to parse and update graph: %v", err)
-  }
-
-
-    return nil
-}
-
-
-
-
-
-// NewKnowledgeGraph creates a new instance of KnowledgeGraph
-func NewKnowledgeGraph() *KnowledgeGraph {
-  return &KnowledgeGraph{
-    Nodes:    make(map[int64]*Node),
-    Edges:    make(map[int64]*Edge),
-    Vertices: make(map[int64]*Vertex),
   }
-}
 
-func main() {
   // Initialize SQLite database
-  dbPath := "./db/mydatabase.db"
-  err := sqlite.Initialize(dbPath)
+  err := sqlite.Initialize(cfg.DBPath)
   if err != nil {
     log.Fatalf("Failed to initialize SQLite database: %v", err)
   }
@@ -237,18 +71,88 @@ func main() {
   log.Println("Audio file read successfully")
 
   // Convert audio to text using speech-to-text service
-  transcription, err := speechtotext.UploadToAssemblyAI(audioBytes)
+  transcript, err := speechtotext.UploadToAssemblyAI(audioBytes)
   if err != nil {
     log.Printf("Failed to transcribe audio: %v", err)
+  } else {
+    log.Println("Transcription:", transcript.Text)
   }
-  log.Println("Transcription:", transcription)
 
-  // Load knowledge graph data
-  graph, err := LoadGraph("knowledge_graph.txt")
+  // Knowledge graphs are loaded per user the first time one of their notes is processed or their graph is queried
+  graphs := graph.NewUserGraphs()
+
+  // Start the background workers that process uploaded voice notes
+  llmClient := llm.NewOpenAI(cfg.OpenAIKey, cfg.OpenAIBaseURL, cfg.LLMModel)
+  summarizer := summarization.New(llmClient)
+  summarizer.Model = cfg.LLMModel
+  summarizer.ChunkTokens = cfg.SummaryChunkTokens
+  summarizer.OverlapTokens = cfg.SummaryOverlapTokens
+  var embedder embeddings.Embedder
+  switch cfg.EmbeddingsProvider {
+  case "openai":
+    embedder = embeddings.NewOpenAI(cfg.OpenAIKey, cfg.OpenAIBaseURL, cfg.EmbeddingsModel)
+  case "local":
+    embedder = embeddings.NewHashing(cfg.EmbeddingsDimensions)
+  default:
+    log.Fatalf("Unknown embeddings provider %q, expected openai or local", cfg.EmbeddingsProvider)
+  }
+  searcher := semantic.New(embedder)
+  log.Println("Semantic search using embedding model", embedder.Model())
+  pipeline := &UploadPipeline{
+    Transcriber: speechtotext.NewAssemblyAI(cfg.AssemblyAIKey, cfg.AssemblyAIBaseURL),
+    Summarizer:  summarizer,
+    Tagger:      tagging.New(llmClient),
+    Insights:    insight.New(llmClient),
+    Actions:     actions.New(llmClient),
+    Graphs:      graphs,
+    Search:      searcher,
+    Concurrency: cfg.PipelineConcurrency,
+  }
+  manager := jobs.NewManager(pipelineStages, cfg.JobWorkers, cfg.JobQueueSize, pipeline.Run)
+  if err := manager.Start(context.Background()); err != nil {
+    log.Fatalf("Failed to start job workers: %v", err)
+  }
+  log.Println("Job workers started")
+
+  // Generate and deliver the digests users have scheduled
+  scheduler := digest.New(summarizer, pipeline.Insights)
+  scheduler.PollInterval = cfg.DigestPollInterval
+  scheduler.Sinks["file"] = &digest.FileSink{Dir: cfg.DigestsDir}
+  scheduler.Sinks["webhook"] = &digest.WebhookSink{
+    HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+    AllowedHosts: cfg.WebhookAllowedHosts,
+  }
+  if cfg.SMTPAddr != "" {
+    scheduler.Sinks["email"] = &digest.SMTPSink{
+      Addr:     cfg.SMTPAddr,
+      From:     cfg.SMTPFrom,
+      Username: cfg.SMTPUsername,
+      Password: cfg.SMTPPassword,
+
+      AllowedDomains: cfg.SMTPAllowedDomains,
+    }
+  }
+  scheduler.Start(context.Background())
+  log.Println("Digest scheduler started with sinks", strings.Join(scheduler.SinkNames(), ", "))
+
+  // Authenticate every request with an API key or a signed bearer token
+  secret := []byte(cfg.AuthTokenSecret)
+  if len(secret) == 0 {
+    log.Println("AUTH_TOKEN_SECRET is not set, bearer tokens will not survive a restart")
+    secret, err = auth.RandomSecret()
+    if err != nil {
+      log.Fatalf("Failed to generate token secret: %v", err)
+    }
+  }
+  signer, err := auth.NewSigner(secret)
   if err != nil {
-    log.Fatalf("Failed to load knowledge graph: %v", err)
+    log.Fatalf("Failed to create token signer: %v", err)
+  }
+  authenticator := &api.Authenticator{
+    Tokens:   signer,
+    TokenTTL: cfg.AuthTokenTTL,
+    Public:   []string{"/healthz", "/calendar.ics"},
   }
-  log.Println("Knowledge graph loaded successfully")
 
   // HTTP handler to upload voice note
   http.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
@@ -262,7 +166,7 @@ func main() {
     log.Println("Form data parsed successfully")
 
     // Get audio file from form data
-    file, _, err := r.FormFile("audio")
+    file, header, err := r.FormFile("audio")
     if err != nil {
       log.Printf("Failed to read audio file: %v", err)
       http.Error(w, "Failed to read audio file", http.StatusBadRequest)
@@ -280,266 +184,178 @@ func main() {
     }
     log.Println("File content read successfully")
 
-    // Convert audio to text using speech-to-text service
-    transcription, err := speechtotext.UploadToAssemblyAI(fileBytes)
+    // Store the audio so the job can be processed after the request returns
+    jobID, err := jobs.NewID()
     if err != nil {
-      log.Printf("Failed to transcribe audio: %v", err)
-      http.Error(w, "Failed to transcribe audio", http.StatusInternalServerError)
+      log.Printf("Failed to create job: %v", err)
+      http.Error(w, "Failed to create job", http.StatusInternalServerError)
       return
     }
-    log.Println("Transcription:", transcription)
-
-    // Summarize the transcription
-    summary, err := summarization.SummarizeText(transcription)
-    if err != nil {
-      log.Printf("Failed to summarize text: %v", err)
-      http.Error(w, "Failed to summarize text", http.StatusInternalServerError)
-      return
+    extension := filepath.Ext(header.Filename)
+    if extension == "" {
+      extension = ".mp3"
     }
-    log.Println("Summary:", summary)
-
-    // Tag the transcription
-    tags, err := tagging.TagText(transcription)
-    if err != nil {
-      log.Printf("Failed to tag text: %v", err)
-      http.Error(w, "Failed to tag text", http.StatusInternalServerError)
+    audioPath := filepath.Join(cfg.RecordingsDir, jobID+extension)
+    if err := os.MkdirAll(cfg.RecordingsDir, os.ModePerm); err != nil {
+      log.Printf("Failed to create recordings directory: %v", err)
+      http.Error(w, "Failed to store audio file", http.StatusInternalServerError)
       return
     }
-    log.Println("Tags:", tags)
-
-    // Generate insights from the transcription
-    groupedNotes := []string{transcription} // For simplicity, using the entire transcription as one note
-    insightText, err := insight.GenerateInsight(groupedNotes)
-    if err != nil {
-      log.Printf("Failed to generate insight: %v", err)
-      http.Error(w, "Failed to generate insight", http.StatusInternalServerError)
+    if err := ioutil.WriteFile(audioPath, fileBytes, 0644); err != nil {
+      log.Printf("Failed to store audio file: %v", err)
+      http.Error(w, "Failed to store audio file", http.StatusInternalServerError)
       return
     }
-    log.Println("Insight:", insightText)
 
-    // Insert the transcription into the database
-    userID := 1                                  // Example user ID
-    filePath := filepath.Join("recordings", "audio.mp3") // Example file path
-    recordingID, err := sqlite.InsertRecording(int64(userID), filePath, transcription)
-    if err != nil {
-      log.Printf("Failed to insert recording into database: %v", err)
-      http.Error(w, "Failed to insert recording into database", http.StatusInternalServerError)
+    // Queue the job for the background workers, on behalf of the uploading user
+    user, _ := api.UserFromContext(r.Context())
+    if err := manager.Submit(jobID, user.ID, audioPath); err != nil {
+      log.Printf("Failed to submit job: %v", err)
+      // No worker will ever read the audio, so do not leave it behind
+      if err := os.Remove(audioPath); err != nil {
+        log.Printf("Failed to remove audio file: %v", err)
+      }
+      if err == jobs.ErrQueueFull {
+        http.Error(w, "Too many uploads in progress, try again later", http.StatusServiceUnavailable)
+        return
+      }
+      http.Error(w, "Failed to submit job", http.StatusInternalServerError)
       return
     }
-    log.Println("Recording inserted with ID:", recordingID)
-
-    // Build or update knowledge graph with the provided note text and concepts
-    if err := BuildOrUpdateKnowledgeGraph(&graph, transcription, tags); err != nil {
-      log.Fatalf("Failed to build or update knowledge graph: %v", err)
-    }
-    log.Println("Knowledge graph updated successfully")
-
-    // Save the updated graph
-    if err := SaveGraph(&graph, "knowledge_graph.txt"); err != nil {
-      log.Fatalf("Failed to save knowledge graph: %v", err)
-    }
-    log.Println("Knowledge graph saved successfully")
-
-    // Send success response
-    w.WriteHeader(http.StatusOK)
-    fmt.Fprintf(w, "Processing completed successfully!")
+    log.Println("Job queued with ID:", jobID)
+
+    // Send the job ID so the client can poll for progress
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusAccepted)
+    json.NewEncoder(w).Encode(map[string]string{
+      "job_id":     jobID,
+      "status":     jobs.StatusQueued,
+      "status_url": "/jobs/" + jobID,
+    })
   })
 
-  // Start HTTP server
-  log.Println("Server is running on port 8080")
-  log.Fatal(http.ListenAndServe(":8080", nil))
-}
+  // HTTP handlers to report the progress of an upload job, by polling or as an event stream
+  http.HandleFunc("/jobs/", api.JobsHandler(manager))
 
-// BuildOrUpdateKnowledgeGraph builds the knowledge graph with the provided note text and tags, or updates an existing graph
-func BuildOrUpdateKnowledgeGraph(graph *Graph, noteText string, tags []string) error {
-  // Extract concepts from tags (assuming tags represent concepts)
-  concepts := tags
+  // HTTP handler to fetch the timed transcript of a recording
+  http.HandleFunc("/recordings/", api.RecordingTranscriptHandler)
 
-  // Create nodes for the note
-  node := Node{
-    ID:       generateNodeID(),
-    Text:     noteText,
-    Concepts: concepts,
-  }
-  graph.Nodes = append(graph.Nodes, node)
-
-  // Create edges and vertices based on the relationships between nodes
-  for _, existingNode := range graph.Nodes {
-    if existingNode.ID != node.ID {
-      // Calculate edge weight based on concept similarity
-      weight := calculateWeight(node.Concepts, existingNode.Concepts)
-      if weight > 0 {
-        // Create an edge between the nodes
-        edge := Edge{
-          SourceID: node.ID,
-          TargetID: existingNode.ID,
-          Weight:   weight,
-        }
-        graph.Edges = append(graph.Edges, edge)
-
-        // Create vertices for the concepts shared by the nodes
-        for _, concept := range node.Concepts {
-          if contains(existingNode.Concepts, concept) {
-            vertex := Vertex{
-              NodeID:   node.ID,
-              TargetID: existingNode.ID,
-              Concept:  concept,
-            }
-            graph.Vertices = append(graph.Vertices, vertex)
-          }
-        }
-      }
-    }
-  }
+  // HTTP handler to search the transcriptions, summaries and tags of recordings
+  http.HandleFunc("/search", api.SearchHandler)
 
-  return nil
-}
+  // HTTP handler to search recordings by meaning rather than by keywords
+  http.HandleFunc("/search/semantic", api.SemanticSearchHandler(searcher))
 
+  // HTTP handler to answer questions about the notes, citing the recordings the answer comes from
+  http.HandleFunc("/ask", api.AskHandler(ask.New(llmClient)))
 
+  // HTTP handler to generate insights over the notes recorded in a time range
+  http.HandleFunc("/insights", api.InsightsHandler(pipeline.Insights))
 
-/// ParseAndUpdateGraph parses the message content and updates the knowledge graph accordingly
-func ParseAndUpdateGraph(graph *Graph, messageContent string) error {
-    // Split the message content into lines
-    lines := strings.Split(messageContent, "\n")
-
-    // Temporary variables to store extracted node, edge, and vertex information
-    var nodes []Node
-    var edges []Edge
-    var vertices []Vertex
-
-    // Iterate over each line to extract nodes, edges, and vertices
-    for _, line := range lines {
-        // Check if the line contains information about a node
-        if strings.HasPrefix(line, "Node ID:") {
-            var node Node
-            _, err := fmt.Sscanf(line, "Node ID: %d", &node.ID)
-            if err != nil {
-                return fmt.Errorf("failed to parse node ID: %v", err)
-            }
-            // Read the subsequent lines to extract node text and concepts
-            node.Concepts = strings.Split(lines[len(nodes)*3+2], ", ")
-
-            // Append the extracted node to the temporary nodes slice
-            nodes = append(nodes, node)
-        } else if strings.HasPrefix(line, "Edge:") { // Check if the line contains information about an edge
-            var edge Edge
-            _, err := fmt.Sscanf(line, "Edge: SourceID: %d, TargetID: %d, Weight: %f", &edge.SourceID, &edge.TargetID, &edge.Weight)
-            if err != nil {
-                return fmt.Errorf("failed to parse edge: %v", err)
-            }
-            // Append the extracted edge to the temporary edges slice
-            edges = append(edges, edge)
-        } else if strings.HasPrefix(line, "Vertex:") { // Check if the line contains information about a vertex
-            var vertex Vertex
-            _, err := fmt.Sscanf(line, "Vertex: NodeID: %d, TargetID: %d, Concept: %s", &vertex.NodeID, &vertex.TargetID, &vertex.Concept)
-            if err != nil {
-                return fmt.Errorf("failed to parse vertex: %v", err)
-            }
-            // Append the extracted vertex to the temporary vertices slice
-            vertices = append(vertices, vertex)
-        }
-    }
-
-    // Update the graph with the extracted nodes, edges, and vertices
-    graph.Nodes = append(graph.Nodes, nodes...)
-    graph.Edges = append(graph.Edges, edges...)
-    graph.Vertices = append(graph.Vertices, vertices...)
+  // HTTP handler to explore the knowledge graph: a note's neighbors, the notes linked by a concept and paths between notes,
+  // to delete notes from it, and to export it for graph tools or import graphs exported elsewhere
+  http.HandleFunc("/graph/", api.GraphHandler(graphs))
 
-    return nil
-}
+  // HTTP handlers to list, complete and reopen the action items extracted from the notes, and list their decisions
+  http.HandleFunc("/action-items", api.ActionItemsHandler)
+  http.HandleFunc("/action-items/", api.ActionItemsHandler)
+  http.HandleFunc("/decisions", api.DecisionsHandler)
 
+  // HTTP handlers to download the action items as a calendar or subscribe to them with a feed token,
+  // which identifies the user of the feed in place of the usual credentials
+  http.HandleFunc("/calendar/", api.CalendarHandler)
+  http.HandleFunc("/calendar.ics", api.CalendarFeedHandler)
 
+  // HTTP handlers to schedule digests of the notes and read the digests generated
+  http.HandleFunc("/digests", api.DigestsHandler(scheduler))
+  http.HandleFunc("/digests/", api.DigestsHandler(scheduler))
 
+  // HTTP handler to exchange an API key for a short-lived bearer token
+  http.HandleFunc("/auth/token", authenticator.TokenHandler)
 
-// SaveGraph saves the knowledge graph to a file.
-func SaveGraph(graph *Graph, filename string) error {
-  filePath := filename
+  // HTTP handler for health checks, served without authentication like the calendar feed
+  http.HandleFunc("/healthz", api.HealthHandler)
 
-  // Open the file for writing
-  file, err := os.Create(filePath)
-  if err != nil {
-    return fmt.Errorf("failed to create file: %v", err)
-  }
-  defer file.Close()
-
-  // Write nodes to the file
-  for _, node := range graph.Nodes {
-    _, err := fmt.Fprintf(file, "Node ID: %d\nText: %s\nConcepts: %s\n\n", node.ID, node.Text, strings.Join(node.Concepts, ", "))
-    if err != nil {
-      return fmt.Errorf("failed to write node to file: %v", err)
-    }
-  }
+  // Start HTTP server
+  log.Println("Server is running on port 8080")
+  log.Fatal(http.ListenAndServe(":8080", authenticator.Middleware(http.DefaultServeMux)))
+}
 
-  // Write edges to the file
-  for _, edge := range graph.Edges {
-    _, err := fmt.Fprintf(file, "Edge: SourceID: %d, TargetID: %d, Weight: %f\n", edge.SourceID, edge.TargetID, edge.Weight)
-    if err != nil {
-      return fmt.Errorf("failed to write edge to file: %v", err)
+// transcriptSegments converts a transcript into the segments stored in the database
+func transcriptSegments(transcript *speechtotext.Transcript) []sqlite.Segment {
+  segments := make([]sqlite.Segment, 0, len(transcript.Segments))
+  for _, segment := range transcript.Segments {
+    words := make([]sqlite.Word, 0, len(segment.Words))
+    for _, word := range segment.Words {
+      words = append(words, sqlite.Word{
+        Text:       word.Text,
+        StartMs:    word.Start,
+        EndMs:      word.End,
+        Confidence: word.Confidence,
+      })
     }
+    segments = append(segments, sqlite.Segment{
+      Text:       segment.Text,
+      StartMs:    segment.Start,
+      EndMs:      segment.End,
+      Confidence: segment.Confidence,
+      Speaker:    segment.Speaker,
+      Words:      words,
+    })
   }
-
-  return nil
+  return segments
 }
 
-
-// LoadGraph loads the knowledge graph from the database
-func LoadGraph(filename string) (Graph, error) {
-  filePath := filename
-  var graph Graph
-
-  // Check if the file exists
-  if _, err := os.Stat(filePath); os.IsNotExist(err) {
-    // If the file doesn't exist, create an empty file
-    file, err := os.Create(filePath)
-    if err != nil {
-      return graph, fmt.Errorf("failed to create file: %v", err)
-    }
-    defer file.Close()
+// transcriptUtterances converts the speaker utterances of a transcript into the ones stored in the database
+func transcriptUtterances(transcript *speechtotext.Transcript) []sqlite.Utterance {
+  utterances := make([]sqlite.Utterance, 0, len(transcript.Utterances))
+  for _, utterance := range transcript.Utterances {
+    utterances = append(utterances, sqlite.Utterance{
+      Speaker:    utterance.Speaker,
+      Text:       utterance.Text,
+      StartMs:    utterance.Start,
+      EndMs:      utterance.End,
+      Confidence: utterance.Confidence,
+    })
   }
+  return utterances
+}
 
-  // Open the file for reading
-  file, err := os.Open(filePath)
-  if err != nil {
-    return graph, fmt.Errorf("failed to open file: %v", err)
+// transcriptLines converts the sentences of a transcript into the lines action items are extracted from,
+// falling back to the whole text as a single line when the transcript has no sentences
+func transcriptLines(transcript *speechtotext.Transcript) []actions.Line {
+  if len(transcript.Segments) == 0 {
+    return []actions.Line{{Text: transcript.Text}}
   }
-  defer file.Close()
-
-  var node Node
-  var edge Edge
-
-  // Read lines from the file and construct the graph
-  scanner := bufio.NewScanner(file)
-  for scanner.Scan() {
-    line := scanner.Text()
-    if strings.HasPrefix(line, "Node ID:") {
-      if node.ID != 0 {
-        graph.Nodes = append(graph.Nodes, node)
-        node = Node{}
-      }
-      _, err := fmt.Sscanf(line, "Node ID: %d", &node.ID)
-      if err != nil {
-        return graph, fmt.Errorf("failed to parse node ID: %v", err)
-      }
-    } else if strings.HasPrefix(line, "Text:") {
-      _, err := fmt.Sscanf(line, "Text: %s", &node.Text)
-      if err != nil {
-        return graph, fmt.Errorf("failed to parse node text: %v", err)
-      }
-    } else if strings.HasPrefix(line, "Concepts:") {
-      conceptsStr := strings.TrimPrefix(line, "Concepts: ")
-      node.Concepts = strings.Split(conceptsStr, ", ")
-    } else if strings.HasPrefix(line, "Edge:") {
-      _, err := fmt.Sscanf(line, "Edge: SourceID: %d, TargetID: %d, Weight: %f", &edge.SourceID, &edge.TargetID, &edge.Weight)
-      if err != nil {
-        return graph, fmt.Errorf("failed to parse edge: %v", err)
-      }
-      graph.Edges = append(graph.Edges, edge)
-    }
+  lines := make([]actions.Line, 0, len(transcript.Segments))
+  for _, segment := range transcript.Segments {
+    lines = append(lines, actions.Line{
+      StartMs: segment.Start,
+      Speaker: segment.Speaker,
+      Text:    segment.Text,
+    })
   }
+  return lines
+}
 
-  if err := scanner.Err(); err != nil {
-    return graph, fmt.Errorf("error reading file: %v", err)
+// storedActions converts extracted action items and decisions into the ones stored in the database
+func storedActions(extracted *actions.Result) ([]sqlite.ActionItem, []sqlite.Decision) {
+  items := make([]sqlite.ActionItem, 0, len(extracted.ActionItems))
+  for _, item := range extracted.ActionItems {
+    items = append(items, sqlite.ActionItem{
+      Owner:       item.Owner,
+      Description: item.Description,
+      DuePhrase:   item.DuePhrase,
+      DueDate:     item.DueDate,
+      SourceMs:    item.SourceMs,
+    })
   }
-
-  return graph, nil
+  decisions := make([]sqlite.Decision, 0, len(extracted.Decisions))
+  for _, decision := range extracted.Decisions {
+    decisions = append(decisions, sqlite.Decision{
+      Description: decision.Description,
+      SourceMs:    decision.SourceMs,
+    })
+  }
+  return items, decisions
 }
@@ -0,0 +1,335 @@
+package main
+
+import (
+  "context"
+  "fmt"
+  "io/ioutil"
+  "log"
+  "sync"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/service/actions"
+  "voice-notetaking-app/service/graph"
+  "voice-notetaking-app/service/insight"
+  "voice-notetaking-app/service/jobs"
+  "voice-notetaking-app/service/pipeline"
+  "voice-notetaking-app/service/semantic"
+  "voice-notetaking-app/service/speechtotext"
+  "voice-notetaking-app/service/summarization"
+  "voice-notetaking-app/service/tagging"
+)
+
+// Stages of the upload pipeline, in the order they are reported
+const (
+  stageTranscribe = "transcribe"
+  stageSummarize  = "summarize"
+  stageTag        = "tag"
+  stageInsight    = "insight"
+  stageExtract    = "extract"
+  stageStore      = "store"
+  stageEmbed      = "embed"
+  stageGraph      = "graph"
+)
+
+var pipelineStages = []string{stageTranscribe, stageSummarize, stageTag, stageInsight, stageExtract, stageStore, stageEmbed, stageGraph}
+
+// Events published to job subscribers when a stage completes, carrying the stage result
+var stageEvents = map[string]string{
+  stageTranscribe: "transcribed",
+  stageSummarize:  "summarized",
+  stageTag:        "tagged",
+  stageInsight:    "insight",
+  stageExtract:    "actions_extracted",
+  stageStore:      "stored",
+  stageEmbed:      "embedded",
+  stageGraph:      "graph_updated",
+}
+
+// eventStageFailed is published when a stage fails, carrying the stage name and error
+const eventStageFailed = "stage_failed"
+
+// UploadPipeline processes uploaded voice notes in the background
+type UploadPipeline struct {
+  Transcriber speechtotext.Transcriber
+  Summarizer  *summarization.Summarizer
+  Tagger      *tagging.Tagger
+  Insights    *insight.Generator
+  Actions     *actions.Extractor
+  Graphs      *graph.UserGraphs
+  Search      *semantic.Searcher
+
+  // Concurrency is how many of the analysis stages may run at once
+  Concurrency int
+}
+
+// Run transcribes, analyses and stores the voice note of a job, reporting each stage as it goes.
+// Summarization, tagging, insight generation and action item extraction only depend on the transcription and run concurrently;
+// if some of them fail the others still complete and the job finishes as partial.
+func (p *UploadPipeline) Run(ctx context.Context, job *jobs.Job) error {
+  recorder := newStageRecorder(job)
+  orchestrator := &pipeline.Orchestrator{
+    Limit:    p.Concurrency,
+    OnStart:  recorder.start,
+    OnFinish: recorder.finish,
+  }
+
+  // Read the uploaded audio file
+  audioBytes, err := ioutil.ReadFile(job.AudioPath)
+  if err != nil {
+    recorder.skipRemaining("audio file could not be read")
+    return fmt.Errorf("failed to read audio file: %v", err)
+  }
+
+  // Convert audio to text using speech-to-text service
+  var transcript *speechtotext.Transcript
+  result := orchestrator.Run(ctx, pipeline.Stage{
+    Name: stageTranscribe,
+    Run: func(ctx context.Context) (interface{}, error) {
+      var err error
+      transcript, err = p.Transcriber.Transcribe(ctx, audioBytes)
+      if err != nil {
+        return nil, err
+      }
+      return map[string]interface{}{"text": transcript.Text}, nil
+    },
+  })[0]
+  if result.Err != nil {
+    recorder.skipRemaining("transcription failed")
+    return fmt.Errorf("%s: %v", stageTranscribe, result.Err)
+  }
+  transcription := transcript.Text
+
+  // Label each line with its speaker so the summary and insight can attribute statements
+  attributed := transcript.SpeakerAttributed()
+
+  // Resolve relative due dates, such as "by Friday", from when the note was recorded in the timezone of its user
+  recordedAt := job.CreatedAt
+  if recordedAt.IsZero() {
+    recordedAt = time.Now()
+  }
+  if user, err := sqlite.GetUserByID(job.UserID); err != nil {
+    log.Printf("Failed to get user %d, resolving due dates in UTC: %v", job.UserID, err)
+    recordedAt = recordedAt.UTC()
+  } else {
+    recordedAt = recordedAt.In(user.Location())
+  }
+
+  // Summarize, tag, generate insights and extract action items from the transcription concurrently
+  var summary, insightText string
+  var tags []tagging.Tag
+  var extracted *actions.Result
+  analysis := orchestrator.Run(ctx,
+    pipeline.Stage{
+      Name: stageSummarize,
+      Run: func(ctx context.Context) (interface{}, error) {
+        var err error
+        summary, err = p.Summarizer.SummarizeText(ctx, attributed)
+        return map[string]interface{}{"summary": summary}, err
+      },
+    },
+    pipeline.Stage{
+      Name: stageTag,
+      Run: func(ctx context.Context) (interface{}, error) {
+        var err error
+        tags, err = p.Tagger.TagText(ctx, transcription)
+        return map[string]interface{}{"tags": tags}, err
+      },
+    },
+    pipeline.Stage{
+      Name: stageInsight,
+      Run: func(ctx context.Context) (interface{}, error) {
+        var err error
+        groupedNotes := []string{attributed} // For simplicity, using the entire transcription as one note
+        insightText, err = p.Insights.GenerateInsight(ctx, groupedNotes)
+        return map[string]interface{}{"insight": insightText}, err
+      },
+    },
+    pipeline.Stage{
+      Name: stageExtract,
+      Run: func(ctx context.Context) (interface{}, error) {
+        var err error
+        extracted, err = p.Actions.Extract(ctx, transcriptLines(transcript), recordedAt)
+        return extracted, err
+      },
+    },
+  )
+  failed := pipeline.Failed(analysis)
+
+  // Insert the transcription, the analysis results, its segments and utterances into the database
+  var recordingID int64
+  segments := transcriptSegments(transcript)
+  result = orchestrator.Run(ctx, pipeline.Stage{
+    Name: stageStore,
+    Run: func(ctx context.Context) (interface{}, error) {
+      // Store everything in one transaction, so a failure leaves no recording without its transcript
+      stored := make(map[string]interface{})
+      err := sqlite.WithTx(func(tx *sqlite.Tx) error {
+        var err error
+        recordingID, err = tx.InsertRecording(job.UserID, job.AudioPath, transcription)
+        if err != nil {
+          return fmt.Errorf("failed to insert recording: %v", err)
+        }
+        var tagNames []string
+        if tags != nil {
+          tagNames = tagging.Names(tags)
+        }
+        if err := tx.UpdateRecordingDetails(recordingID, summary, tagNames, transcript.Duration); err != nil {
+          return fmt.Errorf("failed to store recording details: %v", err)
+        }
+        if err := tx.InsertTranscript(recordingID, segments); err != nil {
+          return fmt.Errorf("failed to insert transcript segments: %v", err)
+        }
+        if err := tx.InsertUtterances(recordingID, transcriptUtterances(transcript)); err != nil {
+          return fmt.Errorf("failed to insert utterances: %v", err)
+        }
+        if extracted != nil {
+          items, decisions := storedActions(extracted)
+          if err := tx.ReplaceRecordingActions(job.UserID, recordingID, items, decisions); err != nil {
+            return fmt.Errorf("failed to store action items: %v", err)
+          }
+          stored["action_items"], stored["decisions"] = len(items), len(decisions)
+        }
+        return nil
+      })
+      if err != nil {
+        return nil, err
+      }
+      stored["recording_id"] = recordingID
+
+      // Jobs are updated outside the transaction, which holds the write lock until it commits
+      if err := job.SetRecording(recordingID); err != nil {
+        return nil, fmt.Errorf("failed to link recording to job: %v", err)
+      }
+      return stored, nil
+    },
+  })[0]
+  if result.Err != nil {
+    recorder.skipRemaining("storing the recording failed")
+    return fmt.Errorf("%s: %v", stageStore, result.Err)
+  }
+
+  // Index the passages of the recording for semantic search
+  final := []pipeline.Stage{{
+    Name: stageEmbed,
+    Run: func(ctx context.Context) (interface{}, error) {
+      passages, err := p.Search.IndexRecording(ctx, job.UserID, recordingID, segments, transcription)
+      if err != nil {
+        return nil, fmt.Errorf("failed to index recording for semantic search: %v", err)
+      }
+      return map[string]interface{}{"passages": passages}, nil
+    },
+  }}
+
+  // Build or update knowledge graph with the provided note text and concepts, at the same time.
+  // The graph is built from the tags, so it cannot be updated without them
+  if !contains(failed, stageTag) {
+    final = append(final, pipeline.Stage{
+      Name: stageGraph,
+      Run: func(ctx context.Context) (interface{}, error) {
+        userGraph, err := p.Graphs.Get(job.UserID)
+        if err != nil {
+          return nil, err
+        }
+        nodeID, err := graph.BuildOrUpdateKnowledgeGraph(userGraph, recordingID, transcription, tagging.Names(tags))
+        if err != nil {
+          return nil, fmt.Errorf("failed to build or update knowledge graph: %v", err)
+        }
+        return map[string]interface{}{"node_id": nodeID}, nil
+      },
+    })
+  }
+  failed = append(failed, pipeline.Failed(orchestrator.Run(ctx, final...))...)
+  if contains(failed, stageTag) {
+    recorder.skipRemaining("tagging failed")
+  }
+
+  if len(failed) > 0 {
+    return &jobs.PartialError{Stages: failed}
+  }
+  return nil
+}
+
+// stageRecorder records the state of each pipeline stage on the job and publishes its progress.
+// Its methods may be called concurrently by stages running in parallel.
+type stageRecorder struct {
+  job *jobs.Job
+
+  mu      sync.Mutex
+  pending map[string]bool
+}
+
+func newStageRecorder(job *jobs.Job) *stageRecorder {
+  pending := make(map[string]bool, len(pipelineStages))
+  for _, name := range pipelineStages {
+    pending[name] = true
+  }
+  return &stageRecorder{job: job, pending: pending}
+}
+
+// start marks a stage as running
+func (s *stageRecorder) start(name string) {
+  s.mu.Lock()
+  delete(s.pending, name)
+  s.mu.Unlock()
+
+  if err := s.job.StartStage(name); err != nil {
+    log.Printf("Failed to mark stage %s of job %s as started: %v", name, s.job.ID, err)
+  }
+}
+
+// finish stores the result or error of a stage and publishes it
+func (s *stageRecorder) finish(result pipeline.Result) {
+  s.mu.Lock()
+  delete(s.pending, result.Name)
+  s.mu.Unlock()
+
+  if result.Err != nil {
+    if err := s.job.FailStage(result.Name, result.Err); err != nil {
+      log.Printf("Failed to mark stage %s of job %s as failed: %v", result.Name, s.job.ID, err)
+    }
+    s.publish(eventStageFailed, map[string]interface{}{"stage": result.Name, "error": result.Err.Error()})
+    log.Printf("Job %s: stage %s failed after %s: %v", s.job.ID, result.Name, result.Duration, result.Err)
+    return
+  }
+
+  if err := s.job.CompleteStage(result.Name, result.Value); err != nil {
+    log.Printf("Failed to mark stage %s of job %s as completed: %v", result.Name, s.job.ID, err)
+  }
+  s.publish(stageEvents[result.Name], map[string]interface{}{"stage": result.Name, "result": result.Value})
+  log.Printf("Job %s: stage %s completed in %s", s.job.ID, result.Name, result.Duration)
+}
+
+// publish sends a progress event to the subscribers of the job
+func (s *stageRecorder) publish(eventType string, data map[string]interface{}) {
+  if err := s.job.Publish(eventType, data); err != nil {
+    log.Printf("Failed to publish %s event for job %s: %v", eventType, s.job.ID, err)
+  }
+}
+
+// skipRemaining marks every stage that has not run yet as skipped
+func (s *stageRecorder) skipRemaining(reason string) {
+  s.mu.Lock()
+  pending := s.pending
+  s.pending = make(map[string]bool)
+  s.mu.Unlock()
+
+  for _, name := range pipelineStages {
+    if !pending[name] {
+      continue
+    }
+    if err := s.job.SkipStage(name, reason); err != nil {
+      log.Printf("Failed to mark stage %s of job %s as skipped: %v", name, s.job.ID, err)
+    }
+  }
+}
+
+// contains checks if a string exists in a slice
+func contains(slice []string, item string) bool {
+  for _, s := range slice {
+    if s == item {
+      return true
+    }
+  }
+  return false
+}
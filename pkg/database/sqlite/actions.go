@@ -0,0 +1,251 @@
+package sqlite
+
+import (
+  "database/sql"
+  "strings"
+  "time"
+)
+
+// Action item statuses.
+const (
+  ActionItemOpen      = "open"
+  ActionItemCompleted = "completed"
+)
+
+// dateLayout is the format due dates are stored in.
+const dateLayout = "2006-01-02"
+
+// ActionItem is a task extracted from a recording. Times are in milliseconds into the recording.
+type ActionItem struct {
+  ID          int64
+  UserID      int64
+  RecordingID int64
+  Owner       string
+  Description string
+  DuePhrase   string
+  DueDate     *time.Time
+  SourceMs    int64
+  Status      string
+  RecordedAt  time.Time
+  CreatedAt   time.Time
+  UpdatedAt   time.Time
+  CompletedAt *time.Time
+}
+
+// Decision is a decision extracted from a recording.
+type Decision struct {
+  ID          int64
+  UserID      int64
+  RecordingID int64
+  Description string
+  SourceMs    int64
+  RecordedAt  time.Time
+  CreatedAt   time.Time
+}
+
+// ActionItemQuery filters the action items listed by GetActionItems. Zero fields do not filter.
+type ActionItemQuery struct {
+  Status      string
+  Owner       string
+  RecordingID int64
+  DueBefore   time.Time
+  Limit       int
+  Offset      int
+}
+
+// ReplaceRecordingActions stores the action items and decisions extracted from a recording,
+// replacing the ones stored before, in a single transaction.
+func ReplaceRecordingActions(userID, recordingID int64, items []ActionItem, decisions []Decision) error {
+  return WithTx(func(tx *Tx) error {
+    return tx.ReplaceRecordingActions(userID, recordingID, items, decisions)
+  })
+}
+
+// ReplaceRecordingActions stores the action items and decisions extracted from a recording in the
+// transaction, replacing the ones stored before.
+func (t *Tx) ReplaceRecordingActions(userID, recordingID int64, items []ActionItem, decisions []Decision) error {
+  if _, err := t.tx.Exec(`DELETE FROM action_items WHERE recording_id = ?`, recordingID); err != nil {
+    return err
+  }
+  if _, err := t.tx.Exec(`DELETE FROM decisions WHERE recording_id = ?`, recordingID); err != nil {
+    return err
+  }
+
+  for _, item := range items {
+    var dueDate interface{}
+    if item.DueDate != nil {
+      dueDate = item.DueDate.Format(dateLayout)
+    }
+    _, err := t.tx.Exec(`
+      INSERT INTO action_items (user_id, recording_id, owner, description, due_phrase, due_date, source_ms, status, updated_at)
+      VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+    `, userID, recordingID, item.Owner, item.Description, item.DuePhrase, dueDate, item.SourceMs, ActionItemOpen)
+    if err != nil {
+      return err
+    }
+  }
+
+  for _, decision := range decisions {
+    _, err := t.tx.Exec(`
+      INSERT INTO decisions (user_id, recording_id, description, source_ms)
+      VALUES (?, ?, ?, ?)
+    `, userID, recordingID, decision.Description, decision.SourceMs)
+    if err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+const actionItemColumns = `a.id, a.user_id, a.recording_id, a.owner, a.description, a.due_phrase, a.due_date,
+  a.source_ms, a.status, r.created_at, a.created_at, a.updated_at, a.completed_at`
+
+// GetActionItems retrieves the user's action items across all recordings. Items are ordered by
+// due date, with undated items last, then by the time they were recorded.
+func GetActionItems(userID int64, query ActionItemQuery) ([]ActionItem, error) {
+  conditions := []string{"a.user_id = ?"}
+  args := []interface{}{userID}
+  if query.Status != "" {
+    conditions = append(conditions, "a.status = ?")
+    args = append(args, query.Status)
+  }
+  if query.Owner != "" {
+    conditions = append(conditions, "a.owner = ? COLLATE NOCASE")
+    args = append(args, query.Owner)
+  }
+  if query.RecordingID != 0 {
+    conditions = append(conditions, "a.recording_id = ?")
+    args = append(args, query.RecordingID)
+  }
+  if !query.DueBefore.IsZero() {
+    conditions = append(conditions, "a.due_date < ?")
+    args = append(args, query.DueBefore.Format(dateLayout))
+  }
+
+  limit := query.Limit
+  if limit <= 0 {
+    limit = 50
+  }
+  args = append(args, limit, query.Offset)
+
+  rows, err := db.Query(`
+    SELECT `+actionItemColumns+`
+    FROM action_items a
+    JOIN recordings r ON r.id = a.recording_id
+    WHERE `+strings.Join(conditions, " AND ")+`
+    ORDER BY a.due_date IS NULL, a.due_date, r.created_at, a.id
+    LIMIT ? OFFSET ?
+  `, args...)
+  if err != nil {
+    return nil, err
+  }
+
+  return scanActionItems(rows)
+}
+
+// GetActionItemByID retrieves an action item of the user.
+func GetActionItemByID(userID, id int64) (ActionItem, error) {
+  rows, err := db.Query(`
+    SELECT `+actionItemColumns+`
+    FROM action_items a
+    JOIN recordings r ON r.id = a.recording_id
+    WHERE a.id = ? AND a.user_id = ?
+  `, id, userID)
+  if err != nil {
+    return ActionItem{}, err
+  }
+  items, err := scanActionItems(rows)
+  if err != nil {
+    return ActionItem{}, err
+  }
+  if len(items) == 0 {
+    return ActionItem{}, sql.ErrNoRows
+  }
+
+  return items[0], nil
+}
+
+// SetActionItemStatus completes or reopens an action item of the user. Completing sets the
+// completion time and reopening clears it; either marks the item as updated. Items owned by other users are reported as sql.ErrNoRows.
+func SetActionItemStatus(userID, id int64, status string) error {
+  result, err := db.Exec(`
+    UPDATE action_items SET status = ?,
+      completed_at = CASE WHEN ? = 'completed' THEN COALESCE(completed_at, CURRENT_TIMESTAMP) ELSE NULL END,
+      updated_at = CASE WHEN status = ? THEN updated_at ELSE CURRENT_TIMESTAMP END
+    WHERE id = ? AND user_id = ?
+  `, status, status, status, id, userID)
+  if err != nil {
+    return err
+  }
+
+  return checkAffected(result)
+}
+
+// scanActionItems reads and closes rows of action items.
+func scanActionItems(rows *sql.Rows) ([]ActionItem, error) {
+  defer rows.Close()
+
+  var items []ActionItem
+  for rows.Next() {
+    var item ActionItem
+    var dueDate, updatedAt, completedAt sql.NullTime
+    err := rows.Scan(&item.ID, &item.UserID, &item.RecordingID, &item.Owner, &item.Description, &item.DuePhrase, &dueDate,
+      &item.SourceMs, &item.Status, &item.RecordedAt, &item.CreatedAt, &updatedAt, &completedAt)
+    if err != nil {
+      return nil, err
+    }
+    if dueDate.Valid {
+      item.DueDate = &dueDate.Time
+    }
+    item.UpdatedAt = item.CreatedAt
+    if updatedAt.Valid {
+      item.UpdatedAt = updatedAt.Time
+    }
+    if completedAt.Valid {
+      item.CompletedAt = &completedAt.Time
+    }
+    items = append(items, item)
+  }
+
+  return items, rows.Err()
+}
+
+// GetDecisions retrieves the user's decisions, newest recording first, optionally only those of one recording.
+func GetDecisions(userID, recordingID int64, limit, offset int) ([]Decision, error) {
+  conditions := []string{"d.user_id = ?"}
+  args := []interface{}{userID}
+  if recordingID != 0 {
+    conditions = append(conditions, "d.recording_id = ?")
+    args = append(args, recordingID)
+  }
+  if limit <= 0 {
+    limit = 50
+  }
+  args = append(args, limit, offset)
+
+  rows, err := db.Query(`
+    SELECT d.id, d.user_id, d.recording_id, d.description, d.source_ms, r.created_at, d.created_at
+    FROM decisions d
+    JOIN recordings r ON r.id = d.recording_id
+    WHERE `+strings.Join(conditions, " AND ")+`
+    ORDER BY r.created_at DESC, d.recording_id DESC, d.source_ms, d.id
+    LIMIT ? OFFSET ?
+  `, args...)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var decisions []Decision
+  for rows.Next() {
+    var decision Decision
+    err := rows.Scan(&decision.ID, &decision.UserID, &decision.RecordingID, &decision.Description, &decision.SourceMs, &decision.RecordedAt, &decision.CreatedAt)
+    if err != nil {
+      return nil, err
+    }
+    decisions = append(decisions, decision)
+  }
+
+  return decisions, rows.Err()
+}
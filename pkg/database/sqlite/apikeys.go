@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+  "database/sql"
+  "time"
+)
+
+// APIKey is a stored API key of a user. The key itself is never stored, only its hash.
+type APIKey struct {
+  ID         int64      `json:"id"`
+  UserID     int64      `json:"user_id"`
+  Name       string     `json:"name"`
+  Hint       string     `json:"hint"`
+  CreatedAt  time.Time  `json:"created_at"`
+  LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+  RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// InsertAPIKey stores the hash of a new API key of the user and returns its ID.
+func InsertAPIKey(userID int64, name, hint, keyHash string) (int64, error) {
+  result, err := db.Exec(`
+    INSERT INTO api_keys (user_id, name, hint, key_hash)
+    VALUES (?, ?, ?, ?)
+  `, userID, name, hint, keyHash)
+  if err != nil {
+    return 0, err
+  }
+
+  id, err := result.LastInsertId()
+  if err != nil {
+    return 0, err
+  }
+
+  return id, nil
+}
+
+// GetActiveAPIKeyByHash retrieves the API key with the given hash, unless it has been revoked.
+func GetActiveAPIKeyByHash(keyHash string) (APIKey, error) {
+  rows, err := db.Query(`
+    SELECT id, user_id, name, hint, created_at, last_used_at, revoked_at
+    FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL
+  `, keyHash)
+  if err != nil {
+    return APIKey{}, err
+  }
+  keys, err := scanAPIKeys(rows)
+  if err != nil {
+    return APIKey{}, err
+  }
+  if len(keys) == 0 {
+    return APIKey{}, sql.ErrNoRows
+  }
+
+  return keys[0], nil
+}
+
+// GetAPIKeysByUserID retrieves all API keys of the user, including revoked ones, ordered by ID.
+func GetAPIKeysByUserID(userID int64) ([]APIKey, error) {
+  rows, err := db.Query(`
+    SELECT id, user_id, name, hint, created_at, last_used_at, revoked_at
+    FROM api_keys WHERE user_id = ? ORDER BY id
+  `, userID)
+  if err != nil {
+    return nil, err
+  }
+
+  return scanAPIKeys(rows)
+}
+
+// TouchAPIKey records that the API key has just been used.
+func TouchAPIKey(id int64) error {
+  _, err := db.Exec(`
+    UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = ?
+  `, id)
+  return err
+}
+
+// RevokeAPIKey revokes an API key of the user. Keys owned by other users are reported as sql.ErrNoRows.
+func RevokeAPIKey(userID, id int64) error {
+  result, err := db.Exec(`
+    UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP
+    WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+  `, id, userID)
+  if err != nil {
+    return err
+  }
+
+  affected, err := result.RowsAffected()
+  if err != nil {
+    return err
+  }
+  if affected == 0 {
+    return sql.ErrNoRows
+  }
+
+  return nil
+}
+
+// scanAPIKeys reads and closes rows of API keys.
+func scanAPIKeys(rows *sql.Rows) ([]APIKey, error) {
+  defer rows.Close()
+
+  var keys []APIKey
+  for rows.Next() {
+    var key APIKey
+    var lastUsedAt, revokedAt sql.NullTime
+    err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Hint, &key.CreatedAt, &lastUsedAt, &revokedAt)
+    if err != nil {
+      return nil, err
+    }
+    if lastUsedAt.Valid {
+      key.LastUsedAt = &lastUsedAt.Time
+    }
+    if revokedAt.Valid {
+      key.RevokedAt = &revokedAt.Time
+    }
+    keys = append(keys, key)
+  }
+
+  return keys, rows.Err()
+}
@@ -0,0 +1,37 @@
+package sqlite
+
+// SetCalendarFeedToken stores the hash of the user's calendar feed token, replacing the previous one.
+func SetCalendarFeedToken(userID int64, tokenHash string) error {
+  _, err := db.Exec(`
+    INSERT INTO calendar_feeds (user_id, token_hash) VALUES (?, ?)
+    ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = CURRENT_TIMESTAMP
+  `, userID, tokenHash)
+  return err
+}
+
+// GetUserByCalendarFeedToken retrieves the user owning the calendar feed token with the hash.
+func GetUserByCalendarFeedToken(tokenHash string) (User, error) {
+  var user User
+  err := db.QueryRow(`
+    SELECT u.id, u.name, COALESCE(u.email, ''), u.created_at
+    FROM calendar_feeds f JOIN users u ON u.id = f.user_id
+    WHERE f.token_hash = ?
+  `, tokenHash).Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt)
+  if err != nil {
+    return User{}, err
+  }
+
+  return user, nil
+}
+
+// DeleteCalendarFeedToken revokes the user's calendar feed token. Users without one are reported as sql.ErrNoRows.
+func DeleteCalendarFeedToken(userID int64) error {
+  result, err := db.Exec(`
+    DELETE FROM calendar_feeds WHERE user_id = ?
+  `, userID)
+  if err != nil {
+    return err
+  }
+
+  return checkAffected(result)
+}
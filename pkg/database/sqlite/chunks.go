@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+  "encoding/binary"
+  "fmt"
+  "math"
+)
+
+// RecordingChunk is a passage of a recording with its embedding vector. Times are in
+// milliseconds and zero when the passage could not be aligned with the audio.
+type RecordingChunk struct {
+  ID          int64     `json:"id"`
+  RecordingID int64     `json:"recording_id"`
+  UserID      int64     `json:"user_id"`
+  Position    int       `json:"position"`
+  Text        string    `json:"text"`
+  StartMs     int64     `json:"start_ms"`
+  EndMs       int64     `json:"end_ms"`
+  Model       string    `json:"model"`
+  Embedding   []float32 `json:"-"`
+}
+
+// ReplaceRecordingChunks stores the chunks of a recording embedded with the model, replacing the
+// ones stored before for that model, in a single transaction. It returns the chunks with their IDs.
+func ReplaceRecordingChunks(userID, recordingID int64, model string, chunks []RecordingChunk) ([]RecordingChunk, error) {
+  tx, err := db.Begin()
+  if err != nil {
+    return nil, err
+  }
+  defer tx.Rollback()
+
+  _, err = tx.Exec(`
+    DELETE FROM recording_chunks WHERE recording_id = ? AND model = ?
+  `, recordingID, model)
+  if err != nil {
+    return nil, err
+  }
+
+  stored := make([]RecordingChunk, 0, len(chunks))
+  for i, chunk := range chunks {
+    result, err := tx.Exec(`
+      INSERT INTO recording_chunks (recording_id, user_id, position, text, start_ms, end_ms, model, embedding)
+      VALUES (?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), ?, ?)
+    `, recordingID, userID, i, chunk.Text, chunk.StartMs, chunk.EndMs, model, encodeVector(chunk.Embedding))
+    if err != nil {
+      return nil, err
+    }
+
+    chunk.ID, err = result.LastInsertId()
+    if err != nil {
+      return nil, err
+    }
+    chunk.RecordingID, chunk.UserID, chunk.Position, chunk.Model = recordingID, userID, i, model
+    stored = append(stored, chunk)
+  }
+
+  if err := tx.Commit(); err != nil {
+    return nil, err
+  }
+  return stored, nil
+}
+
+// GetRecordingChunksByUserID retrieves every chunk of the user's recordings embedded with the model.
+func GetRecordingChunksByUserID(userID int64, model string) ([]RecordingChunk, error) {
+  rows, err := db.Query(`
+    SELECT id, recording_id, user_id, position, text, COALESCE(start_ms, 0), COALESCE(end_ms, 0), model, embedding
+    FROM recording_chunks WHERE user_id = ? AND model = ? ORDER BY id
+  `, userID, model)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var chunks []RecordingChunk
+  for rows.Next() {
+    var chunk RecordingChunk
+    var embedding []byte
+    err := rows.Scan(&chunk.ID, &chunk.RecordingID, &chunk.UserID, &chunk.Position, &chunk.Text, &chunk.StartMs, &chunk.EndMs, &chunk.Model, &embedding)
+    if err != nil {
+      return nil, err
+    }
+    chunk.Embedding, err = decodeVector(embedding)
+    if err != nil {
+      return nil, fmt.Errorf("chunk %d: %v", chunk.ID, err)
+    }
+    chunks = append(chunks, chunk)
+  }
+
+  return chunks, rows.Err()
+}
+
+// encodeVector packs a vector into little-endian float32s.
+func encodeVector(vector []float32) []byte {
+  b := make([]byte, 4*len(vector))
+  for i, v := range vector {
+    binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+  }
+  return b
+}
+
+// decodeVector unpacks a vector stored by encodeVector.
+func decodeVector(b []byte) ([]float32, error) {
+  if len(b)%4 != 0 {
+    return nil, fmt.Errorf("embedding has %d bytes, not a multiple of 4", len(b))
+  }
+  vector := make([]float32, len(b)/4)
+  for i := range vector {
+    vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+  }
+  return vector, nil
+}
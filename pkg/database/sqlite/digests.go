@@ -0,0 +1,266 @@
+package sqlite
+
+import (
+  "database/sql"
+  "time"
+)
+
+// Digest statuses.
+const (
+  DigestDelivered = "delivered"
+  DigestFailed    = "failed"
+  DigestEmpty     = "empty"
+)
+
+// DigestSchedule is a user's cron schedule for generating and delivering digests.
+type DigestSchedule struct {
+  ID        int64
+  UserID    int64
+  Name      string
+  Cron      string
+  Timezone  string
+  Period    string
+  Sink      string
+  Target    string
+  Enabled   bool
+  NextRunAt time.Time
+  LastRunAt *time.Time
+  CreatedAt time.Time
+}
+
+// Digest is a summary and insight generated over a user's recordings in a period.
+type Digest struct {
+  ID             int64
+  UserID         int64
+  ScheduleID     *int64
+  Name           string
+  PeriodStart    time.Time
+  PeriodEnd      time.Time
+  RecordingCount int
+  Summary        string
+  Insight        string
+  Status         string
+  Error          string
+  CreatedAt      time.Time
+  DeliveredAt    *time.Time
+}
+
+const digestScheduleColumns = `id, user_id, name, cron, timezone, period, sink, target, enabled, next_run_at, last_run_at, created_at`
+
+// InsertDigestSchedule stores a new digest schedule and returns its ID.
+func InsertDigestSchedule(schedule DigestSchedule) (int64, error) {
+  result, err := db.Exec(`
+    INSERT INTO digest_schedules (user_id, name, cron, timezone, period, sink, target, enabled, next_run_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+  `, schedule.UserID, schedule.Name, schedule.Cron, schedule.Timezone, schedule.Period, schedule.Sink, schedule.Target, schedule.Enabled, schedule.NextRunAt.UTC().Format(timestampLayout))
+  if err != nil {
+    return 0, err
+  }
+
+  return result.LastInsertId()
+}
+
+// GetDigestScheduleByID retrieves a digest schedule of the user.
+func GetDigestScheduleByID(userID, id int64) (DigestSchedule, error) {
+  rows, err := db.Query(`
+    SELECT `+digestScheduleColumns+` FROM digest_schedules WHERE id = ? AND user_id = ?
+  `, id, userID)
+  if err != nil {
+    return DigestSchedule{}, err
+  }
+  schedules, err := scanDigestSchedules(rows)
+  if err != nil {
+    return DigestSchedule{}, err
+  }
+  if len(schedules) == 0 {
+    return DigestSchedule{}, sql.ErrNoRows
+  }
+
+  return schedules[0], nil
+}
+
+// GetDigestSchedulesByUserID retrieves all digest schedules of the user, ordered by ID.
+func GetDigestSchedulesByUserID(userID int64) ([]DigestSchedule, error) {
+  rows, err := db.Query(`
+    SELECT `+digestScheduleColumns+` FROM digest_schedules WHERE user_id = ? ORDER BY id
+  `, userID)
+  if err != nil {
+    return nil, err
+  }
+
+  return scanDigestSchedules(rows)
+}
+
+// GetDueDigestSchedules retrieves the enabled digest schedules of all users whose next run is not after now.
+func GetDueDigestSchedules(now time.Time) ([]DigestSchedule, error) {
+  rows, err := db.Query(`
+    SELECT `+digestScheduleColumns+` FROM digest_schedules
+    WHERE enabled = 1 AND next_run_at <= ?
+    ORDER BY next_run_at, id
+  `, now.UTC().Format(timestampLayout))
+  if err != nil {
+    return nil, err
+  }
+
+  return scanDigestSchedules(rows)
+}
+
+// UpdateDigestScheduleRun records that the schedule ran at lastRunAt and when it is due next.
+func UpdateDigestScheduleRun(id int64, lastRunAt, nextRunAt time.Time) error {
+  _, err := db.Exec(`
+    UPDATE digest_schedules SET last_run_at = ?, next_run_at = ? WHERE id = ?
+  `, lastRunAt.UTC().Format(timestampLayout), nextRunAt.UTC().Format(timestampLayout), id)
+  return err
+}
+
+// SetDigestScheduleEnabled pauses or resumes a digest schedule of the user, setting when it is due next.
+// Schedules owned by other users are reported as sql.ErrNoRows.
+func SetDigestScheduleEnabled(userID, id int64, enabled bool, nextRunAt time.Time) error {
+  result, err := db.Exec(`
+    UPDATE digest_schedules SET enabled = ?, next_run_at = ? WHERE id = ? AND user_id = ?
+  `, enabled, nextRunAt.UTC().Format(timestampLayout), id, userID)
+  if err != nil {
+    return err
+  }
+
+  return checkAffected(result)
+}
+
+// DeleteDigestSchedule deletes a digest schedule of the user. The digests it generated are kept.
+// Schedules owned by other users are reported as sql.ErrNoRows.
+func DeleteDigestSchedule(userID, id int64) error {
+  return WithTx(func(tx *Tx) error {
+    result, err := tx.tx.Exec(`
+      DELETE FROM digest_schedules WHERE id = ? AND user_id = ?
+    `, id, userID)
+    if err != nil {
+      return err
+    }
+    if err := checkAffected(result); err != nil {
+      return err
+    }
+
+    _, err = tx.tx.Exec(`
+      UPDATE digests SET schedule_id = NULL WHERE schedule_id = ?
+    `, id)
+    return err
+  })
+}
+
+// scanDigestSchedules reads and closes rows of digest schedules.
+func scanDigestSchedules(rows *sql.Rows) ([]DigestSchedule, error) {
+  defer rows.Close()
+
+  var schedules []DigestSchedule
+  for rows.Next() {
+    var schedule DigestSchedule
+    var lastRunAt sql.NullTime
+    err := rows.Scan(&schedule.ID, &schedule.UserID, &schedule.Name, &schedule.Cron, &schedule.Timezone, &schedule.Period,
+      &schedule.Sink, &schedule.Target, &schedule.Enabled, &schedule.NextRunAt, &lastRunAt, &schedule.CreatedAt)
+    if err != nil {
+      return nil, err
+    }
+    if lastRunAt.Valid {
+      schedule.LastRunAt = &lastRunAt.Time
+    }
+    schedules = append(schedules, schedule)
+  }
+
+  return schedules, rows.Err()
+}
+
+const digestColumns = `id, user_id, schedule_id, name, period_start, period_end, recording_count, summary, insight, status, error, created_at, delivered_at`
+
+// InsertDigest stores a generated digest and returns its ID.
+func InsertDigest(digest Digest) (int64, error) {
+  result, err := db.Exec(`
+    INSERT INTO digests (user_id, schedule_id, name, period_start, period_end, recording_count, summary, insight, status, error)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+  `, digest.UserID, digest.ScheduleID, digest.Name, digest.PeriodStart.UTC().Format(timestampLayout), digest.PeriodEnd.UTC().Format(timestampLayout),
+    digest.RecordingCount, digest.Summary, digest.Insight, digest.Status, digest.Error)
+  if err != nil {
+    return 0, err
+  }
+
+  return result.LastInsertId()
+}
+
+// UpdateDigestDelivery records the outcome of delivering a digest. A delivered digest gets its delivery time set.
+func UpdateDigestDelivery(id int64, status, message string) error {
+  _, err := db.Exec(`
+    UPDATE digests SET status = ?, error = ?,
+      delivered_at = CASE WHEN ? = 'delivered' THEN CURRENT_TIMESTAMP ELSE delivered_at END
+    WHERE id = ?
+  `, status, message, status, id)
+  return err
+}
+
+// GetDigestByID retrieves a digest of the user.
+func GetDigestByID(userID, id int64) (Digest, error) {
+  rows, err := db.Query(`
+    SELECT `+digestColumns+` FROM digests WHERE id = ? AND user_id = ?
+  `, id, userID)
+  if err != nil {
+    return Digest{}, err
+  }
+  digests, err := scanDigests(rows)
+  if err != nil {
+    return Digest{}, err
+  }
+  if len(digests) == 0 {
+    return Digest{}, sql.ErrNoRows
+  }
+
+  return digests[0], nil
+}
+
+// GetDigestsByUserID retrieves the latest digests of the user, newest first.
+func GetDigestsByUserID(userID int64, limit int) ([]Digest, error) {
+  rows, err := db.Query(`
+    SELECT `+digestColumns+` FROM digests WHERE user_id = ? ORDER BY id DESC LIMIT ?
+  `, userID, limit)
+  if err != nil {
+    return nil, err
+  }
+
+  return scanDigests(rows)
+}
+
+// scanDigests reads and closes rows of digests.
+func scanDigests(rows *sql.Rows) ([]Digest, error) {
+  defer rows.Close()
+
+  var digests []Digest
+  for rows.Next() {
+    var digest Digest
+    var scheduleID sql.NullInt64
+    var deliveredAt sql.NullTime
+    err := rows.Scan(&digest.ID, &digest.UserID, &scheduleID, &digest.Name, &digest.PeriodStart, &digest.PeriodEnd, &digest.RecordingCount,
+      &digest.Summary, &digest.Insight, &digest.Status, &digest.Error, &digest.CreatedAt, &deliveredAt)
+    if err != nil {
+      return nil, err
+    }
+    if scheduleID.Valid {
+      digest.ScheduleID = &scheduleID.Int64
+    }
+    if deliveredAt.Valid {
+      digest.DeliveredAt = &deliveredAt.Time
+    }
+    digests = append(digests, digest)
+  }
+
+  return digests, rows.Err()
+}
+
+// checkAffected reports sql.ErrNoRows when the statement did not change any row.
+func checkAffected(result sql.Result) error {
+  affected, err := result.RowsAffected()
+  if err != nil {
+    return err
+  }
+  if affected == 0 {
+    return sql.ErrNoRows
+  }
+
+  return nil
+}
@@ -0,0 +1,309 @@
+package sqlite
+
+import (
+  "database/sql"
+)
+
+// execer is implemented by both the database and a transaction, so helpers can run in either.
+type execer interface {
+  Exec(query string, args ...interface{}) (sql.Result, error)
+  QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// GraphNode is a stored knowledge graph node with its concepts.
+type GraphNode struct {
+  ID          int64
+  RecordingID int64
+  Text        string
+  Concepts    []string
+}
+
+// GraphEdge is a stored weighted edge between two knowledge graph nodes.
+type GraphEdge struct {
+  ID       int64
+  SourceID int64
+  TargetID int64
+  Weight   float64
+}
+
+// GraphVertex is a stored concept shared by two knowledge graph nodes.
+type GraphVertex struct {
+  ID       int64
+  NodeID   int64
+  TargetID int64
+  Concept  string
+}
+
+// Tx is a database transaction exposing the insert helpers of recordings and knowledge graphs.
+type Tx struct {
+  tx *sql.Tx
+}
+
+// WithTx runs fn in a transaction, committing if it returns nil and rolling back otherwise.
+func WithTx(fn func(tx *Tx) error) error {
+  tx, err := db.Begin()
+  if err != nil {
+    return err
+  }
+  defer tx.Rollback()
+
+  if err := fn(&Tx{tx: tx}); err != nil {
+    return err
+  }
+
+  return tx.Commit()
+}
+
+// InsertRecording inserts a new recording in the transaction and returns its ID.
+func (t *Tx) InsertRecording(userID int64, filePath, transcription string) (int64, error) {
+  return insertRecording(t.tx, userID, filePath, transcription)
+}
+
+// UpdateRecordingDetails stores the analysis results and audio duration of a recording in the transaction.
+func (t *Tx) UpdateRecordingDetails(recordingID int64, summary string, tags []string, durationMs int64) error {
+  return updateRecordingDetails(t.tx, recordingID, summary, tags, durationMs)
+}
+
+// InsertTranscript stores the segments and words of a recording's transcript in the transaction.
+func (t *Tx) InsertTranscript(recordingID int64, segments []Segment) error {
+  return insertTranscript(t.tx, recordingID, segments)
+}
+
+// InsertUtterances stores the per-speaker utterances of a recording in the transaction.
+func (t *Tx) InsertUtterances(recordingID int64, utterances []Utterance) error {
+  return insertUtterances(t.tx, recordingID, utterances)
+}
+
+// InsertNode inserts a new node owned by the user in the transaction and returns its ID.
+func (t *Tx) InsertNode(userID int64, text string) (int64, error) {
+  return insertNode(t.tx, userID, text)
+}
+
+// InsertEdge inserts a new edge in the transaction and returns its ID.
+func (t *Tx) InsertEdge(sourceID, targetID int64, weight float64) (int64, error) {
+  return insertEdge(t.tx, sourceID, targetID, weight)
+}
+
+// InsertVertex inserts a new vertex in the transaction and returns its ID.
+func (t *Tx) InsertVertex(nodeID, targetID int64, concept string) (int64, error) {
+  return insertVertex(t.tx, nodeID, targetID, concept)
+}
+
+// SetNodeRecording links a node to the recording it was built from.
+func (t *Tx) SetNodeRecording(nodeID, recordingID int64) error {
+  _, err := t.tx.Exec(`
+    UPDATE nodes SET recording_id = ? WHERE id = ?
+  `, recordingID, nodeID)
+  return err
+}
+
+// SetNodeConcepts links a node to the user's concepts, creating concepts that do not exist yet.
+func (t *Tx) SetNodeConcepts(userID, nodeID int64, concepts []string) error {
+  for _, concept := range concepts {
+    conceptID, err := ensureConcept(t.tx, userID, concept)
+    if err != nil {
+      return err
+    }
+
+    _, err = t.tx.Exec(`
+      INSERT OR IGNORE INTO node_concepts (node_id, concept_id)
+      VALUES (?, ?)
+    `, nodeID, conceptID)
+    if err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// DeleteNode deletes the user's node together with its concepts and every edge and vertex touching it.
+// It returns sql.ErrNoRows if the user has no such node.
+func (t *Tx) DeleteNode(userID, nodeID int64) error {
+  result, err := t.tx.Exec(`
+    DELETE FROM nodes WHERE id = ? AND user_id = ?
+  `, nodeID, userID)
+  if err != nil {
+    return err
+  }
+  if err := checkAffected(result); err != nil {
+    return err
+  }
+
+  statements := []string{
+    `DELETE FROM edges WHERE source_id = ? OR target_id = ?`,
+    `DELETE FROM vertices WHERE node_id = ? OR target_id = ?`,
+  }
+  for _, statement := range statements {
+    if _, err := t.tx.Exec(statement, nodeID, nodeID); err != nil {
+      return err
+    }
+  }
+
+  _, err = t.tx.Exec(`
+    DELETE FROM node_concepts WHERE node_id = ?
+  `, nodeID)
+  return err
+}
+
+// ensureConcept returns the ID of the user's named concept, inserting it if needed.
+func ensureConcept(e execer, userID int64, name string) (int64, error) {
+  _, err := e.Exec(`
+    INSERT OR IGNORE INTO concepts (user_id, name)
+    VALUES (?, ?)
+  `, userID, name)
+  if err != nil {
+    return 0, err
+  }
+
+  var id int64
+  err = e.QueryRow(`
+    SELECT id FROM concepts WHERE user_id = ? AND name = ?
+  `, userID, name).Scan(&id)
+  if err != nil {
+    return 0, err
+  }
+
+  return id, nil
+}
+
+// GetGraph retrieves every node with its concepts, edge and vertex of the user's knowledge graph.
+func GetGraph(userID int64) ([]GraphNode, []GraphEdge, []GraphVertex, error) {
+  nodes, err := getGraphNodes(userID)
+  if err != nil {
+    return nil, nil, nil, err
+  }
+
+  edges, err := getGraphEdges(userID)
+  if err != nil {
+    return nil, nil, nil, err
+  }
+
+  vertices, err := getGraphVertices(userID)
+  if err != nil {
+    return nil, nil, nil, err
+  }
+
+  return nodes, edges, vertices, nil
+}
+
+// GetNodeConcepts retrieves the concept names of a node.
+func GetNodeConcepts(nodeID int64) ([]string, error) {
+  rows, err := db.Query(`
+    SELECT c.name FROM node_concepts nc
+    JOIN concepts c ON c.id = nc.concept_id
+    WHERE nc.node_id = ? ORDER BY c.name
+  `, nodeID)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var concepts []string
+  for rows.Next() {
+    var concept string
+    if err := rows.Scan(&concept); err != nil {
+      return nil, err
+    }
+    concepts = append(concepts, concept)
+  }
+
+  return concepts, rows.Err()
+}
+
+// getGraphNodes retrieves all nodes of the user with their concepts, ordered by ID.
+func getGraphNodes(userID int64) ([]GraphNode, error) {
+  rows, err := db.Query(`
+    SELECT id, COALESCE(recording_id, 0), text FROM nodes WHERE user_id = ? ORDER BY id
+  `, userID)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var nodes []GraphNode
+  index := make(map[int64]int)
+  for rows.Next() {
+    var node GraphNode
+    if err := rows.Scan(&node.ID, &node.RecordingID, &node.Text); err != nil {
+      return nil, err
+    }
+    index[node.ID] = len(nodes)
+    nodes = append(nodes, node)
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+
+  conceptRows, err := db.Query(`
+    SELECT nc.node_id, c.name FROM node_concepts nc
+    JOIN concepts c ON c.id = nc.concept_id
+    WHERE c.user_id = ?
+    ORDER BY nc.node_id, nc.rowid
+  `, userID)
+  if err != nil {
+    return nil, err
+  }
+  defer conceptRows.Close()
+
+  for conceptRows.Next() {
+    var nodeID int64
+    var concept string
+    if err := conceptRows.Scan(&nodeID, &concept); err != nil {
+      return nil, err
+    }
+    if i, ok := index[nodeID]; ok {
+      nodes[i].Concepts = append(nodes[i].Concepts, concept)
+    }
+  }
+
+  return nodes, conceptRows.Err()
+}
+
+// getGraphEdges retrieves all edges between nodes of the user, ordered by ID.
+func getGraphEdges(userID int64) ([]GraphEdge, error) {
+  rows, err := db.Query(`
+    SELECT e.id, e.source_id, e.target_id, e.weight FROM edges e
+    JOIN nodes n ON n.id = e.source_id
+    WHERE n.user_id = ? ORDER BY e.id
+  `, userID)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var edges []GraphEdge
+  for rows.Next() {
+    var edge GraphEdge
+    if err := rows.Scan(&edge.ID, &edge.SourceID, &edge.TargetID, &edge.Weight); err != nil {
+      return nil, err
+    }
+    edges = append(edges, edge)
+  }
+
+  return edges, rows.Err()
+}
+
+// getGraphVertices retrieves all vertices between nodes of the user, ordered by ID.
+func getGraphVertices(userID int64) ([]GraphVertex, error) {
+  rows, err := db.Query(`
+    SELECT v.id, v.node_id, v.target_id, v.concept FROM vertices v
+    JOIN nodes n ON n.id = v.node_id
+    WHERE n.user_id = ? ORDER BY v.id
+  `, userID)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var vertices []GraphVertex
+  for rows.Next() {
+    var vertex GraphVertex
+    if err := rows.Scan(&vertex.ID, &vertex.NodeID, &vertex.TargetID, &vertex.Concept); err != nil {
+      return nil, err
+    }
+    vertices = append(vertices, vertex)
+  }
+
+  return vertices, rows.Err()
+}
@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+  "time"
+)
+
+// CachedInsight is an insight generated for a user over a time range.
+type CachedInsight struct {
+  UserID         int64
+  From           time.Time
+  To             time.Time
+  Fingerprint    string
+  RecordingCount int
+  Insight        string
+  CreatedAt      time.Time
+}
+
+// GetCachedInsight retrieves the insight cached for the user and time range.
+func GetCachedInsight(userID int64, from, to time.Time) (CachedInsight, error) {
+  insight := CachedInsight{UserID: userID, From: from, To: to}
+  err := db.QueryRow(`
+    SELECT fingerprint, recording_count, insight, created_at FROM insight_cache
+    WHERE user_id = ? AND range_start = ? AND range_end = ?
+  `, userID, from.UTC().Format(timestampLayout), to.UTC().Format(timestampLayout)).Scan(&insight.Fingerprint, &insight.RecordingCount, &insight.Insight, &insight.CreatedAt)
+  if err != nil {
+    return CachedInsight{}, err
+  }
+
+  return insight, nil
+}
+
+// SaveCachedInsight stores the insight for its user and time range, replacing the one cached before.
+func SaveCachedInsight(insight CachedInsight) error {
+  _, err := db.Exec(`
+    INSERT INTO insight_cache (user_id, range_start, range_end, fingerprint, recording_count, insight)
+    VALUES (?, ?, ?, ?, ?, ?)
+    ON CONFLICT (user_id, range_start, range_end) DO UPDATE SET
+      fingerprint = excluded.fingerprint,
+      recording_count = excluded.recording_count,
+      insight = excluded.insight,
+      created_at = CURRENT_TIMESTAMP
+  `, insight.UserID, insight.From.UTC().Format(timestampLayout), insight.To.UTC().Format(timestampLayout), insight.Fingerprint, insight.RecordingCount, insight.Insight)
+  return err
+}
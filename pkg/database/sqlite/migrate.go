@@ -0,0 +1,276 @@
+package sqlite
+
+import (
+  "crypto/sha256"
+  "database/sql"
+  "embed"
+  "encoding/hex"
+  "fmt"
+  "io/fs"
+  "path"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// migrationFiles holds the schema migrations, named <version>_<name>.up.sql and <version>_<name>.down.sql.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a versioned schema change with the SQL to apply and to revert it.
+type Migration struct {
+  Version  int
+  Name     string
+  Up       string
+  Down     string
+  Checksum string
+}
+
+// MigrationStatus reports whether a migration has been applied to the database.
+type MigrationStatus struct {
+  Migration
+  Applied   bool
+  AppliedAt time.Time
+
+  // Modified is set when the applied migration no longer matches its embedded SQL.
+  Modified bool
+}
+
+// appliedMigration is a row of the schema_migrations table.
+type appliedMigration struct {
+  checksum  string
+  appliedAt time.Time
+}
+
+// LoadMigrations reads the embedded migrations, ordered by version.
+func LoadMigrations() ([]Migration, error) {
+  entries, err := fs.ReadDir(migrationFiles, "migrations")
+  if err != nil {
+    return nil, err
+  }
+
+  byVersion := make(map[int]*Migration)
+  for _, entry := range entries {
+    fileName := entry.Name()
+    var direction string
+    switch {
+    case strings.HasSuffix(fileName, ".up.sql"):
+      direction = "up"
+    case strings.HasSuffix(fileName, ".down.sql"):
+      direction = "down"
+    default:
+      return nil, fmt.Errorf("migration %s: file name must end in .up.sql or .down.sql", fileName)
+    }
+
+    base := strings.TrimSuffix(fileName, "."+direction+".sql")
+    versionPart, name, ok := strings.Cut(base, "_")
+    if !ok {
+      return nil, fmt.Errorf("migration %s: file name must start with <version>_", fileName)
+    }
+    version, err := strconv.Atoi(versionPart)
+    if err != nil || version < 1 {
+      return nil, fmt.Errorf("migration %s: invalid version %q", fileName, versionPart)
+    }
+
+    content, err := fs.ReadFile(migrationFiles, path.Join("migrations", fileName))
+    if err != nil {
+      return nil, err
+    }
+
+    migration, ok := byVersion[version]
+    if !ok {
+      migration = &Migration{Version: version, Name: name}
+      byVersion[version] = migration
+    } else if migration.Name != name {
+      return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, name)
+    }
+
+    if direction == "up" {
+      migration.Up = string(content)
+    } else {
+      migration.Down = string(content)
+    }
+  }
+
+  migrations := make([]Migration, 0, len(byVersion))
+  for _, migration := range byVersion {
+    if migration.Up == "" {
+      return nil, fmt.Errorf("migration %04d_%s has no up migration", migration.Version, migration.Name)
+    }
+    sum := sha256.Sum256([]byte(migration.Up))
+    migration.Checksum = hex.EncodeToString(sum[:])
+    migrations = append(migrations, *migration)
+  }
+  sort.Slice(migrations, func(i, j int) bool {
+    return migrations[i].Version < migrations[j].Version
+  })
+
+  return migrations, nil
+}
+
+// Migrate applies every pending migration in order and returns the ones it applied.
+// It refuses to run if an applied migration has been modified since.
+func Migrate() ([]Migration, error) {
+  return MigrateTo(0)
+}
+
+// MigrateTo applies pending migrations up to and including target, or all of them if target is 0.
+func MigrateTo(target int) ([]Migration, error) {
+  migrations, applied, err := loadMigrationState()
+  if err != nil {
+    return nil, err
+  }
+  if err := validateChecksums(migrations, applied); err != nil {
+    return nil, err
+  }
+
+  var done []Migration
+  for _, migration := range migrations {
+    if target > 0 && migration.Version > target {
+      break
+    }
+    if _, ok := applied[migration.Version]; ok {
+      continue
+    }
+
+    err := WithTx(func(tx *Tx) error {
+      if _, err := tx.tx.Exec(migration.Up); err != nil {
+        return err
+      }
+      _, err := tx.tx.Exec(`
+        INSERT INTO schema_migrations (version, name, checksum)
+        VALUES (?, ?, ?)
+      `, migration.Version, migration.Name, migration.Checksum)
+      return err
+    })
+    if err != nil {
+      return done, fmt.Errorf("failed to apply migration %04d_%s: %v", migration.Version, migration.Name, err)
+    }
+    done = append(done, migration)
+  }
+
+  return done, nil
+}
+
+// Rollback reverts the last steps applied migrations, newest first, and returns the ones it reverted.
+func Rollback(steps int) ([]Migration, error) {
+  migrations, applied, err := loadMigrationState()
+  if err != nil {
+    return nil, err
+  }
+  if err := validateChecksums(migrations, applied); err != nil {
+    return nil, err
+  }
+
+  var done []Migration
+  for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+    migration := migrations[i]
+    if _, ok := applied[migration.Version]; !ok {
+      continue
+    }
+    if migration.Down == "" {
+      return done, fmt.Errorf("migration %04d_%s has no down migration", migration.Version, migration.Name)
+    }
+
+    err := WithTx(func(tx *Tx) error {
+      if _, err := tx.tx.Exec(migration.Down); err != nil {
+        return err
+      }
+      _, err := tx.tx.Exec(`
+        DELETE FROM schema_migrations WHERE version = ?
+      `, migration.Version)
+      return err
+    })
+    if err != nil {
+      return done, fmt.Errorf("failed to revert migration %04d_%s: %v", migration.Version, migration.Name, err)
+    }
+    done = append(done, migration)
+  }
+
+  return done, nil
+}
+
+// MigrationStatuses reports every known migration and whether it has been applied.
+func MigrationStatuses() ([]MigrationStatus, error) {
+  migrations, applied, err := loadMigrationState()
+  if err != nil {
+    return nil, err
+  }
+
+  statuses := make([]MigrationStatus, 0, len(migrations))
+  for _, migration := range migrations {
+    status := MigrationStatus{Migration: migration}
+    if row, ok := applied[migration.Version]; ok {
+      status.Applied = true
+      status.AppliedAt = row.appliedAt
+      status.Modified = row.checksum != migration.Checksum
+    }
+    statuses = append(statuses, status)
+  }
+
+  return statuses, nil
+}
+
+// loadMigrationState reads the embedded migrations and the versions applied to the database.
+func loadMigrationState() ([]Migration, map[int]appliedMigration, error) {
+  migrations, err := LoadMigrations()
+  if err != nil {
+    return nil, nil, err
+  }
+
+  _, err = db.Exec(`
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+      version INTEGER PRIMARY KEY,
+      name TEXT NOT NULL,
+      checksum TEXT NOT NULL,
+      applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    )
+  `)
+  if err != nil {
+    return nil, nil, fmt.Errorf("failed to create schema_migrations table: %v", err)
+  }
+
+  rows, err := db.Query(`
+    SELECT version, checksum, applied_at FROM schema_migrations
+  `)
+  if err != nil {
+    return nil, nil, err
+  }
+  defer rows.Close()
+
+  applied := make(map[int]appliedMigration)
+  for rows.Next() {
+    var version int
+    var row appliedMigration
+    var appliedAt sql.NullTime
+    if err := rows.Scan(&version, &row.checksum, &appliedAt); err != nil {
+      return nil, nil, err
+    }
+    row.appliedAt = appliedAt.Time
+    applied[version] = row
+  }
+
+  return migrations, applied, rows.Err()
+}
+
+// validateChecksums fails if an applied migration differs from its embedded SQL
+// or is unknown to this build.
+func validateChecksums(migrations []Migration, applied map[int]appliedMigration) error {
+  known := make(map[int]bool, len(migrations))
+  for _, migration := range migrations {
+    known[migration.Version] = true
+    if row, ok := applied[migration.Version]; ok && row.checksum != migration.Checksum {
+      return fmt.Errorf("migration %04d_%s has been modified since it was applied", migration.Version, migration.Name)
+    }
+  }
+
+  for version := range applied {
+    if !known[version] {
+      return fmt.Errorf("database has migration %d applied which is unknown to this build", version)
+    }
+  }
+
+  return nil
+}
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS job_events;
+DROP TABLE IF EXISTS job_stages;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS utterances;
+DROP TABLE IF EXISTS transcript_words;
+DROP TABLE IF EXISTS transcript_segments;
+DROP TABLE IF EXISTS node_concepts;
+DROP TABLE IF EXISTS concepts;
+DROP TABLE IF EXISTS recordings;
+DROP TABLE IF EXISTS vertices;
+DROP TABLE IF EXISTS edges;
+DROP TABLE IF EXISTS nodes;
@@ -0,0 +1,144 @@
+-- Tables created by the original InitializeTables. IF NOT EXISTS lets databases
+-- created before migrations existed adopt this version without changes.
+
+CREATE TABLE IF NOT EXISTS nodes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  text TEXT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS edges (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  source_id INTEGER,
+  target_id INTEGER,
+  weight FLOAT,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (source_id) REFERENCES nodes(id),
+  FOREIGN KEY (target_id) REFERENCES nodes(id)
+);
+
+CREATE TABLE IF NOT EXISTS vertices (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  node_id INTEGER,
+  target_id INTEGER,
+  concept TEXT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (node_id) REFERENCES nodes(id),
+  FOREIGN KEY (target_id) REFERENCES nodes(id)
+);
+
+CREATE TABLE IF NOT EXISTS recordings (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER,
+  file_path TEXT NOT NULL,
+  transcription TEXT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS concepts (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT UNIQUE NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS node_concepts (
+  node_id INTEGER NOT NULL,
+  concept_id INTEGER NOT NULL,
+  PRIMARY KEY (node_id, concept_id),
+  FOREIGN KEY (node_id) REFERENCES nodes(id),
+  FOREIGN KEY (concept_id) REFERENCES concepts(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_concepts_concept
+  ON node_concepts (concept_id);
+
+CREATE TABLE IF NOT EXISTS transcript_segments (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  recording_id INTEGER NOT NULL,
+  position INTEGER NOT NULL,
+  text TEXT NOT NULL,
+  start_ms INTEGER NOT NULL,
+  end_ms INTEGER NOT NULL,
+  confidence FLOAT,
+  speaker TEXT,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (recording_id) REFERENCES recordings(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transcript_segments_recording
+  ON transcript_segments (recording_id, start_ms);
+
+CREATE TABLE IF NOT EXISTS transcript_words (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  segment_id INTEGER NOT NULL,
+  position INTEGER NOT NULL,
+  text TEXT NOT NULL,
+  start_ms INTEGER NOT NULL,
+  end_ms INTEGER NOT NULL,
+  confidence FLOAT,
+  FOREIGN KEY (segment_id) REFERENCES transcript_segments(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transcript_words_segment
+  ON transcript_words (segment_id, position);
+
+CREATE TABLE IF NOT EXISTS utterances (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  recording_id INTEGER NOT NULL,
+  position INTEGER NOT NULL,
+  speaker TEXT NOT NULL,
+  text TEXT NOT NULL,
+  start_ms INTEGER NOT NULL,
+  end_ms INTEGER NOT NULL,
+  confidence FLOAT,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (recording_id) REFERENCES recordings(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_utterances_recording
+  ON utterances (recording_id, speaker);
+
+CREATE TABLE IF NOT EXISTS jobs (
+  id TEXT PRIMARY KEY,
+  user_id INTEGER,
+  status TEXT NOT NULL,
+  audio_path TEXT NOT NULL,
+  recording_id INTEGER,
+  error TEXT,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id),
+  FOREIGN KEY (recording_id) REFERENCES recordings(id)
+);
+
+CREATE TABLE IF NOT EXISTS job_stages (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  job_id TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  status TEXT NOT NULL,
+  error TEXT,
+  result TEXT,
+  started_at DATETIME,
+  finished_at DATETIME,
+  UNIQUE (job_id, name),
+  FOREIGN KEY (job_id) REFERENCES jobs(id)
+);
+
+CREATE TABLE IF NOT EXISTS job_events (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  job_id TEXT NOT NULL,
+  type TEXT NOT NULL,
+  data TEXT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (job_id) REFERENCES jobs(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_events_job
+  ON job_events (job_id, id);
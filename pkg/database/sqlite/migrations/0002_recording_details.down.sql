@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_nodes_recording;
+
+ALTER TABLE nodes DROP COLUMN recording_id;
+
+ALTER TABLE recordings DROP COLUMN duration_ms;
+ALTER TABLE recordings DROP COLUMN tags;
+ALTER TABLE recordings DROP COLUMN summary;
@@ -0,0 +1,9 @@
+-- Keep the analysis results with each recording and link graph nodes to the recording they came from.
+
+ALTER TABLE recordings ADD COLUMN summary TEXT;
+ALTER TABLE recordings ADD COLUMN tags TEXT;
+ALTER TABLE recordings ADD COLUMN duration_ms INTEGER;
+
+ALTER TABLE nodes ADD COLUMN recording_id INTEGER REFERENCES recordings(id);
+
+CREATE INDEX IF NOT EXISTS idx_nodes_recording ON nodes (recording_id);
@@ -0,0 +1,27 @@
+CREATE TABLE concepts_global (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT UNIQUE NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Concepts shared by several users collapse into the oldest one.
+INSERT INTO concepts_global (id, name, created_at, updated_at)
+SELECT MIN(id), name, MIN(created_at), MIN(updated_at) FROM concepts GROUP BY name;
+
+UPDATE OR IGNORE node_concepts SET concept_id = (
+  SELECT g.id FROM concepts_global g
+  JOIN concepts c ON c.name = g.name
+  WHERE c.id = node_concepts.concept_id
+);
+DELETE FROM node_concepts WHERE concept_id NOT IN (SELECT id FROM concepts_global);
+
+DROP TABLE concepts;
+ALTER TABLE concepts_global RENAME TO concepts;
+
+DROP INDEX IF EXISTS idx_nodes_user;
+ALTER TABLE nodes DROP COLUMN user_id;
+
+DROP INDEX IF EXISTS idx_recordings_user;
+
+DROP TABLE users;
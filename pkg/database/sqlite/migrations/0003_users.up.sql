@@ -0,0 +1,48 @@
+-- Users own recordings, knowledge graph nodes and concepts.
+
+CREATE TABLE IF NOT EXISTS users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  email TEXT UNIQUE,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Everything stored before users existed was uploaded as the hardcoded user 1.
+INSERT INTO users (id, name)
+SELECT 1, 'default'
+WHERE NOT EXISTS (SELECT 1 FROM users WHERE id = 1)
+  AND (EXISTS (SELECT 1 FROM recordings) OR EXISTS (SELECT 1 FROM nodes) OR EXISTS (SELECT 1 FROM jobs));
+
+UPDATE recordings SET user_id = 1 WHERE user_id IS NULL;
+UPDATE jobs SET user_id = 1 WHERE user_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_recordings_user
+  ON recordings (user_id, created_at);
+
+ALTER TABLE nodes ADD COLUMN user_id INTEGER REFERENCES users(id);
+
+UPDATE nodes SET user_id = COALESCE(
+  (SELECT r.user_id FROM recordings r WHERE r.id = nodes.recording_id),
+  1
+);
+
+CREATE INDEX IF NOT EXISTS idx_nodes_user
+  ON nodes (user_id);
+
+-- Concept names are unique per user rather than globally, which needs the table to be rebuilt.
+CREATE TABLE concepts_scoped (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (user_id, name),
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+INSERT INTO concepts_scoped (id, user_id, name, created_at, updated_at)
+SELECT id, 1, name, created_at, updated_at FROM concepts;
+
+DROP TABLE concepts;
+ALTER TABLE concepts_scoped RENAME TO concepts;
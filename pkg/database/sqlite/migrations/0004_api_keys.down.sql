@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_api_keys_user;
+
+DROP TABLE api_keys;
@@ -0,0 +1,30 @@
+-- Full-text index over recordings, kept in sync by triggers. Requires SQLite built with FTS5.
+
+CREATE VIRTUAL TABLE IF NOT EXISTS recordings_fts USING fts5(
+  transcription,
+  summary,
+  tags,
+  content = 'recordings',
+  content_rowid = 'id',
+  tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS recordings_fts_insert AFTER INSERT ON recordings BEGIN
+  INSERT INTO recordings_fts (rowid, transcription, summary, tags)
+  VALUES (new.id, new.transcription, new.summary, new.tags);
+END;
+
+CREATE TRIGGER IF NOT EXISTS recordings_fts_delete AFTER DELETE ON recordings BEGIN
+  INSERT INTO recordings_fts (recordings_fts, rowid, transcription, summary, tags)
+  VALUES ('delete', old.id, old.transcription, old.summary, old.tags);
+END;
+
+CREATE TRIGGER IF NOT EXISTS recordings_fts_update AFTER UPDATE OF transcription, summary, tags ON recordings BEGIN
+  INSERT INTO recordings_fts (recordings_fts, rowid, transcription, summary, tags)
+  VALUES ('delete', old.id, old.transcription, old.summary, old.tags);
+  INSERT INTO recordings_fts (rowid, transcription, summary, tags)
+  VALUES (new.id, new.transcription, new.summary, new.tags);
+END;
+
+-- Index the recordings stored before this migration.
+INSERT INTO recordings_fts (recordings_fts) VALUES ('rebuild');
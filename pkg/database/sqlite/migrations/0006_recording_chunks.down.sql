@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_recording_chunks_user;
+
+DROP TABLE recording_chunks;
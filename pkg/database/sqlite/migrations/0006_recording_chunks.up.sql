@@ -0,0 +1,21 @@
+-- Passages of recordings with their embedding vectors, stored as little-endian float32 blobs.
+-- Vectors of different models cannot be compared, so each row records the model that made it.
+
+CREATE TABLE IF NOT EXISTS recording_chunks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  recording_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  position INTEGER NOT NULL,
+  text TEXT NOT NULL,
+  start_ms INTEGER,
+  end_ms INTEGER,
+  model TEXT NOT NULL,
+  embedding BLOB NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (recording_id, model, position),
+  FOREIGN KEY (recording_id) REFERENCES recordings(id),
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_recording_chunks_user
+  ON recording_chunks (user_id, model);
@@ -0,0 +1 @@
+DROP TABLE insight_cache;
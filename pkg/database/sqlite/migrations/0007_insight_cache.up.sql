@@ -0,0 +1,15 @@
+-- Insights generated for a user over a time range. The fingerprint identifies the recordings the
+-- insight was generated from, so a cached insight is only reused while they are unchanged.
+
+CREATE TABLE IF NOT EXISTS insight_cache (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  range_start DATETIME NOT NULL,
+  range_end DATETIME NOT NULL,
+  fingerprint TEXT NOT NULL,
+  recording_count INTEGER NOT NULL,
+  insight TEXT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (user_id, range_start, range_end),
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,43 @@
+-- Digests are generated from a user's recordings on a cron schedule and delivered through a sink.
+-- next_run_at is stored in UTC; the cron expression is evaluated in the schedule's timezone.
+
+CREATE TABLE IF NOT EXISTS digest_schedules (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  cron TEXT NOT NULL,
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  period TEXT NOT NULL,
+  sink TEXT NOT NULL,
+  target TEXT NOT NULL DEFAULT '',
+  enabled INTEGER NOT NULL DEFAULT 1,
+  next_run_at DATETIME NOT NULL,
+  last_run_at DATETIME,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_digest_schedules_next_run
+  ON digest_schedules (enabled, next_run_at);
+
+-- The schedule name is copied so a digest still reads well after its schedule is deleted.
+CREATE TABLE IF NOT EXISTS digests (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  schedule_id INTEGER,
+  name TEXT NOT NULL,
+  period_start DATETIME NOT NULL,
+  period_end DATETIME NOT NULL,
+  recording_count INTEGER NOT NULL,
+  summary TEXT NOT NULL,
+  insight TEXT NOT NULL,
+  status TEXT NOT NULL,
+  error TEXT NOT NULL DEFAULT '',
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  delivered_at DATETIME,
+  FOREIGN KEY (user_id) REFERENCES users(id),
+  FOREIGN KEY (schedule_id) REFERENCES digest_schedules(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_digests_user
+  ON digests (user_id, created_at);
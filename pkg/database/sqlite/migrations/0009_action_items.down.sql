@@ -0,0 +1,2 @@
+DROP TABLE decisions;
+DROP TABLE action_items;
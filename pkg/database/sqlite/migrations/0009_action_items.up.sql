@@ -0,0 +1,41 @@
+-- Action items and decisions extracted from a recording. source_ms is the offset into the
+-- recording they were mentioned at; due_date is only set when the due phrase could be resolved.
+
+CREATE TABLE IF NOT EXISTS action_items (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  recording_id INTEGER NOT NULL,
+  owner TEXT NOT NULL DEFAULT '',
+  description TEXT NOT NULL,
+  due_phrase TEXT NOT NULL DEFAULT '',
+  due_date DATE,
+  source_ms INTEGER NOT NULL DEFAULT 0,
+  status TEXT NOT NULL DEFAULT 'open',
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  completed_at DATETIME,
+  FOREIGN KEY (user_id) REFERENCES users(id),
+  FOREIGN KEY (recording_id) REFERENCES recordings(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_action_items_user
+  ON action_items (user_id, status, due_date);
+
+CREATE INDEX IF NOT EXISTS idx_action_items_recording
+  ON action_items (recording_id);
+
+CREATE TABLE IF NOT EXISTS decisions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  recording_id INTEGER NOT NULL,
+  description TEXT NOT NULL,
+  source_ms INTEGER NOT NULL DEFAULT 0,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id),
+  FOREIGN KEY (recording_id) REFERENCES recordings(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_decisions_recording
+  ON decisions (recording_id);
+
+CREATE INDEX IF NOT EXISTS idx_decisions_user
+  ON decisions (user_id, created_at);
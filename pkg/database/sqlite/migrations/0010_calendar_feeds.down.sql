@@ -0,0 +1,2 @@
+DROP TABLE calendar_feeds;
+ALTER TABLE action_items DROP COLUMN updated_at;
@@ -0,0 +1,13 @@
+-- updated_at lets calendar clients see when an action item was completed or reopened.
+ALTER TABLE action_items ADD COLUMN updated_at DATETIME;
+
+UPDATE action_items SET updated_at = COALESCE(completed_at, created_at);
+
+-- Calendar feeds are read by calendar apps that cannot send headers, so each user gets a
+-- secret token to put in the feed URL. Only its SHA-256 hash is stored.
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+  user_id INTEGER PRIMARY KEY,
+  token_hash TEXT UNIQUE NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,4 @@
+DROP INDEX idx_vertices_target;
+DROP INDEX idx_vertices_node;
+DROP INDEX idx_edges_target;
+DROP INDEX idx_edges_source;
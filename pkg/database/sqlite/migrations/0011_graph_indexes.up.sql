@@ -0,0 +1,13 @@
+-- Deleting a node removes every edge and vertex touching it from either end.
+
+CREATE INDEX IF NOT EXISTS idx_edges_source
+  ON edges (source_id);
+
+CREATE INDEX IF NOT EXISTS idx_edges_target
+  ON edges (target_id);
+
+CREATE INDEX IF NOT EXISTS idx_vertices_node
+  ON vertices (node_id);
+
+CREATE INDEX IF NOT EXISTS idx_vertices_target
+  ON vertices (target_id);
@@ -0,0 +1,3 @@
+-- Relative dates said in a recording, such as "by Friday", are resolved in the timezone of its user.
+
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
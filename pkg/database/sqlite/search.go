@@ -0,0 +1,179 @@
+package sqlite
+
+import (
+  "encoding/json"
+  "strings"
+  "time"
+  "unicode"
+)
+
+// Highlight markers wrapped around matched terms in search snippets.
+const (
+  HighlightStart = "<mark>"
+  HighlightEnd   = "</mark>"
+)
+
+// timestampLayout is the layout SQLite's CURRENT_TIMESTAMP stores times in, always UTC.
+const timestampLayout = "2006-01-02 15:04:05"
+
+// SearchQuery filters a full-text search over the recordings of a user.
+// Zero times and an empty tag leave that filter out. Recordings must contain every word
+// of the text unless AnyWord is set.
+type SearchQuery struct {
+  Text    string
+  AnyWord bool
+  From    time.Time
+  To      time.Time
+  Tag     string
+  Limit   int
+  Offset  int
+}
+
+// SearchResult is a recording matching a search, with snippets of the matched text.
+// Lower scores are better matches.
+type SearchResult struct {
+  RecordingID          int64     `json:"recording_id"`
+  CreatedAt            time.Time `json:"created_at"`
+  Score                float64   `json:"score"`
+  TranscriptionSnippet string    `json:"transcription_snippet"`
+  SummarySnippet       string    `json:"summary_snippet,omitempty"`
+  Tags                 []string  `json:"tags"`
+}
+
+// SearchRecordings runs a ranked full-text search over the transcription, summary and tags
+// of the user's recordings. Matches in tags weigh more than in the summary, and those more
+// than in the transcription.
+func SearchRecordings(userID int64, query SearchQuery) ([]SearchResult, error) {
+  match := ftsQuery(query.Text, query.AnyWord)
+  if match == "" {
+    return nil, nil
+  }
+
+  conditions := []string{"recordings_fts MATCH ?", "r.user_id = ?"}
+  args := []interface{}{match, userID}
+  if !query.From.IsZero() {
+    conditions = append(conditions, "r.created_at >= ?")
+    args = append(args, query.From.UTC().Format(timestampLayout))
+  }
+  if !query.To.IsZero() {
+    conditions = append(conditions, "r.created_at < ?")
+    args = append(args, query.To.UTC().Format(timestampLayout))
+  }
+  if query.Tag != "" {
+    conditions = append(conditions, "EXISTS (SELECT 1 FROM json_each(r.tags) WHERE json_each.value = ?)")
+    args = append(args, query.Tag)
+  }
+
+  limit := query.Limit
+  if limit <= 0 {
+    limit = 20
+  }
+  args = append(args, limit, query.Offset)
+
+  rows, err := db.Query(`
+    SELECT r.id, r.created_at, bm25(recordings_fts, 1.0, 2.0, 4.0) AS score,
+      snippet(recordings_fts, 0, '`+HighlightStart+`', '`+HighlightEnd+`', '…', 16),
+      COALESCE(snippet(recordings_fts, 1, '`+HighlightStart+`', '`+HighlightEnd+`', '…', 16), ''),
+      COALESCE(r.tags, '')
+    FROM recordings_fts
+    JOIN recordings r ON r.id = recordings_fts.rowid
+    WHERE `+strings.Join(conditions, " AND ")+`
+    ORDER BY score, r.id DESC
+    LIMIT ? OFFSET ?
+  `, args...)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var results []SearchResult
+  for rows.Next() {
+    var result SearchResult
+    var tags string
+    err := rows.Scan(&result.RecordingID, &result.CreatedAt, &result.Score, &result.TranscriptionSnippet, &result.SummarySnippet, &tags)
+    if err != nil {
+      return nil, err
+    }
+    if tags != "" {
+      if err := json.Unmarshal([]byte(tags), &result.Tags); err != nil {
+        return nil, err
+      }
+    }
+    results = append(results, result)
+  }
+
+  return results, rows.Err()
+}
+
+// ftsQuery turns free text into an FTS5 query matching every word, or any of them, so that
+// user input can never be a syntax error. Each word is quoted; a trailing * is kept as a prefix match.
+func ftsQuery(text string, anyWord bool) string {
+  var terms []string
+  for _, word := range strings.Fields(text) {
+    prefix := strings.HasSuffix(word, "*")
+    word = strings.TrimFunc(word, func(r rune) bool {
+      return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+    if word == "" {
+      continue
+    }
+    term := `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+    if prefix {
+      term += "*"
+    }
+    terms = append(terms, term)
+  }
+  if anyWord {
+    return strings.Join(terms, " OR ")
+  }
+  return strings.Join(terms, " ")
+}
+
+// GetRelatedRecordingIDs retrieves up to limit recordings of the user whose knowledge graph nodes
+// are connected by an edge to a node of one of the given recordings, strongest connection first.
+func GetRelatedRecordingIDs(userID int64, recordingIDs []int64, limit int) ([]int64, error) {
+  if len(recordingIDs) == 0 || limit < 1 {
+    return nil, nil
+  }
+
+  placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(recordingIDs)), ", ")
+  ids := make([]interface{}, len(recordingIDs))
+  for i, id := range recordingIDs {
+    ids[i] = id
+  }
+  args := []interface{}{userID}
+  args = append(args, ids...)
+  args = append(args, userID)
+  args = append(args, ids...)
+  args = append(args, limit)
+
+  // Edges point from newer to older nodes, so follow them in both directions
+  rows, err := db.Query(`
+    SELECT other.recording_id, MAX(e.weight) AS weight
+    FROM edges e
+    JOIN nodes n ON n.id IN (e.source_id, e.target_id)
+    JOIN nodes other ON other.id = CASE WHEN n.id = e.source_id THEN e.target_id ELSE e.source_id END
+    WHERE n.user_id = ? AND n.recording_id IN (`+placeholders+`)
+      AND other.user_id = ? AND other.recording_id IS NOT NULL
+      AND other.recording_id NOT IN (`+placeholders+`)
+    GROUP BY other.recording_id
+    ORDER BY weight DESC, other.recording_id DESC
+    LIMIT ?
+  `, args...)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var related []int64
+  for rows.Next() {
+    var id int64
+    var weight float64
+    if err := rows.Scan(&id, &weight); err != nil {
+      return nil, err
+    }
+    related = append(related, id)
+  }
+
+  return related, rows.Err()
+}
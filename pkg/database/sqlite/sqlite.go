@@ -2,9 +2,13 @@ package sqlite
 
 import (
   "database/sql"
+  "encoding/json"
+  "errors"
   "log"
   "os"
   "path/filepath"
+  "strings"
+  "time"
 
   _ "github.com/mattn/go-sqlite3"
 )
@@ -13,6 +17,24 @@ var db *sql.DB
 
 // Initialize initializes the SQLite database and tables.
 func Initialize(dbPath string) error {
+  err := Open(dbPath)
+  if err != nil {
+    return err
+  }
+
+  // Create or upgrade tables by applying pending migrations
+  err = InitializeTables()
+  if err != nil {
+    return err
+  }
+
+  log.Println("SQLite database initialized successfully")
+
+  return nil
+}
+
+// Open opens the SQLite database without touching its tables.
+func Open(dbPath string) error {
   var err error
 
   // Create the directory to store the database file if it doesn't exist
@@ -22,86 +44,58 @@ func Initialize(dbPath string) error {
     return err
   }
 
-  db, err = sql.Open("sqlite3", dbPath)
+  // Wait for the locks of other connections instead of failing with "database is locked", and take
+  // the write lock as soon as a transaction begins, so concurrent writers queue up instead of
+  // failing when they try to upgrade a read lock
+  db, err = sql.Open("sqlite3", dbPath+"?_busy_timeout=5000&_txlock=immediate")
   if err != nil {
     return err
   }
 
-  // Create tables if they don't exist
-  err = InitializeTables()
+  err = db.Ping()
   if err != nil {
     return err
   }
 
-  log.Println("SQLite database initialized successfully")
+  // Full-text search needs FTS5, which go-sqlite3 only compiles in with the sqlite_fts5 build tag
+  var fts5 bool
+  err = db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5)
+  if err != nil {
+    return err
+  }
+  if !fts5 {
+    return errors.New("SQLite was built without FTS5 support, build with -tags sqlite_fts5 or set GOFLAGS=-tags=sqlite_fts5")
+  }
 
   return nil
 }
 
-// InitializeTables initializes database tables if they don't exist.
+// InitializeTables brings the database tables up to date by applying all pending migrations.
 func InitializeTables() error {
-  _, err := db.Exec(`
-    CREATE TABLE IF NOT EXISTS nodes (
-      id INTEGER PRIMARY KEY AUTOINCREMENT,
-      text TEXT NOT NULL,
-      created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-      updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-    );
-
-    CREATE TABLE IF NOT EXISTS edges (
-      id INTEGER PRIMARY KEY AUTOINCREMENT,
-      source_id INTEGER,
-      target_id INTEGER,
-      weight FLOAT,
-      created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-      updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-      FOREIGN KEY (source_id) REFERENCES nodes(id),
-      FOREIGN KEY (target_id) REFERENCES nodes(id)
-    );
-
-    CREATE TABLE IF NOT EXISTS vertices (
-      id INTEGER PRIMARY KEY AUTOINCREMENT,
-      node_id INTEGER,
-      target_id INTEGER,
-      concept TEXT NOT NULL,
-      created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-      updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-      FOREIGN KEY (node_id) REFERENCES nodes(id),
-      FOREIGN KEY (target_id) REFERENCES nodes(id)
-    );
-
-    CREATE TABLE IF NOT EXISTS recordings (
-      id INTEGER PRIMARY KEY AUTOINCREMENT,
-      user_id INTEGER,
-      file_path TEXT NOT NULL,
-      transcription TEXT NOT NULL,
-      created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-      updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-      FOREIGN KEY (user_id) REFERENCES users(id)
-    );
-
-    CREATE TABLE IF NOT EXISTS concepts (
-      id INTEGER PRIMARY KEY AUTOINCREMENT,
-      name TEXT UNIQUE NOT NULL,
-      created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-      updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-    );
-  `)
+  applied, err := Migrate()
   if err != nil {
     return err
   }
 
+  for _, migration := range applied {
+    log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+  }
   log.Println("Database tables initialized successfully")
 
   return nil
 }
 
-// InsertNode inserts a new node into the database and returns its ID.
-func InsertNode(text string) (int64, error) {
-  result, err := db.Exec(`
-    INSERT INTO nodes (text) 
-    VALUES (?)
-  `, text)
+// InsertNode inserts a new node owned by the user into the database and returns its ID.
+func InsertNode(userID int64, text string) (int64, error) {
+  return insertNode(db, userID, text)
+}
+
+// insertNode inserts a new node through the database or a transaction and returns its ID.
+func insertNode(e execer, userID int64, text string) (int64, error) {
+  result, err := e.Exec(`
+    INSERT INTO nodes (user_id, text) 
+    VALUES (?, ?)
+  `, userID, text)
   if err != nil {
     return 0, err
   }
@@ -129,7 +123,12 @@ func GetNodeByID(id int64) (string, error) {
 
 // InsertEdge inserts a new edge into the database and returns its ID.
 func InsertEdge(sourceID, targetID int64, weight float64) (int64, error) {
-  result, err := db.Exec(`
+  return insertEdge(db, sourceID, targetID, weight)
+}
+
+// insertEdge inserts a new edge through the database or a transaction and returns its ID.
+func insertEdge(e execer, sourceID, targetID int64, weight float64) (int64, error) {
+  result, err := e.Exec(`
     INSERT INTO edges (source_id, target_id, weight) 
     VALUES (?, ?, ?)
   `, sourceID, targetID, weight)
@@ -161,7 +160,12 @@ func GetEdgeByID(id int64) (int64, int64, float64, error) {
 
 // InsertVertex inserts a new vertex into the database and returns its ID.
 func InsertVertex(nodeID, targetID int64, concept string) (int64, error) {
-  result, err := db.Exec(`
+  return insertVertex(db, nodeID, targetID, concept)
+}
+
+// insertVertex inserts a new vertex through the database or a transaction and returns its ID.
+func insertVertex(e execer, nodeID, targetID int64, concept string) (int64, error) {
+  result, err := e.Exec(`
     INSERT INTO vertices (node_id, target_id, concept) 
     VALUES (?, ?, ?)
   `, nodeID, targetID, concept)
@@ -193,7 +197,12 @@ func GetVertexByID(id int64) (int64, int64, string, error) {
 
 // InsertRecording inserts a new recording into the database and returns its ID.
 func InsertRecording(userID int64, filePath, transcription string) (int64, error) {
-  result, err := db.Exec(`
+  return insertRecording(db, userID, filePath, transcription)
+}
+
+// insertRecording inserts a new recording through the database or a transaction and returns its ID.
+func insertRecording(e execer, userID int64, filePath, transcription string) (int64, error) {
+  result, err := e.Exec(`
     INSERT INTO recordings (user_id, file_path, transcription) 
     VALUES (?, ?, ?)
   `, userID, filePath, transcription)
@@ -209,26 +218,146 @@ func InsertRecording(userID int64, filePath, transcription string) (int64, error
   return id, nil
 }
 
-// GetRecordingByID retrieves a recording from the database by its ID.
-func GetRecordingByID(id int64) (int64, string, string, error) {
-  var userID int64
+// GetRecordingByID retrieves a recording of the user from the database by its ID.
+// Recordings owned by other users are reported as sql.ErrNoRows.
+func GetRecordingByID(userID, id int64) (string, string, error) {
   var filePath, transcription string
   err := db.QueryRow(`
-    SELECT user_id, file_path, transcription FROM recordings WHERE id = ?
-  `, id).Scan(&userID, &filePath, &transcription)
+    SELECT file_path, transcription FROM recordings WHERE id = ? AND user_id = ?
+  `, id, userID).Scan(&filePath, &transcription)
   if err != nil {
-    return 0, "", "", err
+    return "", "", err
   }
 
-  return userID, filePath, transcription, nil
+  return filePath, transcription, nil
 }
 
-// InsertConcept inserts a new concept into the database and returns its ID.
-func InsertConcept(name string) (int64, error) {
+// Recording is a stored recording with its transcription and analysis results.
+type Recording struct {
+  ID            int64     `json:"id"`
+  UserID        int64     `json:"user_id"`
+  FilePath      string    `json:"file_path"`
+  Transcription string    `json:"transcription"`
+  Summary       string    `json:"summary,omitempty"`
+  Tags          []string  `json:"tags"`
+  DurationMs    int64     `json:"duration_ms,omitempty"`
+  CreatedAt     time.Time `json:"created_at"`
+  UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// recordingColumns are the columns scanned by scanRecordings, in order.
+const recordingColumns = `id, user_id, file_path, transcription, COALESCE(summary, ''), COALESCE(tags, ''), COALESCE(duration_ms, 0), created_at, updated_at`
+
+// GetRecordingsByIDs retrieves the user's recordings with the given IDs, in the order of the IDs.
+// IDs of recordings that do not exist or belong to other users are left out.
+func GetRecordingsByIDs(userID int64, ids []int64) ([]Recording, error) {
+  if len(ids) == 0 {
+    return nil, nil
+  }
+
+  placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+  args := []interface{}{userID}
+  for _, id := range ids {
+    args = append(args, id)
+  }
+
+  rows, err := db.Query(`
+    SELECT `+recordingColumns+` FROM recordings
+    WHERE user_id = ? AND id IN (`+placeholders+`)
+  `, args...)
+  if err != nil {
+    return nil, err
+  }
+  found, err := scanRecordings(rows)
+  if err != nil {
+    return nil, err
+  }
+
+  byID := make(map[int64]Recording, len(found))
+  for _, recording := range found {
+    byID[recording.ID] = recording
+  }
+  recordings := make([]Recording, 0, len(found))
+  for _, id := range ids {
+    if recording, ok := byID[id]; ok {
+      recordings = append(recordings, recording)
+      delete(byID, id)
+    }
+  }
+
+  return recordings, nil
+}
+
+// GetRecordingsByTimeRange retrieves the user's recordings created at or after from and before to,
+// oldest first.
+func GetRecordingsByTimeRange(userID int64, from, to time.Time) ([]Recording, error) {
+  rows, err := db.Query(`
+    SELECT `+recordingColumns+` FROM recordings
+    WHERE user_id = ? AND created_at >= ? AND created_at < ?
+    ORDER BY created_at, id
+  `, userID, from.UTC().Format(timestampLayout), to.UTC().Format(timestampLayout))
+  if err != nil {
+    return nil, err
+  }
+
+  return scanRecordings(rows)
+}
+
+// scanRecordings reads and closes rows of recordingColumns.
+func scanRecordings(rows *sql.Rows) ([]Recording, error) {
+  defer rows.Close()
+
+  var recordings []Recording
+  for rows.Next() {
+    var recording Recording
+    var tags string
+    err := rows.Scan(&recording.ID, &recording.UserID, &recording.FilePath, &recording.Transcription, &recording.Summary, &tags, &recording.DurationMs, &recording.CreatedAt, &recording.UpdatedAt)
+    if err != nil {
+      return nil, err
+    }
+    if tags != "" {
+      if err := json.Unmarshal([]byte(tags), &recording.Tags); err != nil {
+        return nil, err
+      }
+    }
+    recordings = append(recordings, recording)
+  }
+
+  return recordings, rows.Err()
+}
+
+// UpdateRecordingDetails stores the analysis results and audio duration of a recording.
+// An empty summary or nil tags leave the stored value unset, for example when that stage failed.
+func UpdateRecordingDetails(recordingID int64, summary string, tags []string, durationMs int64) error {
+  return updateRecordingDetails(db, recordingID, summary, tags, durationMs)
+}
+
+// updateRecordingDetails stores the analysis results and audio duration of a recording through the
+// database or a transaction.
+func updateRecordingDetails(e execer, recordingID int64, summary string, tags []string, durationMs int64) error {
+  var encodedTags interface{}
+  if tags != nil {
+    encoded, err := json.Marshal(tags)
+    if err != nil {
+      return err
+    }
+    encodedTags = string(encoded)
+  }
+
+  _, err := e.Exec(`
+    UPDATE recordings
+    SET summary = NULLIF(?, ''), tags = ?, duration_ms = NULLIF(?, 0), updated_at = CURRENT_TIMESTAMP
+    WHERE id = ?
+  `, summary, encodedTags, durationMs, recordingID)
+  return err
+}
+
+// InsertConcept inserts a new concept of the user into the database and returns its ID.
+func InsertConcept(userID int64, name string) (int64, error) {
   result, err := db.Exec(`
-    INSERT INTO concepts (name) 
-    VALUES (?)
-  `, name)
+    INSERT INTO concepts (user_id, name) 
+    VALUES (?, ?)
+  `, userID, name)
   if err != nil {
     return 0, err
   }
@@ -254,6 +383,407 @@ func GetConceptByID(id int64) (string, error) {
   return name, nil
 }
 
+// Segment is a stored sentence of a recording's transcript. Times are in milliseconds.
+type Segment struct {
+  ID          int64   `json:"id"`
+  RecordingID int64   `json:"recording_id"`
+  Position    int     `json:"position"`
+  Text        string  `json:"text"`
+  StartMs     int64   `json:"start_ms"`
+  EndMs       int64   `json:"end_ms"`
+  Confidence  float64 `json:"confidence"`
+  Speaker     string  `json:"speaker,omitempty"`
+  Words       []Word  `json:"words"`
+}
+
+// Word is a stored word of a transcript segment. Times are in milliseconds.
+type Word struct {
+  ID         int64   `json:"id"`
+  SegmentID  int64   `json:"segment_id"`
+  Position   int     `json:"position"`
+  Text       string  `json:"text"`
+  StartMs    int64   `json:"start_ms"`
+  EndMs      int64   `json:"end_ms"`
+  Confidence float64 `json:"confidence"`
+}
+
+// InsertTranscript stores the segments and words of a recording's transcript in a single transaction.
+func InsertTranscript(recordingID int64, segments []Segment) error {
+  return WithTx(func(tx *Tx) error {
+    return tx.InsertTranscript(recordingID, segments)
+  })
+}
+
+// insertTranscript stores the segments and words of a recording's transcript through the database or a transaction.
+func insertTranscript(e execer, recordingID int64, segments []Segment) error {
+  for i, segment := range segments {
+    result, err := e.Exec(`
+      INSERT INTO transcript_segments (recording_id, position, text, start_ms, end_ms, confidence, speaker)
+      VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, recordingID, i, segment.Text, segment.StartMs, segment.EndMs, segment.Confidence, segment.Speaker)
+    if err != nil {
+      return err
+    }
+
+    segmentID, err := result.LastInsertId()
+    if err != nil {
+      return err
+    }
+
+    for j, word := range segment.Words {
+      _, err := e.Exec(`
+        INSERT INTO transcript_words (segment_id, position, text, start_ms, end_ms, confidence)
+        VALUES (?, ?, ?, ?, ?, ?)
+      `, segmentID, j, word.Text, word.StartMs, word.EndMs, word.Confidence)
+      if err != nil {
+        return err
+      }
+    }
+  }
+
+  return nil
+}
+
+// GetSegmentsByRecordingID retrieves the transcript segments of a recording, with their words, in order.
+func GetSegmentsByRecordingID(recordingID int64) ([]Segment, error) {
+  rows, err := db.Query(`
+    SELECT id, recording_id, position, text, start_ms, end_ms, confidence, COALESCE(speaker, '')
+    FROM transcript_segments WHERE recording_id = ? ORDER BY position
+  `, recordingID)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var segments []Segment
+  index := make(map[int64]int)
+  for rows.Next() {
+    var segment Segment
+    err := rows.Scan(&segment.ID, &segment.RecordingID, &segment.Position, &segment.Text, &segment.StartMs, &segment.EndMs, &segment.Confidence, &segment.Speaker)
+    if err != nil {
+      return nil, err
+    }
+    index[segment.ID] = len(segments)
+    segments = append(segments, segment)
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+
+  wordRows, err := db.Query(`
+    SELECT w.id, w.segment_id, w.position, w.text, w.start_ms, w.end_ms, w.confidence
+    FROM transcript_words w
+    JOIN transcript_segments s ON s.id = w.segment_id
+    WHERE s.recording_id = ? ORDER BY w.segment_id, w.position
+  `, recordingID)
+  if err != nil {
+    return nil, err
+  }
+  defer wordRows.Close()
+
+  for wordRows.Next() {
+    var word Word
+    err := wordRows.Scan(&word.ID, &word.SegmentID, &word.Position, &word.Text, &word.StartMs, &word.EndMs, &word.Confidence)
+    if err != nil {
+      return nil, err
+    }
+    if i, ok := index[word.SegmentID]; ok {
+      segments[i].Words = append(segments[i].Words, word)
+    }
+  }
+
+  return segments, wordRows.Err()
+}
+
+// Utterance is a stored stretch of speech by a single speaker. Times are in milliseconds.
+type Utterance struct {
+  ID          int64   `json:"id"`
+  RecordingID int64   `json:"recording_id"`
+  Position    int     `json:"position"`
+  Speaker     string  `json:"speaker"`
+  Text        string  `json:"text"`
+  StartMs     int64   `json:"start_ms"`
+  EndMs       int64   `json:"end_ms"`
+  Confidence  float64 `json:"confidence"`
+}
+
+// InsertUtterances stores the per-speaker utterances of a recording in a single transaction.
+func InsertUtterances(recordingID int64, utterances []Utterance) error {
+  return WithTx(func(tx *Tx) error {
+    return tx.InsertUtterances(recordingID, utterances)
+  })
+}
+
+// insertUtterances stores the per-speaker utterances of a recording through the database or a transaction.
+func insertUtterances(e execer, recordingID int64, utterances []Utterance) error {
+  for i, utterance := range utterances {
+    _, err := e.Exec(`
+      INSERT INTO utterances (recording_id, position, speaker, text, start_ms, end_ms, confidence)
+      VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, recordingID, i, utterance.Speaker, utterance.Text, utterance.StartMs, utterance.EndMs, utterance.Confidence)
+    if err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// GetUtterancesByRecordingID retrieves the utterances of a recording in order.
+func GetUtterancesByRecordingID(recordingID int64) ([]Utterance, error) {
+  rows, err := db.Query(`
+    SELECT id, recording_id, position, speaker, text, start_ms, end_ms, confidence
+    FROM utterances WHERE recording_id = ? ORDER BY position
+  `, recordingID)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var utterances []Utterance
+  for rows.Next() {
+    var utterance Utterance
+    err := rows.Scan(&utterance.ID, &utterance.RecordingID, &utterance.Position, &utterance.Speaker, &utterance.Text, &utterance.StartMs, &utterance.EndMs, &utterance.Confidence)
+    if err != nil {
+      return nil, err
+    }
+    utterances = append(utterances, utterance)
+  }
+
+  return utterances, rows.Err()
+}
+
+// Job is a persisted background processing job for an uploaded recording.
+type Job struct {
+  ID          string     `json:"id"`
+  UserID      int64      `json:"user_id"`
+  Status      string     `json:"status"`
+  AudioPath   string     `json:"audio_path"`
+  RecordingID int64      `json:"recording_id,omitempty"`
+  Error       string     `json:"error,omitempty"`
+  CreatedAt   time.Time  `json:"created_at"`
+  UpdatedAt   time.Time  `json:"updated_at"`
+  Stages      []JobStage `json:"stages"`
+}
+
+// JobStage is the state of a single pipeline stage of a job.
+type JobStage struct {
+  Name       string     `json:"name"`
+  Status     string     `json:"status"`
+  Error      string     `json:"error,omitempty"`
+  Result     string     `json:"result,omitempty"`
+  StartedAt  *time.Time `json:"started_at,omitempty"`
+  FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// InsertJob inserts a new job together with its stages, all in the given initial statuses.
+func InsertJob(id string, userID int64, audioPath, status string, stages []string, stageStatus string) error {
+  tx, err := db.Begin()
+  if err != nil {
+    return err
+  }
+  defer tx.Rollback()
+
+  _, err = tx.Exec(`
+    INSERT INTO jobs (id, user_id, status, audio_path)
+    VALUES (?, ?, ?, ?)
+  `, id, userID, status, audioPath)
+  if err != nil {
+    return err
+  }
+
+  for i, name := range stages {
+    _, err := tx.Exec(`
+      INSERT INTO job_stages (job_id, position, name, status)
+      VALUES (?, ?, ?, ?)
+    `, id, i, name, stageStatus)
+    if err != nil {
+      return err
+    }
+  }
+
+  return tx.Commit()
+}
+
+// UpdateJobStatus updates the status and error message of a job.
+func UpdateJobStatus(id, status, errorMessage string) error {
+  _, err := db.Exec(`
+    UPDATE jobs SET status = ?, error = NULLIF(?, ''), updated_at = CURRENT_TIMESTAMP
+    WHERE id = ?
+  `, status, errorMessage, id)
+  return err
+}
+
+// SetJobRecording links a job to the recording it produced.
+func SetJobRecording(id string, recordingID int64) error {
+  _, err := db.Exec(`
+    UPDATE jobs SET recording_id = ?, updated_at = CURRENT_TIMESTAMP
+    WHERE id = ?
+  `, recordingID, id)
+  return err
+}
+
+// StartJobStage marks a job stage as started with the given status.
+func StartJobStage(jobID, name, status string) error {
+  _, err := db.Exec(`
+    UPDATE job_stages SET status = ?, error = NULL, result = NULL, started_at = CURRENT_TIMESTAMP, finished_at = NULL
+    WHERE job_id = ? AND name = ?
+  `, status, jobID, name)
+  return err
+}
+
+// FinishJobStage marks a job stage as finished with the given status, error message and result.
+func FinishJobStage(jobID, name, status, errorMessage, result string) error {
+  _, err := db.Exec(`
+    UPDATE job_stages SET status = ?, error = NULLIF(?, ''), result = NULLIF(?, ''), finished_at = CURRENT_TIMESTAMP
+    WHERE job_id = ? AND name = ?
+  `, status, errorMessage, result, jobID, name)
+  return err
+}
+
+// GetJobByID retrieves a job and its stages from the database by its ID.
+func GetJobByID(id string) (Job, error) {
+  var job Job
+  err := db.QueryRow(`
+    SELECT id, COALESCE(user_id, 0), status, audio_path, COALESCE(recording_id, 0), COALESCE(error, ''), created_at, updated_at
+    FROM jobs WHERE id = ?
+  `, id).Scan(&job.ID, &job.UserID, &job.Status, &job.AudioPath, &job.RecordingID, &job.Error, &job.CreatedAt, &job.UpdatedAt)
+  if err != nil {
+    return job, err
+  }
+
+  rows, err := db.Query(`
+    SELECT name, status, COALESCE(error, ''), COALESCE(result, ''), started_at, finished_at
+    FROM job_stages WHERE job_id = ? ORDER BY position
+  `, id)
+  if err != nil {
+    return job, err
+  }
+  defer rows.Close()
+
+  for rows.Next() {
+    var stage JobStage
+    var startedAt, finishedAt sql.NullTime
+    err := rows.Scan(&stage.Name, &stage.Status, &stage.Error, &stage.Result, &startedAt, &finishedAt)
+    if err != nil {
+      return job, err
+    }
+    if startedAt.Valid {
+      stage.StartedAt = &startedAt.Time
+    }
+    if finishedAt.Valid {
+      stage.FinishedAt = &finishedAt.Time
+    }
+    job.Stages = append(job.Stages, stage)
+  }
+
+  return job, rows.Err()
+}
+
+// GetUserJobByID retrieves a job of the user and its stages by its ID.
+// Jobs owned by other users are reported as sql.ErrNoRows.
+func GetUserJobByID(userID int64, id string) (Job, error) {
+  job, err := GetJobByID(id)
+  if err != nil {
+    return job, err
+  }
+  if job.UserID != userID {
+    return Job{}, sql.ErrNoRows
+  }
+
+  return job, nil
+}
+
+// GetJobIDsByStatus retrieves the IDs of all jobs in any of the given statuses, oldest first.
+func GetJobIDsByStatus(statuses ...string) ([]string, error) {
+  if len(statuses) == 0 {
+    return nil, nil
+  }
+
+  placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", ")
+  args := make([]interface{}, len(statuses))
+  for i, status := range statuses {
+    args[i] = status
+  }
+
+  rows, err := db.Query(`
+    SELECT id FROM jobs WHERE status IN (`+placeholders+`) ORDER BY created_at, rowid
+  `, args...)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var ids []string
+  for rows.Next() {
+    var id string
+    if err := rows.Scan(&id); err != nil {
+      return nil, err
+    }
+    ids = append(ids, id)
+  }
+
+  return ids, rows.Err()
+}
+
+// JobEvent is a persisted progress event of a job. Data holds the event payload as JSON.
+type JobEvent struct {
+  ID        int64     `json:"id"`
+  JobID     string    `json:"job_id"`
+  Type      string    `json:"type"`
+  Data      string    `json:"data"`
+  CreatedAt time.Time `json:"created_at"`
+}
+
+// InsertJobEvent inserts a new job event into the database and returns its ID.
+func InsertJobEvent(jobID, eventType, data string) (int64, error) {
+  result, err := db.Exec(`
+    INSERT INTO job_events (job_id, type, data)
+    VALUES (?, ?, ?)
+  `, jobID, eventType, data)
+  if err != nil {
+    return 0, err
+  }
+
+  id, err := result.LastInsertId()
+  if err != nil {
+    return 0, err
+  }
+
+  return id, nil
+}
+
+// GetJobEventsAfter retrieves the events of a job with an ID greater than afterID, in order.
+func GetJobEventsAfter(jobID string, afterID int64) ([]JobEvent, error) {
+  rows, err := db.Query(`
+    SELECT id, job_id, type, data, created_at
+    FROM job_events WHERE job_id = ? AND id > ? ORDER BY id
+  `, jobID, afterID)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var events []JobEvent
+  for rows.Next() {
+    var event JobEvent
+    if err := rows.Scan(&event.ID, &event.JobID, &event.Type, &event.Data, &event.CreatedAt); err != nil {
+      return nil, err
+    }
+    events = append(events, event)
+  }
+
+  return events, rows.Err()
+}
+
+// Ping checks that the database connection is alive.
+func Ping() error {
+  if db == nil {
+    return sql.ErrConnDone
+  }
+  return db.Ping()
+}
+
 // Close closes the SQLite database connection.
 func Close() error {
   if db != nil {
@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+  "database/sql"
+  "errors"
+  "path/filepath"
+  "testing"
+)
+
+func TestWithTxStoresRecordingAtomically(t *testing.T) {
+  if err := Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+    t.Fatal(err)
+  }
+  userID, err := InsertUser("Ana", "ana@example.com")
+  if err != nil {
+    t.Fatal(err)
+  }
+  segments := []Segment{{Text: "Send the budget.", EndMs: 1200, Words: []Word{{Text: "Send"}, {Text: "the"}, {Text: "budget."}}}}
+  utterances := []Utterance{{Speaker: "A", Text: "Send the budget.", EndMs: 1200}}
+  items := []ActionItem{{Owner: "Ana", Description: "Send the budget"}}
+
+  // A failure after the recording was inserted rolls back everything stored with it
+  var recordingID int64
+  failure := errors.New("failed to store action items")
+  err = WithTx(func(tx *Tx) error {
+    var err error
+    if recordingID, err = tx.InsertRecording(userID, "a.mp3", "Send the budget."); err != nil {
+      return err
+    }
+    if err := tx.InsertTranscript(recordingID, segments); err != nil {
+      return err
+    }
+    if err := tx.InsertUtterances(recordingID, utterances); err != nil {
+      return err
+    }
+    return failure
+  })
+  if err != failure {
+    t.Fatalf("WithTx = %v, want %v", err, failure)
+  }
+  if _, _, err := GetRecordingByID(userID, recordingID); !errors.Is(err, sql.ErrNoRows) {
+    t.Errorf("GetRecordingByID after rollback = %v, want sql.ErrNoRows", err)
+  }
+  if segments, err := GetSegmentsByRecordingID(recordingID); err != nil || len(segments) != 0 {
+    t.Errorf("segments after rollback = %v, %v, want none", segments, err)
+  }
+  if utterances, err := GetUtterancesByRecordingID(recordingID); err != nil || len(utterances) != 0 {
+    t.Errorf("utterances after rollback = %v, %v, want none", utterances, err)
+  }
+
+  err = WithTx(func(tx *Tx) error {
+    var err error
+    if recordingID, err = tx.InsertRecording(userID, "a.mp3", "Send the budget."); err != nil {
+      return err
+    }
+    if err := tx.UpdateRecordingDetails(recordingID, "A budget.", []string{"budget"}, 1200); err != nil {
+      return err
+    }
+    if err := tx.InsertTranscript(recordingID, segments); err != nil {
+      return err
+    }
+    if err := tx.InsertUtterances(recordingID, utterances); err != nil {
+      return err
+    }
+    return tx.ReplaceRecordingActions(userID, recordingID, items, nil)
+  })
+  if err != nil {
+    t.Fatal(err)
+  }
+  recordings, err := GetRecordingsByIDs(userID, []int64{recordingID})
+  if err != nil || len(recordings) != 1 || recordings[0].Summary != "A budget." || recordings[0].DurationMs != 1200 {
+    t.Fatalf("recordings = %+v, %v, want the stored recording", recordings, err)
+  }
+  if segments, err := GetSegmentsByRecordingID(recordingID); err != nil || len(segments) != 1 || len(segments[0].Words) != 3 {
+    t.Errorf("segments = %+v, %v, want 1 with 3 words", segments, err)
+  }
+  if utterances, err := GetUtterancesByRecordingID(recordingID); err != nil || len(utterances) != 1 {
+    t.Errorf("utterances = %+v, %v, want 1", utterances, err)
+  }
+  if stored, err := GetActionItems(userID, ActionItemQuery{RecordingID: recordingID}); err != nil || len(stored) != 1 {
+    t.Errorf("action items = %+v, %v, want 1", stored, err)
+  }
+}
@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+  "time"
+)
+
+// User owns recordings and the knowledge graph built from them.
+// Timezone is the IANA name of the timezone the user records in, "UTC" unless set.
+type User struct {
+  ID        int64     `json:"id"`
+  Name      string    `json:"name"`
+  Email     string    `json:"email,omitempty"`
+  Timezone  string    `json:"timezone"`
+  CreatedAt time.Time `json:"created_at"`
+}
+
+// Location returns the timezone of the user, or UTC if it is not a known timezone.
+func (u User) Location() *time.Location {
+  location, err := time.LoadLocation(u.Timezone)
+  if err != nil {
+    return time.UTC
+  }
+  return location
+}
+
+// InsertUser inserts a new user into the database and returns its ID.
+func InsertUser(name, email string) (int64, error) {
+  result, err := db.Exec(`
+    INSERT INTO users (name, email)
+    VALUES (?, NULLIF(?, ''))
+  `, name, email)
+  if err != nil {
+    return 0, err
+  }
+
+  id, err := result.LastInsertId()
+  if err != nil {
+    return 0, err
+  }
+
+  return id, nil
+}
+
+// SetUserTimezone changes the timezone of a user, which must be a valid IANA name.
+// It returns sql.ErrNoRows if there is no such user.
+func SetUserTimezone(id int64, timezone string) error {
+  result, err := db.Exec(`
+    UPDATE users SET timezone = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+  `, timezone, id)
+  if err != nil {
+    return err
+  }
+  return checkAffected(result)
+}
+
+// GetUserByID retrieves a user from the database by its ID.
+func GetUserByID(id int64) (User, error) {
+  var user User
+  err := db.QueryRow(`
+    SELECT id, name, COALESCE(email, ''), timezone, created_at FROM users WHERE id = ?
+  `, id).Scan(&user.ID, &user.Name, &user.Email, &user.Timezone, &user.CreatedAt)
+  if err != nil {
+    return User{}, err
+  }
+
+  return user, nil
+}
+
+// GetUsers retrieves all users, ordered by ID.
+func GetUsers() ([]User, error) {
+  rows, err := db.Query(`
+    SELECT id, name, COALESCE(email, ''), timezone, created_at FROM users ORDER BY id
+  `)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var users []User
+  for rows.Next() {
+    var user User
+    if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Timezone, &user.CreatedAt); err != nil {
+      return nil, err
+    }
+    users = append(users, user)
+  }
+
+  return users, rows.Err()
+}
@@ -0,0 +1,15 @@
+// Package timecode formats positions in recorded audio.
+package timecode
+
+import (
+  "fmt"
+)
+
+// Format formats an offset into the audio in milliseconds as m:ss, or h:mm:ss past an hour.
+func Format(ms int64) string {
+  seconds := ms / 1000
+  if seconds >= 3600 {
+    return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+  }
+  return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,21 @@
+package timecode
+
+import (
+  "testing"
+)
+
+func TestFormat(t *testing.T) {
+  tests := map[int64]string{
+    0:       "0:00",
+    999:     "0:00",
+    65000:   "1:05",
+    3599999: "59:59",
+    3600000: "1:00:00",
+    7384000: "2:03:04",
+  }
+  for ms, want := range tests {
+    if got := Format(ms); got != want {
+      t.Errorf("Format(%d) = %q, want %q", ms, got, want)
+    }
+  }
+}
@@ -0,0 +1,7 @@
+#!/bin/sh
+# Reports and applies database schema migrations.
+# Usage: scripts/migrate.sh [-db path] [status | up [-to version] | down [steps]]
+set -e
+
+cd "$(dirname "$0")/.."
+exec go run -tags sqlite_fts5 . migrate "$@"
@@ -0,0 +1,222 @@
+package actions
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "strconv"
+  "strings"
+  "time"
+
+  "voice-notetaking-app/pkg/timecode"
+  "voice-notetaking-app/service/llm"
+  "voice-notetaking-app/service/summarization"
+)
+
+// DefaultMaxInputTokens is the size of the batches of transcript lines sent to the model.
+const DefaultMaxInputTokens = 3000
+
+// Line is a line of a transcript, such as a sentence, starting StartMs milliseconds into the audio.
+type Line struct {
+  StartMs int64
+  Speaker string
+  Text    string
+}
+
+// ActionItem is a task someone committed to or was asked to do. DueDate is set when the
+// due date phrase could be resolved to a day.
+type ActionItem struct {
+  Owner       string     `json:"owner"`
+  Description string     `json:"description"`
+  DuePhrase   string     `json:"due_phrase,omitempty"`
+  DueDate     *time.Time `json:"due_date,omitempty"`
+  SourceMs    int64      `json:"source_ms"`
+}
+
+// Decision is something that was agreed or settled.
+type Decision struct {
+  Description string `json:"description"`
+  SourceMs    int64  `json:"source_ms"`
+}
+
+// Result holds what was extracted from a transcript.
+type Result struct {
+  ActionItems []ActionItem `json:"action_items"`
+  Decisions   []Decision   `json:"decisions"`
+}
+
+// Extractor extracts action items and decisions from transcripts with a language model.
+// The transcript is sent as numbered lines so each item can be traced back to the moment
+// in the recording it comes from.
+type Extractor struct {
+  LLM llm.Client
+
+  // MaxInputTokens bounds the lines sent in a single request; longer transcripts are sent in batches.
+  MaxInputTokens int
+}
+
+// New creates an extractor using the given language model client.
+func New(client llm.Client) *Extractor {
+  return &Extractor{LLM: client, MaxInputTokens: DefaultMaxInputTokens}
+}
+
+// extractionSchema is the JSON schema the model's answer must follow.
+const extractionSchema = `{
+  "type": "object",
+  "properties": {
+    "action_items": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "owner": {"type": "string", "description": "who will do it, as named or labelled in the transcript, or empty if unknown"},
+          "description": {"type": "string", "description": "the task, starting with a verb"},
+          "due": {"type": "string", "description": "the due date exactly as said, such as \"next Tuesday\", or empty"},
+          "line": {"type": "integer", "description": "number of the line it is mentioned in"}
+        },
+        "required": ["owner", "description", "due", "line"]
+      }
+    },
+    "decisions": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "description": {"type": "string"},
+          "line": {"type": "integer"}
+        },
+        "required": ["description", "line"]
+      }
+    }
+  },
+  "required": ["action_items", "decisions"]
+}`
+
+// Extract extracts the action items and decisions of a transcript recorded at the given time,
+// which due date phrases are resolved against.
+func (e *Extractor) Extract(ctx context.Context, lines []Line, recordedAt time.Time) (*Result, error) {
+  result := &Result{ActionItems: []ActionItem{}, Decisions: []Decision{}}
+  for _, batch := range e.batches(lines) {
+    if err := e.extractBatch(ctx, lines, batch, recordedAt, result); err != nil {
+      return nil, err
+    }
+  }
+  return result, nil
+}
+
+// lineBatch is a range of lines sent to the model together.
+type lineBatch struct {
+  start, end int
+}
+
+// batches splits the lines into consecutive batches within the input budget.
+func (e *Extractor) batches(lines []Line) []lineBatch {
+  budget := e.MaxInputTokens
+  if budget <= 0 {
+    budget = DefaultMaxInputTokens
+  }
+
+  var batches []lineBatch
+  start, tokens := 0, 0
+  for i, line := range lines {
+    lineTokens := summarization.EstimateTokens(line.Text) + 8
+    if i > start && tokens+lineTokens > budget {
+      batches = append(batches, lineBatch{start, i})
+      start, tokens = i, 0
+    }
+    tokens += lineTokens
+  }
+  if start < len(lines) {
+    batches = append(batches, lineBatch{start, len(lines)})
+  }
+  return batches
+}
+
+// extractBatch asks the model about one batch of lines and appends what it found to the result.
+func (e *Extractor) extractBatch(ctx context.Context, lines []Line, batch lineBatch, recordedAt time.Time, result *Result) error {
+  systemPrompt := `
+    You are an AI assistant extracting action items and decisions from a voice note transcript.
+    Each line starts with its number in brackets, the time into the recording and, when known, the speaker.
+    An action item is a task someone committed to or was asked to do; a decision is something agreed or settled.
+    Only include what is stated in the transcript. Keep due dates as they were said; do not convert them to dates.
+    Answer with a single JSON object that matches this JSON schema, and nothing else:
+  ` + extractionSchema
+
+  var b strings.Builder
+  fmt.Fprintf(&b, "Recorded on %s\n\n", recordedAt.Format("Monday, January 2, 2006"))
+  for i := batch.start; i < batch.end; i++ {
+    line := lines[i]
+    fmt.Fprintf(&b, "[%d] %s ", i+1, timecode.Format(line.StartMs))
+    if line.Speaker != "" {
+      fmt.Fprintf(&b, "Speaker %s: ", line.Speaker)
+    }
+    fmt.Fprintln(&b, line.Text)
+  }
+
+  messages := []llm.Message{
+    {
+      Role:    llm.RoleSystem,
+      Content: systemPrompt,
+    },
+    {
+      Role:    llm.RoleUser,
+      Content: b.String(),
+    },
+  }
+
+  raw, err := e.LLM.Chat(ctx, messages, llm.WithJSON(), llm.WithTemperature(0))
+  if err != nil {
+    return err
+  }
+
+  var response struct {
+    ActionItems []struct {
+      Owner       string      `json:"owner"`
+      Description string      `json:"description"`
+      Due         string      `json:"due"`
+      Line        json.Number `json:"line"`
+    } `json:"action_items"`
+    Decisions []struct {
+      Description string      `json:"description"`
+      Line        json.Number `json:"line"`
+    } `json:"decisions"`
+  }
+  if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &response); err != nil {
+    return fmt.Errorf("failed to parse extraction JSON: %v", err)
+  }
+
+  // Lines outside the batch are the model's mistake; fall back to the start of the batch
+  sourceMs := func(number json.Number) int64 {
+    n, err := strconv.Atoi(number.String())
+    if err != nil || n < batch.start+1 || n > batch.end {
+      return lines[batch.start].StartMs
+    }
+    return lines[n-1].StartMs
+  }
+
+  for _, item := range response.ActionItems {
+    description := strings.TrimSpace(item.Description)
+    if description == "" {
+      continue
+    }
+    actionItem := ActionItem{
+      Owner:       strings.TrimSpace(item.Owner),
+      Description: description,
+      DuePhrase:   strings.TrimSpace(item.Due),
+      SourceMs:    sourceMs(item.Line),
+    }
+    if due, ok := ResolveDate(actionItem.DuePhrase, recordedAt); ok {
+      actionItem.DueDate = &due
+    }
+    result.ActionItems = append(result.ActionItems, actionItem)
+  }
+  for _, decision := range response.Decisions {
+    description := strings.TrimSpace(decision.Description)
+    if description == "" {
+      continue
+    }
+    result.Decisions = append(result.Decisions, Decision{Description: description, SourceMs: sourceMs(decision.Line)})
+  }
+
+  return nil
+}
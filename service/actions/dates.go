@@ -0,0 +1,220 @@
+package actions
+
+import (
+  "regexp"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// numberWords are the spelled out numbers accepted in relative phrases such as "in two weeks".
+var numberWords = map[string]int{
+  "a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+  "seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+}
+
+var weekdays = map[string]time.Weekday{
+  "sunday": time.Sunday, "sun": time.Sunday,
+  "monday": time.Monday, "mon": time.Monday,
+  "tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+  "wednesday": time.Wednesday, "wed": time.Wednesday,
+  "thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+  "friday": time.Friday, "fri": time.Friday,
+  "saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var months = map[string]time.Month{
+  "january": time.January, "jan": time.January,
+  "february": time.February, "feb": time.February,
+  "march": time.March, "mar": time.March,
+  "april": time.April, "apr": time.April,
+  "may":  time.May,
+  "june": time.June, "jun": time.June,
+  "july": time.July, "jul": time.July,
+  "august": time.August, "aug": time.August,
+  "september": time.September, "sep": time.September, "sept": time.September,
+  "october": time.October, "oct": time.October,
+  "november": time.November, "nov": time.November,
+  "december": time.December, "dec": time.December,
+}
+
+var (
+  relativePattern  = regexp.MustCompile(`^in (\d+|[a-z]+) (day|week|month)s?$`)
+  isoDatePattern   = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+  slashDatePattern = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})(?:/(\d{2}|\d{4}))?$`)
+  monthDayPattern  = regexp.MustCompile(`^([a-z]+)\.? (\d{1,2})(?:st|nd|rd|th)?(?:,? (\d{4}))?$`)
+  dayMonthPattern  = regexp.MustCompile(`^(?:the )?(\d{1,2})(?:st|nd|rd|th)? (?:of )?([a-z]+)\.?(?:,? (\d{4}))?$`)
+)
+
+// duePrefixes are words introducing a due date that carry no meaning of their own.
+var duePrefixes = []string{"due ", "by ", "before ", "until ", "no later than ", "on ", "at "}
+
+// ResolveDate resolves a due date phrase such as "Tuesday", "tomorrow", "end of the week",
+// "in two weeks" or "March 5th" to a day, relative to the time it was said. Weekdays refer to
+// the next such day after ref, "next <weekday>" to that day in the following week, and dates
+// without a year to their next occurrence. Dates with slashes are read month first.
+// It returns false when the phrase is not understood.
+func ResolveDate(phrase string, ref time.Time) (time.Time, bool) {
+  phrase = normalizePhrase(phrase)
+  today := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, ref.Location())
+
+  switch phrase {
+  case "":
+    return time.Time{}, false
+  case "today", "tonight", "end of day", "end of the day", "eod", "this evening", "this afternoon", "this morning":
+    return today, true
+  case "tomorrow", "tomorrow morning", "tomorrow evening":
+    return today.AddDate(0, 0, 1), true
+  case "day after tomorrow", "the day after tomorrow":
+    return today.AddDate(0, 0, 2), true
+  case "end of week", "end of the week", "eow", "this week":
+    friday := today.AddDate(0, 0, int(time.Friday-today.Weekday()))
+    if friday.Before(today) {
+      return today, true
+    }
+    return friday, true
+  case "next week", "early next week", "beginning of next week":
+    return startOfWeek(today).AddDate(0, 0, 7), true
+  case "end of next week":
+    return startOfWeek(today).AddDate(0, 0, 11), true
+  case "end of month", "end of the month", "eom", "this month":
+    return time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, today.Location()), true
+  case "next month", "beginning of next month", "early next month":
+    return time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location()), true
+  case "end of next month":
+    return time.Date(today.Year(), today.Month()+2, 0, 0, 0, 0, 0, today.Location()), true
+  }
+
+  if weekday, ok := weekdays[phrase]; ok {
+    return nextWeekday(today, weekday), true
+  }
+  if name, ok := cutPrefix(phrase, "this "); ok {
+    if weekday, ok := weekdays[name]; ok {
+      days := (int(weekday) - int(today.Weekday()) + 7) % 7
+      return today.AddDate(0, 0, days), true
+    }
+  }
+  if name, ok := cutPrefix(phrase, "next "); ok {
+    if weekday, ok := weekdays[name]; ok {
+      offset := (int(weekday) + 6) % 7 // days from Monday
+      return startOfWeek(today).AddDate(0, 0, 7+offset), true
+    }
+  }
+
+  if m := relativePattern.FindStringSubmatch(phrase); m != nil {
+    n, ok := parseCount(m[1])
+    if !ok {
+      return time.Time{}, false
+    }
+    switch m[2] {
+    case "day":
+      return today.AddDate(0, 0, n), true
+    case "week":
+      return today.AddDate(0, 0, 7*n), true
+    default:
+      return today.AddDate(0, n, 0), true
+    }
+  }
+
+  if m := isoDatePattern.FindStringSubmatch(phrase); m != nil {
+    year, _ := strconv.Atoi(m[1])
+    month, _ := strconv.Atoi(m[2])
+    day, _ := strconv.Atoi(m[3])
+    return makeDate(year, time.Month(month), day, today.Location())
+  }
+  if m := slashDatePattern.FindStringSubmatch(phrase); m != nil {
+    month, _ := strconv.Atoi(m[1])
+    day, _ := strconv.Atoi(m[2])
+    return datedOrNext(m[3], time.Month(month), day, today)
+  }
+  if m := monthDayPattern.FindStringSubmatch(phrase); m != nil {
+    if month, ok := months[m[1]]; ok {
+      day, _ := strconv.Atoi(m[2])
+      return datedOrNext(m[3], month, day, today)
+    }
+  }
+  if m := dayMonthPattern.FindStringSubmatch(phrase); m != nil {
+    if month, ok := months[m[2]]; ok {
+      day, _ := strconv.Atoi(m[1])
+      return datedOrNext(m[3], month, day, today)
+    }
+  }
+
+  return time.Time{}, false
+}
+
+// normalizePhrase lower-cases the phrase, collapses whitespace and strips introducing words and punctuation.
+func normalizePhrase(phrase string) string {
+  phrase = strings.Join(strings.Fields(strings.ToLower(phrase)), " ")
+  phrase = strings.Trim(phrase, " .,;:!?")
+  for stripped := true; stripped; {
+    stripped = false
+    for _, prefix := range duePrefixes {
+      if rest, ok := cutPrefix(phrase, prefix); ok {
+        phrase, stripped = rest, true
+      }
+    }
+  }
+  return phrase
+}
+
+// cutPrefix returns s without the prefix and whether it was there.
+func cutPrefix(s, prefix string) (string, bool) {
+  if !strings.HasPrefix(s, prefix) {
+    return s, false
+  }
+  return s[len(prefix):], true
+}
+
+// parseCount parses a digit or spelled out count.
+func parseCount(s string) (int, bool) {
+  if n, ok := numberWords[s]; ok {
+    return n, true
+  }
+  n, err := strconv.Atoi(s)
+  return n, err == nil && n >= 0 && n <= 1000
+}
+
+// startOfWeek returns the Monday of the week of day.
+func startOfWeek(day time.Time) time.Time {
+  offset := (int(day.Weekday()) + 6) % 7
+  return day.AddDate(0, 0, -offset)
+}
+
+// nextWeekday returns the first day after today falling on the weekday.
+func nextWeekday(today time.Time, weekday time.Weekday) time.Time {
+  days := (int(weekday) - int(today.Weekday()) + 7) % 7
+  if days == 0 {
+    days = 7
+  }
+  return today.AddDate(0, 0, days)
+}
+
+// datedOrNext builds the date in the given year, or in the year of its next occurrence when the year is empty.
+func datedOrNext(yearPart string, month time.Month, day int, today time.Time) (time.Time, bool) {
+  if yearPart != "" {
+    year, _ := strconv.Atoi(yearPart)
+    if year < 100 {
+      year += 2000
+    }
+    return makeDate(year, month, day, today.Location())
+  }
+
+  date, ok := makeDate(today.Year(), month, day, today.Location())
+  if ok && date.Before(today) {
+    date, ok = makeDate(today.Year()+1, month, day, today.Location())
+  }
+  return date, ok
+}
+
+// makeDate builds a date, rejecting days that do not exist such as February 30th.
+func makeDate(year int, month time.Month, day int, location *time.Location) (time.Time, bool) {
+  if month < time.January || month > time.December || day < 1 {
+    return time.Time{}, false
+  }
+  date := time.Date(year, month, day, 0, 0, 0, 0, location)
+  if date.Month() != month || date.Day() != day {
+    return time.Time{}, false
+  }
+  return date, true
+}
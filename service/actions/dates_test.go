@@ -0,0 +1,103 @@
+package actions
+
+import (
+  "testing"
+  "time"
+  _ "time/tzdata"
+)
+
+func TestResolveDate(t *testing.T) {
+  // Wednesday
+  ref := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+  tests := []struct {
+    phrase string
+    want   string
+  }{
+    {"today", "2026-10-14"},
+    {"by EOD", "2026-10-14"},
+    {"Tomorrow", "2026-10-15"},
+    {"the day after tomorrow", "2026-10-16"},
+    {"end of the week", "2026-10-16"},
+    {"next week", "2026-10-19"},
+    {"end of next week", "2026-10-23"},
+    {"end of month", "2026-10-31"},
+    {"next month", "2026-11-01"},
+    {"end of next month", "2026-11-30"},
+    {"Friday", "2026-10-16"},
+    {"Wednesday", "2026-10-21"},
+    {"this wednesday", "2026-10-14"},
+    {"next Tuesday", "2026-10-20"},
+    {"next friday", "2026-10-23"},
+    {"in two weeks", "2026-10-28"},
+    {"in 3 days", "2026-10-17"},
+    {"in a month", "2026-11-14"},
+    {"2026-11-02", "2026-11-02"},
+    {"11/2", "2026-11-02"},
+    {"10/1", "2027-10-01"},
+    {"3/5/27", "2027-03-05"},
+    {"March 5th", "2027-03-05"},
+    {"Oct. 20", "2026-10-20"},
+    {"the 5th of December", "2026-12-05"},
+    {"December 5, 2026", "2026-12-05"},
+    {"Due by Friday.", "2026-10-16"},
+    {"no later than  the 1st of november", "2026-11-01"},
+  }
+  for _, tt := range tests {
+    got, ok := ResolveDate(tt.phrase, ref)
+    if !ok {
+      t.Errorf("ResolveDate(%q) was not understood, want %s", tt.phrase, tt.want)
+      continue
+    }
+    if got.Format("2006-01-02") != tt.want || got.Hour() != 0 || got.Location() != time.UTC {
+      t.Errorf("ResolveDate(%q) = %s, want %s at midnight UTC", tt.phrase, got, tt.want)
+    }
+  }
+}
+
+func TestResolveDateNotUnderstood(t *testing.T) {
+  ref := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+  for _, phrase := range []string{"", "soon", "someday", "February 30", "13/1", "2026-02-29", "in many weeks", "next year"} {
+    if got, ok := ResolveDate(phrase, ref); ok {
+      t.Errorf("ResolveDate(%q) = %s, want it not understood", phrase, got)
+    }
+  }
+}
+
+func TestResolveDateEndOfWeekOnWeekend(t *testing.T) {
+  saturday := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+  got, ok := ResolveDate("end of week", saturday)
+  if !ok || got.Format("2006-01-02") != "2026-10-17" {
+    t.Errorf("ResolveDate(end of week) on a Saturday = %s, %v, want the same day", got, ok)
+  }
+}
+
+func TestResolveDateInLocation(t *testing.T) {
+  newYork, err := time.LoadLocation("America/New_York")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  // Late on Tuesday evening in New York, when it is already Wednesday in UTC
+  ref := time.Date(2026, 10, 14, 3, 30, 0, 0, time.UTC).In(newYork)
+  tests := []struct {
+    phrase string
+    want   string
+  }{
+    {"today", "2026-10-13"},
+    {"tomorrow", "2026-10-14"},
+    {"wednesday", "2026-10-14"},
+    {"end of the month", "2026-10-31"},
+  }
+  for _, tt := range tests {
+    got, ok := ResolveDate(tt.phrase, ref)
+    if !ok || got.Format("2006-01-02") != tt.want || got.Location() != newYork {
+      t.Errorf("ResolveDate(%q) = %s, %v, want %s in New York", tt.phrase, got, ok, tt.want)
+    }
+  }
+
+  // Days keep their date across the end of daylight saving time
+  got, ok := ResolveDate("in three weeks", ref)
+  if !ok || got.Format("2006-01-02 15:04 MST") != "2026-11-03 00:00 EST" {
+    t.Errorf("ResolveDate(in three weeks) = %s, %v, want midnight on November 3 EST", got, ok)
+  }
+}
@@ -0,0 +1,325 @@
+package ask
+
+import (
+  "context"
+  "fmt"
+  "regexp"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+  "unicode"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/pkg/timecode"
+  "voice-notetaking-app/service/llm"
+  "voice-notetaking-app/service/summarization"
+)
+
+// Defaults used when the Answerer fields are zero.
+const (
+  DefaultMaxContextTokens = 3000
+  DefaultMaxRecordings    = 5
+  DefaultMaxRelated       = 3
+)
+
+// NotFoundAnswer is returned without asking the model when no recording matches the question.
+const NotFoundAnswer = "I could not find anything in your notes about that."
+
+// untimedPassageTokens is the size of the passages a recording without timed segments is split into.
+const untimedPassageTokens = 150
+
+// Passage is an excerpt of a recording that may be given to the model as context.
+// Times are in milliseconds; Timed is false when the excerpt could not be aligned with the audio.
+type Passage struct {
+  RecordingID int64     `json:"recording_id"`
+  RecordedAt  time.Time `json:"recorded_at"`
+  StartMs     int64     `json:"start_ms"`
+  EndMs       int64     `json:"end_ms"`
+  Timed       bool      `json:"-"`
+  Text        string    `json:"text"`
+
+  rank  int
+  score int
+}
+
+// Citation is a passage the answer relies on.
+type Citation struct {
+  Label string `json:"label"`
+  Passage
+}
+
+// Answer is the model's answer with the passages it cites.
+type Answer struct {
+  Question   string     `json:"question"`
+  Answer     string     `json:"answer"`
+  Citations  []Citation `json:"citations"`
+  Recordings []int64    `json:"recordings"`
+}
+
+// Answerer answers questions about a user's notes. It retrieves the recordings matching the
+// question by full-text search, adds recordings connected to them in the knowledge graph, and
+// asks the model to answer from the most relevant passages only, citing each one it uses.
+type Answerer struct {
+  LLM llm.Client
+
+  MaxContextTokens int
+  MaxRecordings    int
+  MaxRelated       int
+}
+
+// New creates an answerer using the given language model client.
+func New(client llm.Client) *Answerer {
+  return &Answerer{
+    LLM:              client,
+    MaxContextTokens: DefaultMaxContextTokens,
+    MaxRecordings:    DefaultMaxRecordings,
+    MaxRelated:       DefaultMaxRelated,
+  }
+}
+
+// Ask answers the question from the user's recordings.
+func (a *Answerer) Ask(ctx context.Context, userID int64, question string) (*Answer, error) {
+  passages, err := a.Retrieve(userID, question)
+  if err != nil {
+    return nil, err
+  }
+  if len(passages) == 0 {
+    return &Answer{Question: question, Answer: NotFoundAnswer, Citations: []Citation{}, Recordings: []int64{}}, nil
+  }
+
+  labels := make(map[string]Passage, len(passages))
+  var excerpts strings.Builder
+  for i, passage := range passages {
+    label := "P" + strconv.Itoa(i+1)
+    labels[label] = passage
+    fmt.Fprintf(&excerpts, "[%s] %s\n%s\n\n", label, describe(passage), passage.Text)
+  }
+
+  systemPrompt := `
+    You answer questions about the user's own voice notes using only the excerpts provided.
+    Each excerpt starts with a label in square brackets, such as [P1], followed by the recording
+    it comes from. After every statement, cite the excerpts supporting it by their labels in
+    square brackets, for example "The budget was approved [P2][P5]." Do not cite anything else.
+    If the excerpts do not answer the question, say so instead of guessing.
+  `
+  messages := []llm.Message{
+    {Role: llm.RoleSystem, Content: systemPrompt},
+    {Role: llm.RoleUser, Content: "Excerpts:\n\n" + excerpts.String() + "Question: " + question},
+  }
+
+  reply, err := a.LLM.Chat(ctx, messages, llm.WithTemperature(0))
+  if err != nil {
+    return nil, err
+  }
+
+  answer := &Answer{Question: question, Recordings: []int64{}}
+  answer.Answer, answer.Citations = resolveCitations(reply, labels)
+  seen := make(map[int64]bool)
+  for _, citation := range answer.Citations {
+    if !seen[citation.RecordingID] {
+      seen[citation.RecordingID] = true
+      answer.Recordings = append(answer.Recordings, citation.RecordingID)
+    }
+  }
+
+  return answer, nil
+}
+
+// Retrieve returns the passages of the user's recordings most relevant to the question that fit
+// in the context budget, grouped by recording in order of relevance and in time order within each.
+func (a *Answerer) Retrieve(userID int64, question string) ([]Passage, error) {
+  maxRecordings := a.MaxRecordings
+  if maxRecordings <= 0 {
+    maxRecordings = DefaultMaxRecordings
+  }
+
+  matches, err := sqlite.SearchRecordings(userID, sqlite.SearchQuery{Text: question, AnyWord: true, Limit: maxRecordings})
+  if err != nil {
+    return nil, fmt.Errorf("failed to search recordings: %v", err)
+  }
+  ids := make([]int64, 0, len(matches))
+  for _, match := range matches {
+    ids = append(ids, match.RecordingID)
+  }
+
+  related, err := sqlite.GetRelatedRecordingIDs(userID, ids, a.MaxRelated)
+  if err != nil {
+    return nil, fmt.Errorf("failed to get related recordings: %v", err)
+  }
+  ids = append(ids, related...)
+
+  recordings, err := sqlite.GetRecordingsByIDs(userID, ids)
+  if err != nil {
+    return nil, fmt.Errorf("failed to get recordings: %v", err)
+  }
+
+  terms := questionTerms(question)
+  var candidates []Passage
+  for rank, recording := range recordings {
+    passages, err := recordingPassages(recording)
+    if err != nil {
+      return nil, err
+    }
+    for _, passage := range passages {
+      passage.rank = rank
+      passage.score = overlap(terms, passage.Text)
+      candidates = append(candidates, passage)
+    }
+  }
+
+  return a.fit(candidates), nil
+}
+
+// fit picks the passages sharing the most words with the question, preferring better ranked
+// recordings, until the context budget is spent. Passages without any word in common are only
+// used to fill what is left.
+func (a *Answerer) fit(candidates []Passage) []Passage {
+  budget := a.MaxContextTokens
+  if budget <= 0 {
+    budget = DefaultMaxContextTokens
+  }
+
+  sort.SliceStable(candidates, func(i, j int) bool {
+    if candidates[i].score != candidates[j].score {
+      return candidates[i].score > candidates[j].score
+    }
+    return candidates[i].rank < candidates[j].rank
+  })
+
+  var picked []Passage
+  for _, passage := range candidates {
+    tokens := summarization.EstimateTokens(passage.Text) + 16
+    if tokens > budget {
+      continue
+    }
+    budget -= tokens
+    picked = append(picked, passage)
+  }
+
+  sort.SliceStable(picked, func(i, j int) bool {
+    if picked[i].rank != picked[j].rank {
+      return picked[i].rank < picked[j].rank
+    }
+    return picked[i].StartMs < picked[j].StartMs
+  })
+  return picked
+}
+
+// recordingPassages splits a recording into its timed transcript segments, or into untimed
+// chunks of its transcription when no segments were stored.
+func recordingPassages(recording sqlite.Recording) ([]Passage, error) {
+  segments, err := sqlite.GetSegmentsByRecordingID(recording.ID)
+  if err != nil {
+    return nil, fmt.Errorf("failed to get transcript segments: %v", err)
+  }
+
+  var passages []Passage
+  for _, segment := range segments {
+    passages = append(passages, Passage{
+      RecordingID: recording.ID,
+      RecordedAt:  recording.CreatedAt,
+      StartMs:     segment.StartMs,
+      EndMs:       segment.EndMs,
+      Timed:       true,
+      Text:        segment.Text,
+    })
+  }
+  if len(passages) > 0 {
+    return passages, nil
+  }
+
+  for _, text := range summarization.Chunk(recording.Transcription, untimedPassageTokens, 0) {
+    passages = append(passages, Passage{
+      RecordingID: recording.ID,
+      RecordedAt:  recording.CreatedAt,
+      Text:        text,
+    })
+  }
+  return passages, nil
+}
+
+// citationPattern matches runs of passage labels such as [P1] or [P1, P3], with the space before them.
+var citationPattern = regexp.MustCompile(`\s*\[P\d+(?:\s*,\s*P\d+)*\]`)
+
+// resolveCitations replaces the passage labels in the reply with the recording and timestamp they
+// stand for and returns the cited passages in order of first use. Labels the model made up are dropped.
+func resolveCitations(reply string, labels map[string]Passage) (string, []Citation) {
+  citations := []Citation{}
+  cited := make(map[string]bool)
+
+  text := citationPattern.ReplaceAllStringFunc(reply, func(match string) string {
+    labelList := strings.TrimLeftFunc(match, unicode.IsSpace)
+    space := match[:len(match)-len(labelList)]
+
+    var refs []string
+    for _, label := range strings.Split(labelList[1:len(labelList)-1], ",") {
+      label = strings.TrimSpace(label)
+      passage, ok := labels[label]
+      if !ok {
+        continue
+      }
+      if !cited[label] {
+        cited[label] = true
+        citations = append(citations, Citation{Label: label, Passage: passage})
+      }
+      refs = append(refs, reference(passage))
+    }
+    if len(refs) == 0 {
+      return ""
+    }
+    return space + "[" + strings.Join(refs, "; ") + "]"
+  })
+
+  return strings.TrimSpace(text), citations
+}
+
+// describe introduces a passage to the model with its recording, date and time offset.
+func describe(passage Passage) string {
+  return fmt.Sprintf("%s, recorded %s", reference(passage), passage.RecordedAt.Format("2006-01-02 15:04"))
+}
+
+// reference names the recording of a passage and, when known, its offset into the audio.
+func reference(passage Passage) string {
+  if !passage.Timed {
+    return fmt.Sprintf("recording %d", passage.RecordingID)
+  }
+  return fmt.Sprintf("recording %d at %s", passage.RecordingID, timecode.Format(passage.StartMs))
+}
+
+// stopWords are frequent question words that say nothing about which passage is relevant.
+var stopWords = map[string]bool{
+  "and": true, "are": true, "did": true, "does": true, "for": true, "from": true, "has": true,
+  "have": true, "how": true, "the": true, "that": true, "this": true, "was": true, "were": true,
+  "what": true, "when": true, "where": true, "which": true, "who": true, "why": true, "with": true,
+}
+
+// questionTerms returns the distinct lower case words of the question longer than two letters,
+// leaving out stop words.
+func questionTerms(question string) map[string]bool {
+  terms := make(map[string]bool)
+  for _, word := range splitWords(question) {
+    if len(word) > 2 && !stopWords[word] {
+      terms[word] = true
+    }
+  }
+  return terms
+}
+
+// overlap counts the distinct question terms found in the text.
+func overlap(terms map[string]bool, text string) int {
+  found := make(map[string]bool)
+  for _, word := range splitWords(text) {
+    if terms[word] {
+      found[word] = true
+    }
+  }
+  return len(found)
+}
+
+// splitWords splits text into lower case words of letters and digits.
+func splitWords(text string) []string {
+  return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+    return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+  })
+}
@@ -0,0 +1,38 @@
+package ask
+
+import (
+  "encoding/json"
+  "strings"
+  "testing"
+)
+
+func TestResolveCitations(t *testing.T) {
+  labels := map[string]Passage{
+    "P1": {RecordingID: 7, StartMs: 65000, Timed: true, Text: "The budget is due Friday."},
+    "P2": {RecordingID: 9, Text: "Hiring is on hold."},
+  }
+
+  text, citations := resolveCitations("The budget is due Friday [P1]. Hiring waits [P2, P1] [P5].", labels)
+  if want := "The budget is due Friday [recording 7 at 1:05]. Hiring waits [recording 9; recording 7 at 1:05]."; text != want {
+    t.Errorf("text = %q, want %q", text, want)
+  }
+  if len(citations) != 2 || citations[0].Label != "P1" || citations[1].Label != "P2" {
+    t.Errorf("citations = %+v, want P1 then P2", citations)
+  }
+}
+
+func TestResolveCitationsWithoutCitations(t *testing.T) {
+  text, citations := resolveCitations("I could not find that in your notes [P9].", nil)
+  if text != "I could not find that in your notes." {
+    t.Errorf("text = %q", text)
+  }
+
+  // Answers without citations list none rather than null
+  body, err := json.Marshal(Answer{Answer: text, Citations: citations, Recordings: []int64{}})
+  if err != nil {
+    t.Fatal(err)
+  }
+  if !strings.Contains(string(body), `"citations":[]`) {
+    t.Errorf("answer JSON = %s, want an empty citations list", body)
+  }
+}
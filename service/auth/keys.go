@@ -0,0 +1,59 @@
+package auth
+
+import (
+  "crypto/rand"
+  "crypto/sha256"
+  "encoding/base64"
+  "encoding/hex"
+  "fmt"
+  "strings"
+)
+
+// APIKeyPrefix starts every API key, telling them apart from signed tokens.
+const APIKeyPrefix = "vn_"
+
+// apiKeyBytes is the number of random bytes in an API key.
+const apiKeyBytes = 32
+
+// GenerateAPIKey creates a new random API key. Only its hash should be stored;
+// the key itself is shown to the user once.
+func GenerateAPIKey() (string, error) {
+  b := make([]byte, apiKeyBytes)
+  if _, err := rand.Read(b); err != nil {
+    return "", fmt.Errorf("failed to generate API key: %v", err)
+  }
+  return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// FeedTokenPrefix starts every calendar feed token.
+const FeedTokenPrefix = "vncal_"
+
+// GenerateFeedToken creates a random token granting read access to a user's calendar feed,
+// for calendar apps that can only be given a URL. Like API keys, only its hash should be stored.
+func GenerateFeedToken() (string, error) {
+  b := make([]byte, apiKeyBytes)
+  if _, err := rand.Read(b); err != nil {
+    return "", fmt.Errorf("failed to generate feed token: %v", err)
+  }
+  return FeedTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// IsAPIKey reports whether the credential looks like an API key rather than a signed token.
+func IsAPIKey(credential string) bool {
+  return strings.HasPrefix(credential, APIKeyPrefix)
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash under which an API key or feed token is stored.
+// API keys are long and random, so a fast unsalted hash is enough to keep them safe at rest.
+func HashAPIKey(key string) string {
+  sum := sha256.Sum256([]byte(key))
+  return hex.EncodeToString(sum[:])
+}
+
+// KeyHint returns the beginning of an API key, enough for users to recognise it in listings.
+func KeyHint(key string) string {
+  if len(key) <= len(APIKeyPrefix)+6 {
+    return key
+  }
+  return key[:len(APIKeyPrefix)+6]
+}
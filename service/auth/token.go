@@ -0,0 +1,103 @@
+package auth
+
+import (
+  "crypto/hmac"
+  "crypto/rand"
+  "crypto/sha256"
+  "encoding/base64"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "strings"
+  "time"
+)
+
+var (
+  // ErrInvalidToken is returned for tokens that are malformed or carry a bad signature.
+  ErrInvalidToken = errors.New("invalid token")
+
+  // ErrTokenExpired is returned for correctly signed tokens past their expiry.
+  ErrTokenExpired = errors.New("token expired")
+)
+
+// Claims are the contents of a signed token.
+type Claims struct {
+  UserID    int64 `json:"sub"`
+  IssuedAt  int64 `json:"iat"`
+  ExpiresAt int64 `json:"exp"`
+}
+
+// Signer issues and verifies bearer tokens signed with HMAC-SHA256.
+// A token is the base64url encoded JSON claims and signature, joined by a dot.
+type Signer struct {
+  secret []byte
+  now    func() time.Time
+}
+
+// NewSigner creates a signer using the secret as HMAC key.
+func NewSigner(secret []byte) (*Signer, error) {
+  if len(secret) < 32 {
+    return nil, fmt.Errorf("token secret must be at least 32 bytes, got %d", len(secret))
+  }
+  return &Signer{secret: secret, now: time.Now}, nil
+}
+
+// RandomSecret generates a secret suitable for NewSigner.
+func RandomSecret() ([]byte, error) {
+  secret := make([]byte, 32)
+  if _, err := rand.Read(secret); err != nil {
+    return nil, fmt.Errorf("failed to generate token secret: %v", err)
+  }
+  return secret, nil
+}
+
+// Issue creates a token for the user that expires after ttl.
+func (s *Signer) Issue(userID int64, ttl time.Duration) (string, Claims, error) {
+  now := s.now()
+  claims := Claims{
+    UserID:    userID,
+    IssuedAt:  now.Unix(),
+    ExpiresAt: now.Add(ttl).Unix(),
+  }
+
+  payload, err := json.Marshal(claims)
+  if err != nil {
+    return "", Claims{}, fmt.Errorf("failed to marshal token claims: %v", err)
+  }
+
+  encoded := base64.RawURLEncoding.EncodeToString(payload)
+  return encoded + "." + s.sign(encoded), claims, nil
+}
+
+// Verify checks the signature and expiry of a token and returns its claims.
+func (s *Signer) Verify(token string) (Claims, error) {
+  encoded, signature, ok := strings.Cut(token, ".")
+  if !ok {
+    return Claims{}, ErrInvalidToken
+  }
+  if !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+    return Claims{}, ErrInvalidToken
+  }
+
+  payload, err := base64.RawURLEncoding.DecodeString(encoded)
+  if err != nil {
+    return Claims{}, ErrInvalidToken
+  }
+  var claims Claims
+  if err := json.Unmarshal(payload, &claims); err != nil || claims.UserID == 0 {
+    return Claims{}, ErrInvalidToken
+  }
+
+  if s.now().Unix() >= claims.ExpiresAt {
+    return Claims{}, ErrTokenExpired
+  }
+
+  return claims, nil
+}
+
+// sign returns the base64url encoded HMAC of the encoded claims.
+func (s *Signer) sign(encoded string) string {
+  mac := hmac.New(sha256.New, s.secret)
+  mac.Write([]byte(encoded))
+  return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+  "bytes"
+  "encoding/base64"
+  "errors"
+  "strings"
+  "testing"
+  "time"
+)
+
+// newTestSigner returns a signer whose clock is set by the returned function.
+func newTestSigner(t *testing.T, secret string) (*Signer, func(time.Time)) {
+  t.Helper()
+  signer, err := NewSigner([]byte(secret))
+  if err != nil {
+    t.Fatal(err)
+  }
+  now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+  signer.now = func() time.Time { return now }
+  return signer, func(t time.Time) { now = t }
+}
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestNewSignerRejectsShortSecrets(t *testing.T) {
+  if _, err := NewSigner([]byte("too short")); err == nil {
+    t.Fatal("NewSigner accepted a 9 byte secret")
+  }
+
+  secret, err := RandomSecret()
+  if err != nil {
+    t.Fatal(err)
+  }
+  if _, err := NewSigner(secret); err != nil {
+    t.Fatalf("NewSigner rejected a random secret: %v", err)
+  }
+}
+
+func TestSignerIssueAndVerify(t *testing.T) {
+  signer, setNow := newTestSigner(t, testSecret)
+  issuedAt := signer.now()
+
+  token, claims, err := signer.Issue(42, time.Hour)
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := Claims{UserID: 42, IssuedAt: issuedAt.Unix(), ExpiresAt: issuedAt.Add(time.Hour).Unix()}
+  if claims != want {
+    t.Fatalf("claims = %+v, want %+v", claims, want)
+  }
+  if IsAPIKey(token) {
+    t.Errorf("token %q looks like an API key", token)
+  }
+
+  verified, err := signer.Verify(token)
+  if err != nil || verified != want {
+    t.Fatalf("Verify = %+v, %v, want %+v", verified, err, want)
+  }
+
+  setNow(issuedAt.Add(time.Hour - time.Second))
+  if _, err := signer.Verify(token); err != nil {
+    t.Errorf("Verify one second before expiry: %v", err)
+  }
+  setNow(issuedAt.Add(time.Hour))
+  if _, err := signer.Verify(token); !errors.Is(err, ErrTokenExpired) {
+    t.Errorf("Verify at expiry = %v, want ErrTokenExpired", err)
+  }
+}
+
+func TestSignerVerifyRejectsTamperedTokens(t *testing.T) {
+  signer, _ := newTestSigner(t, testSecret)
+  token, _, err := signer.Issue(42, time.Hour)
+  if err != nil {
+    t.Fatal(err)
+  }
+  encoded, signature, _ := strings.Cut(token, ".")
+
+  // Claims rewritten to another user, keeping the original signature
+  payload, err := base64.RawURLEncoding.DecodeString(encoded)
+  if err != nil {
+    t.Fatal(err)
+  }
+  forged := base64.RawURLEncoding.EncodeToString(bytes.Replace(payload, []byte(`"sub":42`), []byte(`"sub":1`), 1))
+
+  other, _ := newTestSigner(t, strings.Repeat("x", 32))
+  otherToken, _, err := other.Issue(42, time.Hour)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  tokens := map[string]string{
+    "empty":             "",
+    "no signature":      encoded,
+    "empty signature":   encoded + ".",
+    "forged claims":     forged + "." + signature,
+    "changed signature": encoded + "." + changeFirst(signature),
+    "other secret":      otherToken,
+    "extra dot":         token + ".x",
+  }
+  for name, token := range tokens {
+    if _, err := signer.Verify(token); !errors.Is(err, ErrInvalidToken) {
+      t.Errorf("%s: Verify = %v, want ErrInvalidToken", name, err)
+    }
+  }
+}
+
+// changeFirst replaces the first character of s with another one.
+func changeFirst(s string) string {
+  if s[0] == 'A' {
+    return "B" + s[1:]
+  }
+  return "A" + s[1:]
+}
+
+func TestSignerVerifyRejectsTokensWithoutUser(t *testing.T) {
+  signer, _ := newTestSigner(t, testSecret)
+  token, _, err := signer.Issue(0, time.Hour)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if _, err := signer.Verify(token); !errors.Is(err, ErrInvalidToken) {
+    t.Errorf("Verify = %v, want ErrInvalidToken", err)
+  }
+}
+
+func TestAPIKeys(t *testing.T) {
+  key, err := GenerateAPIKey()
+  if err != nil {
+    t.Fatal(err)
+  }
+  other, err := GenerateAPIKey()
+  if err != nil {
+    t.Fatal(err)
+  }
+  if !IsAPIKey(key) || key == other {
+    t.Fatalf("GenerateAPIKey returned %q and %q, want distinct keys with the API key prefix", key, other)
+  }
+  if hash := HashAPIKey(key); len(hash) != 64 || hash == HashAPIKey(other) {
+    t.Errorf("HashAPIKey(%q) = %q, want a distinct hex SHA-256", key, hash)
+  }
+  if hint := KeyHint(key); hint != key[:9] {
+    t.Errorf("KeyHint = %q, want %q", hint, key[:9])
+  }
+
+  feed, err := GenerateFeedToken()
+  if err != nil {
+    t.Fatal(err)
+  }
+  if !strings.HasPrefix(feed, FeedTokenPrefix) || IsAPIKey(feed) {
+    t.Errorf("GenerateFeedToken = %q, want a feed token prefix", feed)
+  }
+}
@@ -0,0 +1,179 @@
+package calendar
+
+import (
+  "bufio"
+  "fmt"
+  "io"
+  "strings"
+  "time"
+  "unicode/utf8"
+)
+
+// ProductID identifies the application in the calendars it writes.
+const ProductID = "-//voice-notetaking-app//Action items//EN"
+
+// maxLineOctets is the longest a content line may be before it is folded, excluding the line break.
+const maxLineOctets = 75
+
+// Event is an all-day event, such as the due date of a task.
+type Event struct {
+  UID          string
+  Summary      string
+  Description  string
+  Date         time.Time
+  LastModified time.Time
+}
+
+// Todo is a task, completed when Completed is set.
+type Todo struct {
+  UID          string
+  Summary      string
+  Description  string
+  Due          *time.Time
+  Completed    *time.Time
+  Created      time.Time
+  LastModified time.Time
+}
+
+// Calendar is an iCalendar (RFC 5545) object holding events and tasks.
+type Calendar struct {
+  Name string
+
+  // RefreshInterval suggests how often subscribers should reload the calendar.
+  RefreshInterval time.Duration
+
+  Events []Event
+  Todos  []Todo
+}
+
+// WriteTo writes the calendar in iCalendar format.
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+  cw := &contentWriter{w: bufio.NewWriter(w)}
+
+  cw.line("BEGIN", "VCALENDAR")
+  cw.line("VERSION", "2.0")
+  cw.line("PRODID", ProductID)
+  cw.line("CALSCALE", "GREGORIAN")
+  if c.Name != "" {
+    cw.line("NAME", escapeText(c.Name))
+    cw.line("X-WR-CALNAME", escapeText(c.Name))
+  }
+  if c.RefreshInterval > 0 {
+    cw.line("REFRESH-INTERVAL;VALUE=DURATION", formatDuration(c.RefreshInterval))
+    cw.line("X-PUBLISHED-TTL", formatDuration(c.RefreshInterval))
+  }
+
+  for _, event := range c.Events {
+    cw.line("BEGIN", "VEVENT")
+    cw.line("UID", event.UID)
+    cw.line("DTSTAMP", formatTime(event.LastModified))
+    cw.line("LAST-MODIFIED", formatTime(event.LastModified))
+    cw.line("DTSTART;VALUE=DATE", formatDate(event.Date))
+    cw.line("DTEND;VALUE=DATE", formatDate(event.Date.AddDate(0, 0, 1)))
+    cw.line("SUMMARY", escapeText(event.Summary))
+    if event.Description != "" {
+      cw.line("DESCRIPTION", escapeText(event.Description))
+    }
+    cw.line("TRANSP", "TRANSPARENT")
+    cw.line("END", "VEVENT")
+  }
+
+  for _, todo := range c.Todos {
+    cw.line("BEGIN", "VTODO")
+    cw.line("UID", todo.UID)
+    cw.line("DTSTAMP", formatTime(todo.LastModified))
+    cw.line("CREATED", formatTime(todo.Created))
+    cw.line("LAST-MODIFIED", formatTime(todo.LastModified))
+    cw.line("SUMMARY", escapeText(todo.Summary))
+    if todo.Description != "" {
+      cw.line("DESCRIPTION", escapeText(todo.Description))
+    }
+    if todo.Due != nil {
+      cw.line("DUE;VALUE=DATE", formatDate(*todo.Due))
+    }
+    if todo.Completed != nil {
+      cw.line("STATUS", "COMPLETED")
+      cw.line("COMPLETED", formatTime(*todo.Completed))
+      cw.line("PERCENT-COMPLETE", "100")
+    } else {
+      cw.line("STATUS", "NEEDS-ACTION")
+    }
+    cw.line("END", "VTODO")
+  }
+
+  cw.line("END", "VCALENDAR")
+  if cw.err == nil {
+    cw.err = cw.w.Flush()
+  }
+  return cw.n, cw.err
+}
+
+// contentWriter writes folded content lines, keeping the first error.
+type contentWriter struct {
+  w   *bufio.Writer
+  n   int64
+  err error
+}
+
+// line writes a content line, folding it into lines of at most 75 octets without splitting characters.
+func (cw *contentWriter) line(name, value string) {
+  if cw.err != nil {
+    return
+  }
+
+  text := name + ":" + value
+  var b strings.Builder
+  limit := maxLineOctets
+  for len(text) > limit {
+    cut := limit
+    for cut > 0 && !utf8.RuneStart(text[cut]) {
+      cut--
+    }
+    b.WriteString(text[:cut])
+    b.WriteString("\r\n ")
+    text = text[cut:]
+    // Continuation lines start with a space, which counts towards their length
+    limit = maxLineOctets - 1
+  }
+  b.WriteString(text)
+  b.WriteString("\r\n")
+
+  n, err := cw.w.WriteString(b.String())
+  cw.n += int64(n)
+  cw.err = err
+}
+
+// textEscaper escapes the characters with a meaning in TEXT values.
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escapeText escapes a TEXT property value.
+func escapeText(text string) string {
+  return textEscaper.Replace(text)
+}
+
+// formatTime formats a UTC DATE-TIME value.
+func formatTime(t time.Time) string {
+  return t.UTC().Format("20060102T150405Z")
+}
+
+// formatDate formats a DATE value.
+func formatDate(t time.Time) string {
+  return t.Format("20060102")
+}
+
+// formatDuration formats a DURATION value in whole minutes, such as PT1H30M.
+func formatDuration(d time.Duration) string {
+  minutes := int64(d / time.Minute)
+  if minutes < 1 {
+    minutes = 1
+  }
+  var b strings.Builder
+  b.WriteString("PT")
+  if minutes >= 60 {
+    fmt.Fprintf(&b, "%dH", minutes/60)
+  }
+  if minutes%60 != 0 {
+    fmt.Fprintf(&b, "%dM", minutes%60)
+  }
+  return b.String()
+}
@@ -0,0 +1,163 @@
+package calendar
+
+import (
+  "bufio"
+  "bytes"
+  "strings"
+  "testing"
+  "time"
+  "unicode/utf8"
+)
+
+func TestEscapeText(t *testing.T) {
+  tests := []struct {
+    text string
+    want string
+  }{
+    {"Call the vendor", "Call the vendor"},
+    {"Budget; Q3, Q4", `Budget\; Q3\, Q4`},
+    {`C:\notes`, `C:\\notes`},
+    {"Owner: Ana\nDue: Friday", `Owner: Ana\nDue: Friday`},
+    {"one\r\ntwo\rthree", `one\ntwo\nthree`},
+  }
+  for _, tt := range tests {
+    if got := escapeText(tt.text); got != tt.want {
+      t.Errorf("escapeText(%q) = %q, want %q", tt.text, got, tt.want)
+    }
+  }
+}
+
+func TestFormatDuration(t *testing.T) {
+  tests := map[time.Duration]string{
+    time.Hour:        "PT1H",
+    90 * time.Minute: "PT1H30M",
+    15 * time.Minute: "PT15M",
+    30 * time.Second: "PT1M",
+    24 * time.Hour:   "PT24H",
+  }
+  for d, want := range tests {
+    if got := formatDuration(d); got != want {
+      t.Errorf("formatDuration(%s) = %q, want %q", d, got, want)
+    }
+  }
+}
+
+// writeLine writes a single content line and returns it.
+func writeLine(t *testing.T, name, value string) string {
+  t.Helper()
+  var b bytes.Buffer
+  cw := &contentWriter{w: bufio.NewWriter(&b)}
+  cw.line(name, value)
+  if err := cw.w.Flush(); err != nil || cw.err != nil {
+    t.Fatal(err, cw.err)
+  }
+  if int64(b.Len()) != cw.n {
+    t.Errorf("wrote %d octets, counted %d", b.Len(), cw.n)
+  }
+  return b.String()
+}
+
+// checkFolded checks that a folded content line has lines of at most 75 octets that do not split
+// characters, and unfolds to the line.
+func checkFolded(t *testing.T, folded, want string) {
+  t.Helper()
+  if !strings.HasSuffix(folded, "\r\n") {
+    t.Fatalf("line %q does not end with CRLF", folded)
+  }
+  for i, line := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+    if len(line) > maxLineOctets {
+      t.Errorf("line %d has %d octets: %q", i, len(line), line)
+    }
+    if i > 0 && !strings.HasPrefix(line, " ") {
+      t.Errorf("continuation line %d does not start with a space: %q", i, line)
+    }
+    if !utf8.ValidString(line) {
+      t.Errorf("line %d splits a character: %q", i, line)
+    }
+  }
+  if unfolded := strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", ""); unfolded != want {
+    t.Errorf("unfolded line = %q, want %q", unfolded, want)
+  }
+}
+
+func TestContentLineFolding(t *testing.T) {
+  if got := writeLine(t, "SUMMARY", "Short"); got != "SUMMARY:Short\r\n" {
+    t.Errorf("short line = %q", got)
+  }
+
+  exact := strings.Repeat("a", maxLineOctets-len("SUMMARY:"))
+  if got := writeLine(t, "SUMMARY", exact); got != "SUMMARY:"+exact+"\r\n" {
+    t.Errorf("line of exactly 75 octets was folded: %q", got)
+  }
+
+  values := []string{
+    strings.Repeat("a", maxLineOctets-len("SUMMARY:")+1),
+    strings.Repeat("Draft the quarterly budget and send it to finance. ", 8),
+    strings.Repeat("é", 100),
+    strings.Repeat("预算", 60),
+    strings.Repeat("a🎯", 50),
+  }
+  for _, value := range values {
+    checkFolded(t, writeLine(t, "DESCRIPTION", value), "DESCRIPTION:"+value)
+  }
+}
+
+func TestCalendarWriteTo(t *testing.T) {
+  due := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+  created := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+  completed := time.Date(2026, 10, 15, 18, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+  cal := &Calendar{
+    Name:            "Action items of Ana",
+    RefreshInterval: time.Hour,
+    Events: []Event{{
+      UID:          "1-event@example",
+      Summary:      "Call the vendor, today",
+      Description:  "Owner: B\nDue: Friday",
+      Date:         due,
+      LastModified: created,
+    }},
+    Todos: []Todo{
+      {UID: "1-todo@example", Summary: "Call the vendor", Due: &due, Created: created, LastModified: created},
+      {UID: "2-todo@example", Summary: "Draft a budget", Completed: &completed, Created: created, LastModified: completed},
+    },
+  }
+
+  var b bytes.Buffer
+  n, err := cal.WriteTo(&b)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if n != int64(b.Len()) {
+    t.Errorf("WriteTo returned %d, wrote %d octets", n, b.Len())
+  }
+
+  out := b.String()
+  if !strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+    t.Errorf("calendar is not wrapped in VCALENDAR:\n%s", out)
+  }
+  if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+    t.Error("calendar has bare line feeds")
+  }
+  for _, line := range []string{
+    "X-WR-CALNAME:Action items of Ana",
+    "REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+    "UID:1-event@example",
+    "DTSTART;VALUE=DATE:20261016",
+    "DTEND;VALUE=DATE:20261017",
+    `SUMMARY:Call the vendor\, today`,
+    `DESCRIPTION:Owner: B\nDue: Friday`,
+    "DTSTAMP:20261014T093000Z",
+    "DUE;VALUE=DATE:20261016",
+    "STATUS:NEEDS-ACTION",
+    "STATUS:COMPLETED",
+    "COMPLETED:20261015T160000Z",
+    "PERCENT-COMPLETE:100",
+  } {
+    if !strings.Contains(out, "\r\n"+line+"\r\n") {
+      t.Errorf("calendar has no line %q:\n%s", line, out)
+    }
+  }
+  if strings.Count(out, "BEGIN:VEVENT") != 1 || strings.Count(out, "BEGIN:VTODO") != 2 {
+    t.Errorf("calendar does not have 1 event and 2 to-dos:\n%s", out)
+  }
+}
@@ -3,9 +3,7 @@
 package database
 
 import (
-  "database/sql"
   "errors"
-  "github.com/yourusername/mare/pkg/database/sqlite"
 )
 
 // SaveVoiceNote saves voice note to database.
@@ -0,0 +1,220 @@
+package digest
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// Schedule is a parsed cron expression with the usual five fields: minute, hour, day of month,
+// month and day of week. Fields accept *, numbers, ranges (1-5), lists (1,3,5), steps (*/15, 8-18/2)
+// and English month and day names (jan, mon). Day of week runs from 0 (Sunday) to 6; 7 is also Sunday.
+// As in cron, when both day of month and day of week are restricted a day matching either runs.
+type Schedule struct {
+  minute, hour, dom, month, dow uint64
+
+  domStar, dowStar bool
+}
+
+// cronField describes the allowed values of one field of a cron expression.
+type cronField struct {
+  name     string
+  min, max int
+  names    map[string]int
+}
+
+var cronFields = [5]cronField{
+  {name: "minute", min: 0, max: 59},
+  {name: "hour", min: 0, max: 23},
+  {name: "day of month", min: 1, max: 31},
+  {name: "month", min: 1, max: 12, names: map[string]int{
+    "jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+    "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+  }},
+  {name: "day of week", min: 0, max: 7, names: map[string]int{
+    "sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+  }},
+}
+
+// cronAliases are shorthands for common schedules.
+var cronAliases = map[string]string{
+  "@hourly":  "0 * * * *",
+  "@daily":   "0 0 * * *",
+  "@weekly":  "0 0 * * 0",
+  "@monthly": "0 0 1 * *",
+}
+
+// ParseCron parses a five field cron expression or one of @hourly, @daily, @weekly and @monthly.
+func ParseCron(expression string) (*Schedule, error) {
+  expression = strings.TrimSpace(strings.ToLower(expression))
+  if alias, ok := cronAliases[expression]; ok {
+    expression = alias
+  }
+
+  fields := strings.Fields(expression)
+  if len(fields) != len(cronFields) {
+    return nil, fmt.Errorf("cron expression %q must have %d fields, got %d", expression, len(cronFields), len(fields))
+  }
+
+  var bits [5]uint64
+  for i, field := range fields {
+    var err error
+    bits[i], err = parseCronField(field, cronFields[i])
+    if err != nil {
+      return nil, err
+    }
+  }
+
+  // Sunday may be written as 0 or 7
+  if bits[4]&(1<<7) != 0 {
+    bits[4] = bits[4]&^(1<<7) | 1
+  }
+
+  return &Schedule{
+    minute:  bits[0],
+    hour:    bits[1],
+    dom:     bits[2],
+    month:   bits[3],
+    dow:     bits[4],
+    domStar: fields[2] == "*",
+    dowStar: fields[4] == "*",
+  }, nil
+}
+
+// parseCronField parses one field into a bit set of the values it allows.
+func parseCronField(field string, spec cronField) (uint64, error) {
+  var bits uint64
+  for _, part := range strings.Split(field, ",") {
+    rangePart, stepPart, hasStep := strings.Cut(part, "/")
+    step := 1
+    if hasStep {
+      var err error
+      step, err = strconv.Atoi(stepPart)
+      if err != nil || step < 1 {
+        return 0, fmt.Errorf("invalid step %q in %s field", stepPart, spec.name)
+      }
+    }
+
+    low, high := spec.min, spec.max
+    if rangePart != "*" {
+      lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+      var err error
+      if low, err = parseCronValue(lowPart, spec); err != nil {
+        return 0, err
+      }
+      high = low
+      if isRange {
+        if high, err = parseCronValue(highPart, spec); err != nil {
+          return 0, err
+        }
+      } else if hasStep {
+        high = spec.max
+      }
+      if low > high {
+        return 0, fmt.Errorf("invalid range %q in %s field", rangePart, spec.name)
+      }
+    }
+
+    for value := low; value <= high; value += step {
+      bits |= 1 << uint(value)
+    }
+  }
+  return bits, nil
+}
+
+// parseCronValue parses a number or name within the bounds of the field.
+func parseCronValue(value string, spec cronField) (int, error) {
+  if n, ok := spec.names[value]; ok {
+    return n, nil
+  }
+  n, err := strconv.Atoi(value)
+  if err != nil || n < spec.min || n > spec.max {
+    return 0, fmt.Errorf("invalid value %q in %s field, expected %d-%d", value, spec.name, spec.min, spec.max)
+  }
+  return n, nil
+}
+
+// Next returns the first time after t matching the schedule, in t's location, or the zero time
+// if none is found within five years (for example "0 0 30 2 *").
+//
+// The schedule is matched against wall-clock times. A time skipped when clocks spring forward runs
+// as soon as they have jumped, and a time repeated when clocks fall back only runs the first time.
+func (s *Schedule) Next(t time.Time) time.Time {
+  location := t.Location()
+  limit := t.AddDate(5, 0, 0)
+
+  // Walk wall-clock times in UTC, which has no gaps or repeats, and map each match back to the
+  // location until one falls after t
+  wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+  for {
+    wall = s.nextWall(wall)
+    if wall.IsZero() {
+      return time.Time{}
+    }
+
+    next := wallTime(wall, location)
+    if next.After(limit) {
+      return time.Time{}
+    }
+    if next.After(t) {
+      return next
+    }
+  }
+}
+
+// nextWall returns the first wall-clock time after the given one matching the schedule, both in UTC,
+// or the zero time if none is found within five years.
+func (s *Schedule) nextWall(wall time.Time) time.Time {
+  t := wall.Add(time.Minute)
+  limit := t.AddDate(5, 0, 0)
+
+  for t.Before(limit) {
+    if s.month&(1<<uint(t.Month())) == 0 {
+      t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+      continue
+    }
+    if !s.dayMatches(t) {
+      t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+      continue
+    }
+    if s.hour&(1<<uint(t.Hour())) == 0 {
+      t = t.Truncate(time.Hour).Add(time.Hour)
+      continue
+    }
+    if s.minute&(1<<uint(t.Minute())) == 0 {
+      t = t.Add(time.Minute)
+      continue
+    }
+    return t
+  }
+  return time.Time{}
+}
+
+// wallTime returns the instant the location's clocks show the wall-clock time, given in UTC.
+// When the time is skipped because clocks spring forward, it returns the instant they jump.
+func wallTime(wall time.Time, location *time.Location) time.Time {
+  t := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, location)
+  shown := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+  if shown.Equal(wall) {
+    return t
+  }
+
+  // Go resolved the missing time with the offset of one side of the gap; the jump is the bound
+  // of that zone period facing the other side
+  start, end := t.ZoneBounds()
+  if shown.Before(wall) {
+    return end
+  }
+  return start
+}
+
+// dayMatches reports whether the day of t is allowed by the day of month and day of week fields.
+func (s *Schedule) dayMatches(t time.Time) bool {
+  dom := s.dom&(1<<uint(t.Day())) != 0
+  dow := s.dow&(1<<uint(t.Weekday())) != 0
+  if s.domStar || s.dowStar {
+    return dom && dow
+  }
+  return dom || dow
+}
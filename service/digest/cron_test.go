@@ -0,0 +1,129 @@
+package digest
+
+import (
+  "testing"
+  "time"
+  _ "time/tzdata"
+)
+
+func TestParseCronErrors(t *testing.T) {
+  for _, expression := range []string{
+    "",
+    "* * * *",
+    "60 * * * *",
+    "* 24 * * *",
+    "* * 0 * *",
+    "* * * 13 *",
+    "* * * * 8",
+    "5-1 * * * *",
+    "*/0 * * * *",
+    "* * * foo *",
+  } {
+    if _, err := ParseCron(expression); err == nil {
+      t.Errorf("ParseCron(%q) succeeded, want an error", expression)
+    }
+  }
+}
+
+func TestScheduleNext(t *testing.T) {
+  newYork := mustLoadLocation(t, "America/New_York")
+  london := mustLoadLocation(t, "Europe/London")
+  sydney := mustLoadLocation(t, "Australia/Sydney")
+
+  tests := []struct {
+    name       string
+    expression string
+    after      time.Time
+    want       time.Time
+  }{
+    {"next minute", "* * * * *", date(2026, 1, 1, 10, 0, time.UTC), date(2026, 1, 1, 10, 1, time.UTC)},
+    {"later today", "30 8 * * *", date(2026, 1, 1, 7, 0, time.UTC), date(2026, 1, 1, 8, 30, time.UTC)},
+    {"tomorrow", "0 8 * * *", date(2026, 1, 1, 8, 0, time.UTC), date(2026, 1, 2, 8, 0, time.UTC)},
+    {"alias", "@weekly", date(2026, 1, 1, 0, 0, time.UTC), date(2026, 1, 4, 0, 0, time.UTC)},
+    {"day names", "0 9 * * mon-fri", date(2026, 1, 2, 9, 0, time.UTC), date(2026, 1, 5, 9, 0, time.UTC)},
+    {"steps", "*/20 8-18/5 * * *", date(2026, 1, 1, 13, 40, time.UTC), date(2026, 1, 1, 18, 0, time.UTC)},
+    {"sunday as 7", "0 0 * * 7", date(2026, 1, 1, 0, 0, time.UTC), date(2026, 1, 4, 0, 0, time.UTC)},
+    {"day of month or week", "0 0 13 * fri", date(2026, 2, 1, 0, 0, time.UTC), date(2026, 2, 6, 0, 0, time.UTC)},
+    {"leap day", "0 0 29 2 *", date(2026, 3, 1, 0, 0, time.UTC), date(2028, 2, 29, 0, 0, time.UTC)},
+    {"never", "0 0 30 2 *", date(2026, 1, 1, 0, 0, time.UTC), time.Time{}},
+
+    // Clocks spring forward from 02:00 to 03:00 on 8 March 2026 in New York
+    {"spring forward, before the gap", "0 8 * * *", date(2026, 3, 7, 23, 59, newYork), date(2026, 3, 8, 8, 0, newYork)},
+    {"spring forward, hourly", "0 * * * *", date(2026, 3, 8, 1, 30, newYork), date(2026, 3, 8, 3, 0, newYork)},
+    {"spring forward, skipped time", "30 2 * * *", date(2026, 3, 8, 0, 0, newYork), date(2026, 3, 8, 3, 0, newYork)},
+    {"spring forward, day after skipped time", "30 2 * * *", date(2026, 3, 8, 3, 0, newYork), date(2026, 3, 9, 2, 30, newYork)},
+    {"spring forward, London", "0 8 * * *", date(2026, 3, 28, 12, 0, london), date(2026, 3, 29, 8, 0, london)},
+
+    // Clocks fall back from 02:00 to 01:00 on 1 November 2026 in New York
+    {"fall back, after the repeat", "0 8 * * *", date(2026, 10, 31, 23, 59, newYork), date(2026, 11, 1, 8, 0, newYork)},
+    {"fall back, hourly", "0 * * * *", date(2026, 11, 1, 0, 30, newYork), date(2026, 11, 1, 1, 0, newYork)},
+    {"fall back, Sydney", "0 8 * * *", date(2026, 4, 4, 12, 0, sydney), date(2026, 4, 5, 8, 0, sydney)},
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      schedule, err := ParseCron(test.expression)
+      if err != nil {
+        t.Fatalf("ParseCron(%q): %v", test.expression, err)
+      }
+      got := schedule.Next(test.after)
+      if !got.Equal(test.want) {
+        t.Errorf("Next(%s) = %s, want %s", test.after, got, test.want)
+      }
+    })
+  }
+}
+
+func TestScheduleNextRepeatedTimeRunsOnce(t *testing.T) {
+  newYork := mustLoadLocation(t, "America/New_York")
+  schedule, err := ParseCron("30 1 * * *")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  first := schedule.Next(date(2026, 10, 31, 12, 0, newYork))
+  if first.Hour() != 1 || first.Minute() != 30 || first.Day() != 1 {
+    t.Fatalf("first run = %s, want 01:30 on 1 November", first)
+  }
+  second := schedule.Next(first)
+  if want := date(2026, 11, 2, 1, 30, newYork); !second.Equal(want) {
+    t.Errorf("run after %s = %s, want %s", first, second, want)
+  }
+}
+
+func TestScheduleNextAcrossAYearOfTransitions(t *testing.T) {
+  newYork := mustLoadLocation(t, "America/New_York")
+  schedule, err := ParseCron("*/30 * * * *")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  // Every run is later than the previous one and no wall-clock time runs twice
+  seen := make(map[string]bool)
+  previous := date(2026, 1, 1, 0, 0, newYork)
+  for i := 0; i < 365*48; i++ {
+    next := schedule.Next(previous)
+    if !next.After(previous) {
+      t.Fatalf("Next(%s) = %s, not after it", previous, next)
+    }
+    wall := next.Format("2006-01-02 15:04")
+    if seen[wall] {
+      t.Fatalf("%s ran twice", wall)
+    }
+    seen[wall] = true
+    previous = next
+  }
+}
+
+func date(year int, month time.Month, day, hour, minute int, location *time.Location) time.Time {
+  return time.Date(year, month, day, hour, minute, 0, 0, location)
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+  t.Helper()
+  location, err := time.LoadLocation(name)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return location
+}
@@ -0,0 +1,362 @@
+package digest
+
+import (
+  "context"
+  "fmt"
+  "log"
+  "sort"
+  "strings"
+  "sync"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/service/insight"
+  "voice-notetaking-app/service/summarization"
+)
+
+// Periods a digest can cover: the calendar day or the Monday to Sunday week before the one it runs in.
+const (
+  PeriodDay  = "day"
+  PeriodWeek = "week"
+)
+
+// DefaultPollInterval is how often the scheduler looks for schedules that are due.
+const DefaultPollInterval = time.Minute
+
+// DefaultRunTimeout bounds generating and delivering a single digest.
+const DefaultRunTimeout = 5 * time.Minute
+
+// Message is a digest as delivered to a sink. Times are in the timezone of its schedule.
+type Message struct {
+  DigestID   int64     `json:"digest_id"`
+  UserID     int64     `json:"user_id"`
+  Name       string    `json:"name"`
+  From       time.Time `json:"from"`
+  To         time.Time `json:"to"`
+  Recordings int       `json:"recordings"`
+  Summary    string    `json:"summary"`
+  Insight    string    `json:"insight"`
+}
+
+// Subject is a one line title for the digest, such as an email subject.
+func (m *Message) Subject() string {
+  return fmt.Sprintf("%s: %s", m.Name, m.days())
+}
+
+// days describes the days covered by the digest, such as "Mon Jan 5" or "Mon Jan 5 – Sun Jan 11".
+// To is the start of the day after the last one.
+func (m *Message) days() string {
+  last := m.To.Add(-time.Second)
+  if last.Format("2006-01-02") == m.From.Format("2006-01-02") {
+    return m.From.Format("Mon Jan 2")
+  }
+  return m.From.Format("Mon Jan 2") + " – " + last.Format("Mon Jan 2")
+}
+
+// Markdown renders the digest as a Markdown document.
+func (m *Message) Markdown() string {
+  var b strings.Builder
+  fmt.Fprintf(&b, "# %s\n\n", m.Name)
+  notes := "notes"
+  if m.Recordings == 1 {
+    notes = "note"
+  }
+  fmt.Fprintf(&b, "%s (%s), %d %s\n\n", m.days(), m.From.Format("MST"), m.Recordings, notes)
+  fmt.Fprintf(&b, "## Summary\n\n%s\n\n", strings.TrimSpace(m.Summary))
+  fmt.Fprintf(&b, "## Insights\n\n%s\n", strings.TrimSpace(m.Insight))
+  return b.String()
+}
+
+// Sink delivers digests to a target chosen per schedule, such as a URL or an email address.
+type Sink interface {
+  // Validate checks that the user may deliver to a target, before a schedule delivering to
+  // it is stored and again before each delivery.
+  Validate(userID int64, target string) error
+  Deliver(ctx context.Context, target string, message *Message) error
+}
+
+// Scheduler periodically generates the digests of schedules that are due, summarizing the
+// recordings of their period and generating insights over them, stores them and delivers
+// them through the sink of the schedule.
+type Scheduler struct {
+  Summarizer *summarization.Summarizer
+  Insights   *insight.Generator
+
+  // Sinks are the available delivery methods by name, as chosen by schedules.
+  Sinks map[string]Sink
+
+  PollInterval time.Duration
+  RunTimeout   time.Duration
+
+  wg sync.WaitGroup
+}
+
+// New creates a scheduler without any sinks.
+func New(summarizer *summarization.Summarizer, insights *insight.Generator) *Scheduler {
+  return &Scheduler{
+    Summarizer:   summarizer,
+    Insights:     insights,
+    Sinks:        make(map[string]Sink),
+    PollInterval: DefaultPollInterval,
+    RunTimeout:   DefaultRunTimeout,
+  }
+}
+
+// Prepare validates a new schedule, fills in its defaults and sets when it first runs after now.
+func (s *Scheduler) Prepare(schedule *sqlite.DigestSchedule, now time.Time) error {
+  schedule.Name = strings.TrimSpace(schedule.Name)
+  if schedule.Name == "" {
+    return fmt.Errorf("name is required")
+  }
+  if schedule.Timezone == "" {
+    schedule.Timezone = "UTC"
+  }
+  if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+    return fmt.Errorf("unknown timezone %q", schedule.Timezone)
+  }
+  if schedule.Period != PeriodDay && schedule.Period != PeriodWeek {
+    return fmt.Errorf("period must be %s or %s", PeriodDay, PeriodWeek)
+  }
+
+  sink, ok := s.Sinks[schedule.Sink]
+  if !ok {
+    return fmt.Errorf("unknown sink %q, expected one of %s", schedule.Sink, strings.Join(s.SinkNames(), ", "))
+  }
+  if err := sink.Validate(schedule.UserID, schedule.Target); err != nil {
+    return err
+  }
+
+  next, err := NextRun(*schedule, now)
+  if err != nil {
+    return err
+  }
+  schedule.NextRunAt = next
+  return nil
+}
+
+// SinkNames returns the names of the available sinks in alphabetical order.
+func (s *Scheduler) SinkNames() []string {
+  names := make([]string, 0, len(s.Sinks))
+  for name := range s.Sinks {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  return names
+}
+
+// NextRun returns the first time after the given one that the schedule runs.
+func NextRun(schedule sqlite.DigestSchedule, after time.Time) (time.Time, error) {
+  cron, err := ParseCron(schedule.Cron)
+  if err != nil {
+    return time.Time{}, err
+  }
+  location, err := time.LoadLocation(schedule.Timezone)
+  if err != nil {
+    return time.Time{}, fmt.Errorf("unknown timezone %q", schedule.Timezone)
+  }
+
+  next := cron.Next(after.In(location))
+  if next.IsZero() {
+    return time.Time{}, fmt.Errorf("cron expression %q never matches", schedule.Cron)
+  }
+  return next, nil
+}
+
+// PeriodRange returns the range of time covered by a digest of the period run at the given time,
+// from the start of its first day to the start of the day after its last, in the location of at.
+// A day is the calendar day before the run, so a morning digest covers all of yesterday, and
+// a week is the Monday to Sunday week before the one of the run.
+func PeriodRange(period string, at time.Time) (time.Time, time.Time) {
+  today := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+  if period == PeriodWeek {
+    monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+    return wallTime(monday.AddDate(0, 0, -7), at.Location()), wallTime(monday, at.Location())
+  }
+  return wallTime(today.AddDate(0, 0, -1), at.Location()), wallTime(today, at.Location())
+}
+
+// Start runs due schedules every poll interval until the context is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+  interval := s.PollInterval
+  if interval <= 0 {
+    interval = DefaultPollInterval
+  }
+
+  s.wg.Add(1)
+  go func() {
+    defer s.wg.Done()
+
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+      s.RunDue(ctx, time.Now())
+
+      select {
+      case <-ctx.Done():
+        return
+      case <-ticker.C:
+      }
+    }
+  }()
+}
+
+// Wait blocks until the scheduler has stopped after the start context is cancelled.
+func (s *Scheduler) Wait() {
+  s.wg.Wait()
+}
+
+// RunDue runs every schedule that is due at now. A schedule that was missed, for example while
+// the server was down, runs once for the time it was due and is then scheduled after now.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) {
+  schedules, err := sqlite.GetDueDigestSchedules(now)
+  if err != nil {
+    log.Printf("Failed to get due digest schedules: %v", err)
+    return
+  }
+
+  for _, schedule := range schedules {
+    if ctx.Err() != nil {
+      return
+    }
+
+    // Move the schedule on before running it so a failing digest is not retried on every poll
+    next, err := NextRun(schedule, now)
+    if err != nil {
+      log.Printf("Disabling digest schedule %d: %v", schedule.ID, err)
+      if err := sqlite.SetDigestScheduleEnabled(schedule.UserID, schedule.ID, false, schedule.NextRunAt); err != nil {
+        log.Printf("Failed to disable digest schedule %d: %v", schedule.ID, err)
+      }
+      continue
+    }
+    if err := sqlite.UpdateDigestScheduleRun(schedule.ID, now, next); err != nil {
+      log.Printf("Failed to update digest schedule %d: %v", schedule.ID, err)
+      continue
+    }
+
+    digest, err := s.Run(ctx, schedule, schedule.NextRunAt)
+    if err != nil {
+      log.Printf("Digest schedule %d failed: %v", schedule.ID, err)
+      continue
+    }
+    log.Printf("Digest %d of schedule %d %s", digest.ID, schedule.ID, digest.Status)
+  }
+}
+
+// Run generates the digest of the schedule for the period ending at the given time, stores it
+// and delivers it. Periods without recordings are stored as empty and not delivered. A digest
+// that could not be generated or delivered is stored as failed and its error returned.
+func (s *Scheduler) Run(ctx context.Context, schedule sqlite.DigestSchedule, at time.Time) (sqlite.Digest, error) {
+  timeout := s.RunTimeout
+  if timeout <= 0 {
+    timeout = DefaultRunTimeout
+  }
+  ctx, cancel := context.WithTimeout(ctx, timeout)
+  defer cancel()
+
+  location, err := time.LoadLocation(schedule.Timezone)
+  if err != nil {
+    return sqlite.Digest{}, fmt.Errorf("unknown timezone %q", schedule.Timezone)
+  }
+  from, to := PeriodRange(schedule.Period, at.In(location))
+
+  recordings, err := sqlite.GetRecordingsByTimeRange(schedule.UserID, from, to)
+  if err != nil {
+    return sqlite.Digest{}, fmt.Errorf("failed to get recordings: %v", err)
+  }
+
+  scheduleID := schedule.ID
+  digest := sqlite.Digest{
+    UserID:         schedule.UserID,
+    ScheduleID:     &scheduleID,
+    Name:           schedule.Name,
+    PeriodStart:    from,
+    PeriodEnd:      to,
+    RecordingCount: len(recordings),
+    Status:         sqlite.DigestDelivered,
+  }
+
+  var genErr error
+  if len(recordings) == 0 {
+    digest.Status = sqlite.DigestEmpty
+  } else {
+    digest.Summary, digest.Insight, genErr = s.generate(ctx, recordings, from, to)
+    if genErr != nil {
+      digest.Status, digest.Error = sqlite.DigestFailed, genErr.Error()
+    }
+  }
+
+  digest.ID, err = sqlite.InsertDigest(digest)
+  if err != nil {
+    return sqlite.Digest{}, fmt.Errorf("failed to store digest: %v", err)
+  }
+  if digest.Status != sqlite.DigestDelivered {
+    return digest, genErr
+  }
+
+  message := &Message{
+    DigestID:   digest.ID,
+    UserID:     digest.UserID,
+    Name:       digest.Name,
+    From:       from,
+    To:         to,
+    Recordings: digest.RecordingCount,
+    Summary:    digest.Summary,
+    Insight:    digest.Insight,
+  }
+  deliverErr := s.deliver(ctx, schedule, message)
+  if deliverErr != nil {
+    digest.Status, digest.Error = sqlite.DigestFailed, deliverErr.Error()
+  }
+  if err := sqlite.UpdateDigestDelivery(digest.ID, digest.Status, digest.Error); err != nil {
+    log.Printf("Failed to record delivery of digest %d: %v", digest.ID, err)
+  }
+  if deliverErr == nil {
+    now := time.Now()
+    digest.DeliveredAt = &now
+  }
+
+  return digest, deliverErr
+}
+
+// generate summarizes the recordings of a period and generates insights over them.
+func (s *Scheduler) generate(ctx context.Context, recordings []sqlite.Recording, from, to time.Time) (string, string, error) {
+  notes := make([]insight.Note, 0, len(recordings))
+  var combined strings.Builder
+  for _, recording := range recordings {
+    // Summaries are shorter and already condensed; fall back to the transcription without one
+    text := recording.Summary
+    if text == "" {
+      text = recording.Transcription
+    }
+    recordedAt := recording.CreatedAt.In(from.Location())
+    notes = append(notes, insight.Note{RecordedAt: recordedAt, Text: text})
+    fmt.Fprintf(&combined, "[%s]\n%s\n\n", recordedAt.Format("Mon 2006-01-02 15:04"), text)
+  }
+
+  summary, err := s.Summarizer.SummarizeText(ctx, combined.String())
+  if err != nil {
+    return "", "", fmt.Errorf("failed to summarize notes: %v", err)
+  }
+  text, err := s.Insights.GenerateInsightByTime(ctx, notes, from, to)
+  if err != nil {
+    return "", "", fmt.Errorf("failed to generate insight: %v", err)
+  }
+
+  return summary, text, nil
+}
+
+// deliver sends the message through the sink of the schedule.
+func (s *Scheduler) deliver(ctx context.Context, schedule sqlite.DigestSchedule, message *Message) error {
+  sink, ok := s.Sinks[schedule.Sink]
+  if !ok {
+    return fmt.Errorf("sink %q is not configured", schedule.Sink)
+  }
+  if err := sink.Validate(schedule.UserID, schedule.Target); err != nil {
+    return fmt.Errorf("target of %s is no longer allowed: %v", schedule.Sink, err)
+  }
+  if err := sink.Deliver(ctx, schedule.Target, message); err != nil {
+    return fmt.Errorf("failed to deliver digest through %s: %v", schedule.Sink, err)
+  }
+  return nil
+}
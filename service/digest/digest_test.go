@@ -0,0 +1,44 @@
+package digest
+
+import (
+  "testing"
+  "time"
+)
+
+func TestPeriodRange(t *testing.T) {
+  newYork := mustLoadLocation(t, "America/New_York")
+
+  tests := []struct {
+    name     string
+    period   string
+    at       time.Time
+    from, to time.Time
+  }{
+    {"morning digest covers yesterday", PeriodDay, date(2026, 1, 7, 8, 0, newYork), date(2026, 1, 6, 0, 0, newYork), date(2026, 1, 7, 0, 0, newYork)},
+    {"just after midnight", PeriodDay, date(2026, 1, 7, 0, 1, newYork), date(2026, 1, 6, 0, 0, newYork), date(2026, 1, 7, 0, 0, newYork)},
+    {"yesterday was 23 hours long", PeriodDay, date(2026, 3, 9, 8, 0, newYork), date(2026, 3, 8, 0, 0, newYork), date(2026, 3, 9, 0, 0, newYork)},
+    {"week run on Monday", PeriodWeek, date(2026, 1, 12, 8, 0, newYork), date(2026, 1, 5, 0, 0, newYork), date(2026, 1, 12, 0, 0, newYork)},
+    {"week run on Sunday", PeriodWeek, date(2026, 1, 18, 20, 0, newYork), date(2026, 1, 5, 0, 0, newYork), date(2026, 1, 12, 0, 0, newYork)},
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      from, to := PeriodRange(test.period, test.at)
+      if !from.Equal(test.from) || !to.Equal(test.to) {
+        t.Errorf("PeriodRange(%s, %s) = %s – %s, want %s – %s", test.period, test.at, from, to, test.from, test.to)
+      }
+    })
+  }
+}
+
+func TestMessageSubject(t *testing.T) {
+  day := Message{Name: "Daily", From: date(2026, 1, 6, 0, 0, time.UTC), To: date(2026, 1, 7, 0, 0, time.UTC)}
+  if got, want := day.Subject(), "Daily: Tue Jan 6"; got != want {
+    t.Errorf("Subject() = %q, want %q", got, want)
+  }
+
+  week := Message{Name: "Weekly", From: date(2026, 1, 5, 0, 0, time.UTC), To: date(2026, 1, 12, 0, 0, time.UTC)}
+  if got, want := week.Subject(), "Weekly: Mon Jan 5 – Sun Jan 11"; got != want {
+    t.Errorf("Subject() = %q, want %q", got, want)
+  }
+}
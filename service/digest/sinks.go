@@ -0,0 +1,321 @@
+package digest
+
+import (
+  "bytes"
+  "context"
+  "crypto/tls"
+  "encoding/json"
+  "fmt"
+  "io"
+  "mime"
+  "mime/quotedprintable"
+  "net"
+  "net/http"
+  "net/mail"
+  "net/smtp"
+  "net/url"
+  "os"
+  "path/filepath"
+  "strings"
+  "syscall"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+)
+
+// FileSink writes each digest as a Markdown file into a directory per user.
+// Schedules cannot choose where the file is written, so their target must be empty.
+type FileSink struct {
+  Dir string
+}
+
+// Validate accepts only an empty target.
+func (f *FileSink) Validate(userID int64, target string) error {
+  if target != "" {
+    return fmt.Errorf("the file sink does not take a target")
+  }
+  return nil
+}
+
+// Deliver writes the digest to <dir>/user-<id>/<date>-<digest id>.md.
+func (f *FileSink) Deliver(ctx context.Context, target string, message *Message) error {
+  dir := filepath.Join(f.Dir, fmt.Sprintf("user-%d", message.UserID))
+  if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+    return fmt.Errorf("failed to create digest directory: %v", err)
+  }
+
+  path := filepath.Join(dir, fmt.Sprintf("%s-%d.md", message.To.Format("2006-01-02"), message.DigestID))
+  if err := os.WriteFile(path, []byte(message.Markdown()), 0644); err != nil {
+    return fmt.Errorf("failed to write digest file: %v", err)
+  }
+  return nil
+}
+
+// WebhookSink posts each digest as JSON to the URL given as the target of the schedule.
+// Targets must resolve to public addresses, so users cannot make the server post to
+// loopback, private or link-local services such as cloud metadata endpoints.
+type WebhookSink struct {
+  HTTPClient *http.Client
+  // AllowedHosts may be posted to even though they resolve to non-public addresses,
+  // such as an internal service the administrator trusts.
+  AllowedHosts []string
+}
+
+// Validate checks that the target is an absolute http or https URL whose host is allowed
+// or only resolves to public addresses.
+func (h *WebhookSink) Validate(userID int64, target string) error {
+  u, err := url.Parse(target)
+  if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+    return fmt.Errorf("webhook target must be an http or https URL")
+  }
+  if h.allowed(u.Hostname()) {
+    return nil
+  }
+
+  ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+  defer cancel()
+  addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+  if err != nil {
+    return fmt.Errorf("failed to resolve webhook host %q: %v", u.Hostname(), err)
+  }
+  for _, addr := range addrs {
+    if !isPublicIP(addr.IP) {
+      return fmt.Errorf("webhook host %q resolves to the non-public address %s", u.Hostname(), addr.IP)
+    }
+  }
+  return nil
+}
+
+// allowed reports whether the host is one of the allowed hosts.
+func (h *WebhookSink) allowed(host string) bool {
+  for _, allowed := range h.AllowedHosts {
+    if strings.EqualFold(host, allowed) {
+      return true
+    }
+  }
+  return false
+}
+
+// client returns the HTTP client to post to host with. Hosts that are not allowed are
+// posted to through a transport that refuses to connect to non-public addresses, so a
+// host cannot pass validation and later resolve to an internal address.
+func (h *WebhookSink) client(host string) *http.Client {
+  client := http.DefaultClient
+  if h.HTTPClient != nil {
+    client = h.HTTPClient
+  }
+  if h.allowed(host) {
+    return client
+  }
+  public := *client
+  public.Transport = publicTransport
+  return &public
+}
+
+// publicTransport only connects to public addresses and ignores proxy settings.
+var publicTransport = &http.Transport{
+  DialContext: (&net.Dialer{
+    Timeout:   30 * time.Second,
+    KeepAlive: 30 * time.Second,
+    Control:   dialPublicOnly,
+  }).DialContext,
+  ForceAttemptHTTP2:     true,
+  MaxIdleConns:          10,
+  IdleConnTimeout:       90 * time.Second,
+  TLSHandshakeTimeout:   10 * time.Second,
+  ExpectContinueTimeout: time.Second,
+}
+
+// dialPublicOnly is a net.Dialer control function that refuses non-public addresses.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+  host, _, err := net.SplitHostPort(address)
+  if err != nil {
+    return err
+  }
+  if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+    return fmt.Errorf("refusing to connect to non-public address %s", host)
+  }
+  return nil
+}
+
+// nonPublicBlocks are reserved ranges that the net.IP methods do not cover.
+var nonPublicBlocks = mustParseCIDRs(
+  "0.0.0.0/8",     // this network
+  "100.64.0.0/10", // carrier-grade NAT
+  "192.0.0.0/24",  // IETF protocol assignments
+  "198.18.0.0/15", // benchmarking
+  "240.0.0.0/4",   // reserved and broadcast
+  "64:ff9b::/96",  // NAT64, which can reach any IPv4 address
+)
+
+// isPublicIP reports whether ip is a globally routable unicast address.
+func isPublicIP(ip net.IP) bool {
+  if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+    ip.IsMulticast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+    return false
+  }
+  for _, block := range nonPublicBlocks {
+    if block.Contains(ip) {
+      return false
+    }
+  }
+  return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+  blocks := make([]*net.IPNet, 0, len(cidrs))
+  for _, cidr := range cidrs {
+    _, block, err := net.ParseCIDR(cidr)
+    if err != nil {
+      panic(err)
+    }
+    blocks = append(blocks, block)
+  }
+  return blocks
+}
+
+// Deliver posts the message and expects a 2xx response.
+func (h *WebhookSink) Deliver(ctx context.Context, target string, message *Message) error {
+  body, err := json.Marshal(message)
+  if err != nil {
+    return fmt.Errorf("failed to marshal digest: %v", err)
+  }
+
+  req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+  if err != nil {
+    return err
+  }
+  req.Header.Set("Content-Type", "application/json")
+
+  resp, err := h.client(req.URL.Hostname()).Do(req)
+  if err != nil {
+    return err
+  }
+  defer resp.Body.Close()
+
+  if resp.StatusCode < 200 || resp.StatusCode > 299 {
+    message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+    return fmt.Errorf("unexpected response status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+  }
+  return nil
+}
+
+// SMTPSink emails each digest to the address given as the target of the schedule, which
+// must be the address of the user or in one of the allowed domains.
+// STARTTLS is used when the server offers it; credentials are only sent when a username is set.
+type SMTPSink struct {
+  Addr     string
+  From     string
+  Username string
+  Password string
+  // AllowedDomains may receive the digests of any user, such as the domain of the organization.
+  AllowedDomains []string
+}
+
+// Validate checks that the target is a single email address the user may send digests to.
+func (s *SMTPSink) Validate(userID int64, target string) error {
+  addr, err := mail.ParseAddress(target)
+  if err != nil {
+    return fmt.Errorf("email target must be an email address: %v", err)
+  }
+  domain := addr.Address[strings.LastIndex(addr.Address, "@")+1:]
+  for _, allowed := range s.AllowedDomains {
+    if strings.EqualFold(domain, allowed) {
+      return nil
+    }
+  }
+
+  user, err := sqlite.GetUserByID(userID)
+  if err != nil {
+    return fmt.Errorf("failed to get user: %v", err)
+  }
+  if user.Email == "" || !strings.EqualFold(addr.Address, user.Email) {
+    return fmt.Errorf("email target must be the email address of the user")
+  }
+  return nil
+}
+
+// Deliver sends the digest as a plain text email.
+func (s *SMTPSink) Deliver(ctx context.Context, target string, message *Message) error {
+  from, err := mail.ParseAddress(s.From)
+  if err != nil {
+    return fmt.Errorf("invalid sender address %q: %v", s.From, err)
+  }
+  to, err := mail.ParseAddress(target)
+  if err != nil {
+    return fmt.Errorf("invalid recipient address %q: %v", target, err)
+  }
+  email, err := s.compose(from, to, message)
+  if err != nil {
+    return err
+  }
+
+  var dialer net.Dialer
+  conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+  if err != nil {
+    return err
+  }
+  defer conn.Close()
+  if deadline, ok := ctx.Deadline(); ok {
+    conn.SetDeadline(deadline)
+  }
+
+  host, _, err := net.SplitHostPort(s.Addr)
+  if err != nil {
+    return fmt.Errorf("invalid SMTP address %q: %v", s.Addr, err)
+  }
+  client, err := smtp.NewClient(conn, host)
+  if err != nil {
+    return err
+  }
+  defer client.Close()
+
+  if ok, _ := client.Extension("STARTTLS"); ok {
+    if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+      return err
+    }
+  }
+  if s.Username != "" {
+    if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+      return err
+    }
+  }
+  if err := client.Mail(from.Address); err != nil {
+    return err
+  }
+  if err := client.Rcpt(to.Address); err != nil {
+    return err
+  }
+  w, err := client.Data()
+  if err != nil {
+    return err
+  }
+  if _, err := w.Write(email); err != nil {
+    return err
+  }
+  if err := w.Close(); err != nil {
+    return err
+  }
+  return client.Quit()
+}
+
+// compose builds the email for a digest, with the Markdown as a quoted-printable body.
+func (s *SMTPSink) compose(from, to *mail.Address, message *Message) ([]byte, error) {
+  var b bytes.Buffer
+  fmt.Fprintf(&b, "From: %s\r\n", from.String())
+  fmt.Fprintf(&b, "To: %s\r\n", to.String())
+  fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject()))
+  fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+  fmt.Fprintf(&b, "MIME-Version: 1.0\r\n")
+  fmt.Fprintf(&b, "Content-Type: text/plain; charset=utf-8\r\n")
+  fmt.Fprintf(&b, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+  body := quotedprintable.NewWriter(&b)
+  if _, err := body.Write([]byte(strings.ReplaceAll(message.Markdown(), "\n", "\r\n"))); err != nil {
+    return nil, fmt.Errorf("failed to encode email: %v", err)
+  }
+  if err := body.Close(); err != nil {
+    return nil, fmt.Errorf("failed to encode email: %v", err)
+  }
+  return b.Bytes(), nil
+}
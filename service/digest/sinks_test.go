@@ -0,0 +1,133 @@
+package digest
+
+import (
+  "context"
+  "net"
+  "net/http"
+  "net/http/httptest"
+  "net/url"
+  "path/filepath"
+  "testing"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+)
+
+func TestIsPublicIP(t *testing.T) {
+  tests := []struct {
+    ip     string
+    public bool
+  }{
+    {"93.184.216.34", true},
+    {"2606:2800:220:1:248:1893:25c8:1946", true},
+    {"127.0.0.1", false},
+    {"::1", false},
+    {"::ffff:127.0.0.1", false},
+    {"10.1.2.3", false},
+    {"172.16.0.1", false},
+    {"192.168.1.1", false},
+    {"169.254.169.254", false},
+    {"fe80::1", false},
+    {"fd00::1", false},
+    {"0.0.0.0", false},
+    {"0.1.2.3", false},
+    {"100.64.0.1", false},
+    {"224.0.0.1", false},
+    {"255.255.255.255", false},
+    {"64:ff9b::a00:1", false},
+  }
+  for _, tt := range tests {
+    if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.public {
+      t.Errorf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.public)
+    }
+  }
+}
+
+func TestWebhookSinkValidate(t *testing.T) {
+  sink := &WebhookSink{AllowedHosts: []string{"hooks.internal"}}
+  rejected := []string{
+    "",
+    "ftp://example.com/hook",
+    "http:///hook",
+    "http://127.0.0.1:8080/hook",
+    "http://localhost/hook",
+    "http://[::1]/hook",
+    "http://169.254.169.254/latest/meta-data/",
+    "https://10.0.0.5/hook",
+  }
+  for _, target := range rejected {
+    if err := sink.Validate(1, target); err == nil {
+      t.Errorf("Validate(%q) succeeded, want an error", target)
+    }
+  }
+
+  for _, target := range []string{"https://93.184.216.34/hook", "http://hooks.internal:9000/hook"} {
+    if err := sink.Validate(1, target); err != nil {
+      t.Errorf("Validate(%q) failed: %v", target, err)
+    }
+  }
+}
+
+func TestWebhookSinkDeliverRefusesNonPublicAddresses(t *testing.T) {
+  delivered := 0
+  server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    delivered++
+  }))
+  defer server.Close()
+  message := &Message{UserID: 1, Name: "Daily"}
+
+  // A target that passed validation but now resolves to loopback is still refused
+  sink := &WebhookSink{}
+  if err := sink.Deliver(context.Background(), server.URL, message); err == nil {
+    t.Fatal("Deliver to a loopback address succeeded, want an error")
+  }
+  if delivered != 0 {
+    t.Fatalf("server received %d requests, want none", delivered)
+  }
+
+  u, err := url.Parse(server.URL)
+  if err != nil {
+    t.Fatal(err)
+  }
+  sink.AllowedHosts = []string{u.Hostname()}
+  if err := sink.Deliver(context.Background(), server.URL, message); err != nil {
+    t.Fatalf("Deliver to an allowed host failed: %v", err)
+  }
+  if delivered != 1 {
+    t.Fatalf("server received %d requests, want 1", delivered)
+  }
+}
+
+func TestSMTPSinkValidate(t *testing.T) {
+  if err := sqlite.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+    t.Fatal(err)
+  }
+  userID, err := sqlite.InsertUser("Ana", "ana@example.com")
+  if err != nil {
+    t.Fatal(err)
+  }
+  noEmailID, err := sqlite.InsertUser("Bo", "")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  sink := &SMTPSink{AllowedDomains: []string{"team.example.org"}}
+  tests := []struct {
+    userID int64
+    target string
+    ok     bool
+  }{
+    {userID, "ana@example.com", true},
+    {userID, "Ana <ANA@example.com>", true},
+    {userID, "someone@example.com", false},
+    {userID, "bo@team.example.org", true},
+    {userID, "not an address", false},
+    {noEmailID, "bo@example.com", false},
+    {noEmailID, "bo@team.example.org", true},
+  }
+  for _, tt := range tests {
+    err := sink.Validate(tt.userID, tt.target)
+    if (err == nil) != tt.ok {
+      t.Errorf("Validate(%d, %q) = %v, want ok %v", tt.userID, tt.target, err, tt.ok)
+    }
+  }
+}
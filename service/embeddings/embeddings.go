@@ -0,0 +1,112 @@
+package embeddings
+
+import (
+  "context"
+  "fmt"
+  "math"
+  "strings"
+
+  openai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultModel is the embedding model used when none is configured.
+const DefaultModel = string(openai.SmallEmbedding3)
+
+// batchSize is the number of texts sent in a single embeddings request.
+const batchSize = 64
+
+// Embedder turns texts into vectors whose cosine similarity reflects how related the texts are.
+// Returned vectors are normalized to unit length, so their dot product is their cosine similarity.
+type Embedder interface {
+  Embed(ctx context.Context, texts []string) ([][]float32, error)
+
+  // Model names the embedding model. Vectors of different models cannot be compared.
+  Model() string
+}
+
+// OpenAI embeds texts with an OpenAI-compatible embeddings API.
+type OpenAI struct {
+  client *openai.Client
+  model  string
+}
+
+// NewOpenAI creates an embedder for the API at baseURL. An empty baseURL uses the OpenAI API
+// and an empty model uses DefaultModel.
+func NewOpenAI(apiKey, baseURL, model string) *OpenAI {
+  config := openai.DefaultConfig(apiKey)
+  if baseURL != "" {
+    config.BaseURL = strings.TrimRight(baseURL, "/")
+  }
+  if model == "" {
+    model = DefaultModel
+  }
+  return &OpenAI{
+    client: openai.NewClientWithConfig(config),
+    model:  model,
+  }
+}
+
+// Model returns the embedding model of the client.
+func (e *OpenAI) Model() string {
+  return e.model
+}
+
+// Embed requests the embeddings of the texts in batches, returning them in the same order.
+func (e *OpenAI) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+  vectors := make([][]float32, 0, len(texts))
+  for start := 0; start < len(texts); start += batchSize {
+    end := start + batchSize
+    if end > len(texts) {
+      end = len(texts)
+    }
+
+    resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+      Input: texts[start:end],
+      Model: openai.EmbeddingModel(e.model),
+    })
+    if err != nil {
+      return nil, fmt.Errorf("failed to create embeddings: %v", err)
+    }
+    if len(resp.Data) != end-start {
+      return nil, fmt.Errorf("failed to create embeddings: got %d vectors for %d texts", len(resp.Data), end-start)
+    }
+
+    batch := make([][]float32, end-start)
+    for _, data := range resp.Data {
+      if data.Index < 0 || data.Index >= len(batch) {
+        return nil, fmt.Errorf("failed to create embeddings: vector index %d out of range", data.Index)
+      }
+      batch[data.Index] = Normalize(data.Embedding)
+    }
+    vectors = append(vectors, batch...)
+  }
+
+  return vectors, nil
+}
+
+// Normalize scales the vector to unit length in place and returns it. Zero vectors are left as is.
+func Normalize(vector []float32) []float32 {
+  var sum float64
+  for _, v := range vector {
+    sum += float64(v) * float64(v)
+  }
+  if sum == 0 {
+    return vector
+  }
+
+  norm := float32(math.Sqrt(sum))
+  for i := range vector {
+    vector[i] /= norm
+  }
+  return vector
+}
+
+// Dot returns the dot product of two vectors of the same length, which is their cosine
+// similarity when both are normalized.
+func Dot(a, b []float32) float32 {
+  var sum float32
+  for i := range a {
+    sum += a[i] * b[i]
+  }
+  return sum
+}
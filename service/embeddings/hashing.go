@@ -0,0 +1,73 @@
+package embeddings
+
+import (
+  "context"
+  "fmt"
+  "hash/fnv"
+  "strings"
+  "unicode"
+)
+
+// DefaultHashingDimensions is the vector size of the hashing embedder when none is configured.
+const DefaultHashingDimensions = 512
+
+// trigramWeight is how much each character trigram counts relative to a whole word.
+const trigramWeight = 0.5
+
+// Hashing is a deterministic embedder that works offline. It hashes the words of a text and
+// their character trigrams into a fixed number of dimensions, so texts sharing words or word
+// stems end up close. Unlike a model it cannot match paraphrases without words in common.
+type Hashing struct {
+  dimensions int
+}
+
+// NewHashing creates a hashing embedder producing vectors of the given size.
+func NewHashing(dimensions int) *Hashing {
+  if dimensions < 1 {
+    dimensions = DefaultHashingDimensions
+  }
+  return &Hashing{dimensions: dimensions}
+}
+
+// Model names the embedder after its vector size, since vectors of different sizes are incompatible.
+func (h *Hashing) Model() string {
+  return fmt.Sprintf("hashing-%d", h.dimensions)
+}
+
+// Embed returns the normalized hashed feature vector of each text.
+func (h *Hashing) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+  vectors := make([][]float32, len(texts))
+  for i, text := range texts {
+    vector := make([]float32, h.dimensions)
+    for _, word := range words(text) {
+      h.add(vector, "w:"+word, 1)
+
+      padded := []rune("<" + word + ">")
+      for j := 0; j+3 <= len(padded); j++ {
+        h.add(vector, "t:"+string(padded[j:j+3]), trigramWeight)
+      }
+    }
+    vectors[i] = Normalize(vector)
+  }
+  return vectors, nil
+}
+
+// add hashes the feature into a dimension, using another bit of the hash as its sign
+// so that collisions tend to cancel out instead of piling up.
+func (h *Hashing) add(vector []float32, feature string, weight float32) {
+  hasher := fnv.New64a()
+  hasher.Write([]byte(feature))
+  sum := hasher.Sum64()
+
+  if sum>>63 == 1 {
+    weight = -weight
+  }
+  vector[sum%uint64(h.dimensions)] += weight
+}
+
+// words splits text into lower case words of letters and digits.
+func words(text string) []string {
+  return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+    return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+  })
+}
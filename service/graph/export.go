@@ -0,0 +1,513 @@
+package graph
+
+import (
+  "encoding/json"
+  "encoding/xml"
+  "fmt"
+  "io"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// Export formats.
+const (
+  FormatGraphML = "graphml"
+  FormatGEXF    = "gexf"
+  FormatDOT     = "dot"
+  FormatJGF     = "jgf"
+)
+
+// Edge kinds, telling similarity edges from the concept-labelled edges vertices are exported as.
+const (
+  KindSimilarity = "similarity"
+  KindConcept    = "concept"
+)
+
+// conceptSeparator joins the concepts of a node into a single attribute in formats without lists.
+const conceptSeparator = "; "
+
+// labelLength is how many characters of a node's text are used as its label.
+const labelLength = 80
+
+// Filter selects the part of a graph to export. An empty filter selects the whole graph.
+type Filter struct {
+  // Concept keeps only the nodes having the concept, compared case-insensitively.
+  Concept string
+
+  // MinWeight drops the edges weaker than it, and the vertices between nodes whose edges were all
+  // dropped. Vertices between nodes without an edge are kept.
+  MinWeight float64
+}
+
+// Format is a file format a graph can be exported to.
+type Format struct {
+  Name        string
+  ContentType string
+  Extension   string
+
+  write func(w io.Writer, graph *Graph) error
+}
+
+// formats are the supported export formats.
+var formats = []Format{
+  {Name: FormatGraphML, ContentType: "application/graphml+xml", Extension: ".graphml", write: writeGraphML},
+  {Name: FormatGEXF, ContentType: "application/gexf+xml", Extension: ".gexf", write: writeGEXF},
+  {Name: FormatDOT, ContentType: "text/vnd.graphviz", Extension: ".dot", write: writeDOT},
+  {Name: FormatJGF, ContentType: "application/vnd.jgf+json", Extension: ".json", write: writeJGF},
+}
+
+// LookupFormat returns the export format with the name. "json" is accepted for JSON Graph Format.
+func LookupFormat(name string) (Format, bool) {
+  name = strings.ToLower(name)
+  if name == "json" {
+    name = FormatJGF
+  }
+  for _, format := range formats {
+    if format.Name == name {
+      return format, true
+    }
+  }
+  return Format{}, false
+}
+
+// FormatNames returns the names of the supported export formats.
+func FormatNames() []string {
+  names := make([]string, 0, len(formats))
+  for _, format := range formats {
+    names = append(names, format.Name)
+  }
+  return names
+}
+
+// Export writes the part of the graph selected by the filter in the format.
+func (f Format) Export(w io.Writer, graph *Graph, filter Filter) error {
+  return f.write(w, graph.Subgraph(filter))
+}
+
+// Subgraph returns a copy of the part of the graph selected by the filter, which is not shared with the graph.
+func (g *Graph) Subgraph(filter Filter) *Graph {
+  g.mu.RLock()
+  defer g.mu.RUnlock()
+
+  subgraph := &Graph{UserID: g.UserID}
+  kept := make(map[int64]bool)
+  for _, node := range g.Nodes {
+    if filter.Concept != "" && !hasConcept(node.Concepts, filter.Concept) {
+      continue
+    }
+    node.Concepts = append([]string(nil), node.Concepts...)
+    subgraph.Nodes = append(subgraph.Nodes, node)
+    kept[node.ID] = true
+  }
+
+  // Whether each pair of nodes joined by edges keeps at least one of them
+  pairs := make(map[pairKey]bool)
+  for _, edge := range g.Edges {
+    if !kept[edge.SourceID] || !kept[edge.TargetID] {
+      continue
+    }
+    key := newPairKey(edge.SourceID, edge.TargetID)
+    if edge.Weight < filter.MinWeight {
+      if _, ok := pairs[key]; !ok {
+        pairs[key] = false
+      }
+      continue
+    }
+    subgraph.Edges = append(subgraph.Edges, edge)
+    pairs[key] = true
+  }
+
+  for _, vertex := range g.Vertices {
+    if !kept[vertex.NodeID] || !kept[vertex.TargetID] {
+      continue
+    }
+    if hasEdge, joined := pairs[newPairKey(vertex.NodeID, vertex.TargetID)]; joined && !hasEdge {
+      continue
+    }
+    subgraph.Vertices = append(subgraph.Vertices, vertex)
+  }
+
+  return subgraph
+}
+
+// hasConcept reports whether the concepts include the concept, ignoring case.
+func hasConcept(concepts []string, concept string) bool {
+  for _, c := range concepts {
+    if strings.EqualFold(c, concept) {
+      return true
+    }
+  }
+  return false
+}
+
+// nodeKey is the identifier of a node in exported files.
+func nodeKey(id int64) string {
+  return "n" + strconv.FormatInt(id, 10)
+}
+
+// nodeLabel returns the start of the node's text on a single line, to label it in graph tools.
+func nodeLabel(node Node) string {
+  label := strings.Join(strings.Fields(node.Text), " ")
+  if runes := []rune(label); len(runes) > labelLength {
+    label = strings.TrimSpace(string(runes[:labelLength])) + "…"
+  }
+  return label
+}
+
+// formatWeight formats an edge weight without needless digits.
+func formatWeight(weight float64) string {
+  return strconv.FormatFloat(weight, 'f', -1, 64)
+}
+
+// graphMLDocument is a GraphML file holding a single graph, with the elements below.
+type graphMLDocument struct {
+  XMLName xml.Name     `xml:"graphml"`
+  XMLNS   string       `xml:"xmlns,attr"`
+  Keys    []graphMLKey `xml:"key"`
+  Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+  ID   string `xml:"id,attr"`
+  For  string `xml:"for,attr"`
+  Name string `xml:"attr.name,attr"`
+  Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+  ID          string        `xml:"id,attr"`
+  EdgeDefault string        `xml:"edgedefault,attr"`
+  Nodes       []graphMLNode `xml:"node"`
+  Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+  ID   string        `xml:"id,attr"`
+  Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+  ID     string        `xml:"id,attr"`
+  Source string        `xml:"source,attr"`
+  Target string        `xml:"target,attr"`
+  Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+  Key   string `xml:"key,attr"`
+  Value string `xml:",chardata"`
+}
+
+// graphMLKeys declares the attributes of nodes and edges. Their IDs double as attribute names.
+var graphMLKeys = []graphMLKey{
+  {ID: "label", For: "node", Name: "label", Type: "string"},
+  {ID: "text", For: "node", Name: "text", Type: "string"},
+  {ID: "concepts", For: "node", Name: "concepts", Type: "string"},
+  {ID: "recording_id", For: "node", Name: "recording_id", Type: "long"},
+  {ID: "kind", For: "edge", Name: "kind", Type: "string"},
+  {ID: "weight", For: "edge", Name: "weight", Type: "double"},
+  {ID: "concept", For: "edge", Name: "concept", Type: "string"},
+}
+
+// writeGraphML writes the graph as GraphML, for Gephi, Cytoscape and yEd.
+func writeGraphML(w io.Writer, graph *Graph) error {
+  document := graphMLDocument{
+    XMLNS: "http://graphml.graphdrawing.org/xmlns",
+    Keys:  graphMLKeys,
+    Graph: graphMLGraph{ID: "knowledge-graph", EdgeDefault: "undirected"},
+  }
+
+  for _, node := range graph.Nodes {
+    document.Graph.Nodes = append(document.Graph.Nodes, graphMLNode{
+      ID: nodeKey(node.ID),
+      Data: []graphMLData{
+        {Key: "label", Value: nodeLabel(node)},
+        {Key: "text", Value: node.Text},
+        {Key: "concepts", Value: strings.Join(node.Concepts, conceptSeparator)},
+        {Key: "recording_id", Value: strconv.FormatInt(node.RecordingID, 10)},
+      },
+    })
+  }
+
+  for i, edge := range graph.Edges {
+    document.Graph.Edges = append(document.Graph.Edges, graphMLEdge{
+      ID:     "e" + strconv.Itoa(i+1),
+      Source: nodeKey(edge.SourceID),
+      Target: nodeKey(edge.TargetID),
+      Data: []graphMLData{
+        {Key: "kind", Value: KindSimilarity},
+        {Key: "weight", Value: formatWeight(edge.Weight)},
+      },
+    })
+  }
+
+  for _, vertex := range graph.Vertices {
+    document.Graph.Edges = append(document.Graph.Edges, graphMLEdge{
+      ID:     "v" + strconv.FormatInt(vertex.ID, 10),
+      Source: nodeKey(vertex.NodeID),
+      Target: nodeKey(vertex.TargetID),
+      Data: []graphMLData{
+        {Key: "kind", Value: KindConcept},
+        {Key: "concept", Value: vertex.Concept},
+      },
+    })
+  }
+
+  return writeXML(w, document)
+}
+
+// gexfDocument is a GEXF file, with the elements below.
+type gexfDocument struct {
+  XMLName xml.Name  `xml:"gexf"`
+  XMLNS   string    `xml:"xmlns,attr"`
+  Version string    `xml:"version,attr"`
+  Meta    gexfMeta  `xml:"meta"`
+  Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfMeta struct {
+  LastModified string `xml:"lastmodifieddate,attr"`
+  Creator      string `xml:"creator"`
+  Description  string `xml:"description"`
+}
+
+type gexfGraph struct {
+  DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+  Attributes      []gexfAttributes `xml:"attributes"`
+  Nodes           []gexfNode       `xml:"nodes>node"`
+  Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+  Class      string          `xml:"class,attr"`
+  Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+  ID    string `xml:"id,attr"`
+  Title string `xml:"title,attr"`
+  Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+  ID        string         `xml:"id,attr"`
+  Label     string         `xml:"label,attr"`
+  AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+  ID        string         `xml:"id,attr"`
+  Source    string         `xml:"source,attr"`
+  Target    string         `xml:"target,attr"`
+  Kind      string         `xml:"kind,attr"`
+  Label     string         `xml:"label,attr,omitempty"`
+  Weight    string         `xml:"weight,attr,omitempty"`
+  AttValues []gexfAttValue `xml:"attvalues>attvalue,omitempty"`
+}
+
+type gexfAttValue struct {
+  For   string `xml:"for,attr"`
+  Value string `xml:"value,attr"`
+}
+
+// writeGEXF writes the graph as GEXF 1.3, for Gephi. Both kinds of edge join the same nodes,
+// so each edge has a kind to keep Gephi from merging them.
+func writeGEXF(w io.Writer, graph *Graph) error {
+  document := gexfDocument{
+    XMLNS:   "http://gexf.net/1.3",
+    Version: "1.3",
+    Meta: gexfMeta{
+      LastModified: time.Now().Format("2006-01-02"),
+      Creator:      "voice-notetaking-app",
+      Description:  "Knowledge graph of voice notes",
+    },
+    Graph: gexfGraph{
+      DefaultEdgeType: "undirected",
+      Attributes: []gexfAttributes{
+        {Class: "node", Attributes: []gexfAttribute{
+          {ID: "text", Title: "text", Type: "string"},
+          {ID: "concepts", Title: "concepts", Type: "liststring"},
+          {ID: "recording_id", Title: "recording_id", Type: "long"},
+        }},
+        {Class: "edge", Attributes: []gexfAttribute{
+          {ID: "concept", Title: "concept", Type: "string"},
+        }},
+      },
+    },
+  }
+
+  for _, node := range graph.Nodes {
+    document.Graph.Nodes = append(document.Graph.Nodes, gexfNode{
+      ID:    nodeKey(node.ID),
+      Label: nodeLabel(node),
+      AttValues: []gexfAttValue{
+        {For: "text", Value: node.Text},
+        {For: "concepts", Value: "[" + strings.Join(node.Concepts, ", ") + "]"},
+        {For: "recording_id", Value: strconv.FormatInt(node.RecordingID, 10)},
+      },
+    })
+  }
+
+  for i, edge := range graph.Edges {
+    document.Graph.Edges = append(document.Graph.Edges, gexfEdge{
+      ID:     "e" + strconv.Itoa(i+1),
+      Source: nodeKey(edge.SourceID),
+      Target: nodeKey(edge.TargetID),
+      Kind:   KindSimilarity,
+      Weight: formatWeight(edge.Weight),
+    })
+  }
+
+  for _, vertex := range graph.Vertices {
+    document.Graph.Edges = append(document.Graph.Edges, gexfEdge{
+      ID:        "v" + strconv.FormatInt(vertex.ID, 10),
+      Source:    nodeKey(vertex.NodeID),
+      Target:    nodeKey(vertex.TargetID),
+      Kind:      KindConcept,
+      Label:     vertex.Concept,
+      AttValues: []gexfAttValue{{For: "concept", Value: vertex.Concept}},
+    })
+  }
+
+  return writeXML(w, document)
+}
+
+// writeXML writes the document as indented XML after the XML declaration.
+func writeXML(w io.Writer, document interface{}) error {
+  if _, err := io.WriteString(w, xml.Header); err != nil {
+    return err
+  }
+
+  encoder := xml.NewEncoder(w)
+  encoder.Indent("", "  ")
+  if err := encoder.Encode(document); err != nil {
+    return fmt.Errorf("failed to encode XML: %v", err)
+  }
+  _, err := io.WriteString(w, "\n")
+  return err
+}
+
+// writeDOT writes the graph in the DOT language of Graphviz. Similarity edges are drawn thicker the
+// higher their weight; concept edges are dashed and labelled with the concept.
+func writeDOT(w io.Writer, graph *Graph) error {
+  var b strings.Builder
+  b.WriteString("graph \"knowledge-graph\" {\n")
+  b.WriteString("  node [shape=box];\n")
+
+  for _, node := range graph.Nodes {
+    fmt.Fprintf(&b, "  %s [label=%s, text=%s, concepts=%s, recording_id=%d];\n",
+      nodeKey(node.ID), dotQuote(nodeLabel(node)), dotQuote(node.Text),
+      dotQuote(strings.Join(node.Concepts, conceptSeparator)), node.RecordingID)
+  }
+
+  for _, edge := range graph.Edges {
+    fmt.Fprintf(&b, "  %s -- %s [kind=%s, similarity=%s, label=\"%.2f\", penwidth=%.2f];\n",
+      nodeKey(edge.SourceID), nodeKey(edge.TargetID), KindSimilarity,
+      formatWeight(edge.Weight), edge.Weight, 1+4*edge.Weight)
+  }
+
+  for _, vertex := range graph.Vertices {
+    fmt.Fprintf(&b, "  %s -- %s [kind=%s, concept=%s, label=%s, style=dashed];\n",
+      nodeKey(vertex.NodeID), nodeKey(vertex.TargetID), KindConcept,
+      dotQuote(vertex.Concept), dotQuote(vertex.Concept))
+  }
+
+  b.WriteString("}\n")
+  _, err := io.WriteString(w, b.String())
+  return err
+}
+
+// dotQuote returns the string as a quoted DOT ID.
+func dotQuote(s string) string {
+  replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+  return `"` + replacer.Replace(s) + `"`
+}
+
+// jgfDocument is a single graph in JSON Graph Format version 2, which keys nodes by ID, with the objects below.
+type jgfDocument struct {
+  Graph jgfGraph `json:"graph"`
+}
+
+type jgfGraph struct {
+  ID       string                 `json:"id,omitempty"`
+  Type     string                 `json:"type,omitempty"`
+  Label    string                 `json:"label,omitempty"`
+  Directed bool                   `json:"directed"`
+  Metadata map[string]interface{} `json:"metadata,omitempty"`
+  Nodes    map[string]jgfNode     `json:"nodes"`
+  Edges    []jgfEdge              `json:"edges"`
+}
+
+type jgfNode struct {
+  Label    string      `json:"label,omitempty"`
+  Metadata jgfMetadata `json:"metadata"`
+}
+
+type jgfEdge struct {
+  Source   string      `json:"source"`
+  Target   string      `json:"target"`
+  Relation string      `json:"relation"`
+  Label    string      `json:"label,omitempty"`
+  Metadata jgfMetadata `json:"metadata"`
+}
+
+// jgfMetadata holds the attributes of nodes and edges. Weight is a pointer so that it is
+// kept for similarity edges of weight 0 but left out of concept edges.
+type jgfMetadata struct {
+  Text        string   `json:"text,omitempty"`
+  Concepts    []string `json:"concepts,omitempty"`
+  RecordingID int64    `json:"recording_id,omitempty"`
+  Weight      *float64 `json:"weight,omitempty"`
+  Concept     string   `json:"concept,omitempty"`
+}
+
+// writeJGF writes the graph in JSON Graph Format, for Cytoscape and other JSON tools.
+func writeJGF(w io.Writer, graph *Graph) error {
+  document := jgfDocument{Graph: jgfGraph{
+    ID:       "knowledge-graph",
+    Type:     "knowledge-graph",
+    Label:    "Knowledge graph of voice notes",
+    Metadata: map[string]interface{}{"exported_at": time.Now().UTC().Format(time.RFC3339)},
+    Nodes:    make(map[string]jgfNode, len(graph.Nodes)),
+    Edges:    make([]jgfEdge, 0, len(graph.Edges)+len(graph.Vertices)),
+  }}
+
+  for _, node := range graph.Nodes {
+    document.Graph.Nodes[nodeKey(node.ID)] = jgfNode{
+      Label: nodeLabel(node),
+      Metadata: jgfMetadata{
+        Text:        node.Text,
+        Concepts:    node.Concepts,
+        RecordingID: node.RecordingID,
+      },
+    }
+  }
+
+  for _, edge := range graph.Edges {
+    weight := edge.Weight
+    document.Graph.Edges = append(document.Graph.Edges, jgfEdge{
+      Source:   nodeKey(edge.SourceID),
+      Target:   nodeKey(edge.TargetID),
+      Relation: KindSimilarity,
+      Metadata: jgfMetadata{Weight: &weight},
+    })
+  }
+
+  for _, vertex := range graph.Vertices {
+    document.Graph.Edges = append(document.Graph.Edges, jgfEdge{
+      Source:   nodeKey(vertex.NodeID),
+      Target:   nodeKey(vertex.TargetID),
+      Relation: KindConcept,
+      Label:    vertex.Concept,
+      Metadata: jgfMetadata{Concept: vertex.Concept},
+    })
+  }
+
+  encoder := json.NewEncoder(w)
+  encoder.SetIndent("", "  ")
+  if err := encoder.Encode(document); err != nil {
+    return fmt.Errorf("failed to encode JSON: %v", err)
+  }
+  return nil
+}
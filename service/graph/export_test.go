@@ -0,0 +1,218 @@
+package graph
+
+import (
+  "bytes"
+  "encoding/xml"
+  "fmt"
+  "reflect"
+  "sort"
+  "strings"
+  "testing"
+)
+
+// testExportGraph returns a graph whose first two nodes are joined by a similarity edge and a
+// concept edge, while the last two are only joined by a concept edge, as an import can leave them.
+func testExportGraph() *Graph {
+  return &Graph{
+    UserID: 1,
+    Nodes: []Node{
+      {ID: 1, RecordingID: 7, Text: "Budget review", Concepts: []string{"budget", "finance"}},
+      {ID: 2, RecordingID: 8, Text: "Trip \"plan\" for <next> week & the offsite\nwith C:\\ paths", Concepts: []string{"travel", "budget"}},
+      {ID: 3, Text: "Offsite agenda", Concepts: []string{"hiring"}},
+    },
+    Edges: []Edge{
+      {SourceID: 2, TargetID: 1, Weight: 1.0 / 3},
+    },
+    Vertices: []Vertex{
+      {ID: 1, NodeID: 2, TargetID: 1, Concept: "budget"},
+      {ID: 2, NodeID: 3, TargetID: 2, Concept: "offsite"},
+    },
+  }
+}
+
+// describeImport describes an imported file in terms of node texts, in a canonical order, so files
+// are compared independently of the IDs they use.
+func describeImport(file *ImportFile) []string {
+  texts := make(map[string]string)
+  var description []string
+  for _, node := range file.nodes {
+    texts[node.key] = node.text
+    description = append(description, fmt.Sprintf("node %q %q", node.text, node.concepts))
+  }
+  for _, edge := range file.edges {
+    ends := []string{texts[edge.source], texts[edge.target]}
+    sort.Strings(ends)
+    if edge.kind == KindConcept {
+      description = append(description, fmt.Sprintf("concept %q %q", ends, edge.concept))
+    } else {
+      description = append(description, fmt.Sprintf("edge %q %v %v", ends, edge.weight, edge.hasWeight))
+    }
+  }
+  sort.Strings(description)
+  return description
+}
+
+func TestSubgraph(t *testing.T) {
+  graph := testExportGraph()
+  tests := []struct {
+    name     string
+    filter   Filter
+    nodes    int
+    edges    int
+    vertices []string
+  }{
+    {"whole graph", Filter{}, 3, 1, []string{"budget", "offsite"}},
+    {"min weight kept", Filter{MinWeight: 0.3}, 3, 1, []string{"budget", "offsite"}},
+    {"min weight dropped", Filter{MinWeight: 0.5}, 3, 0, []string{"offsite"}},
+    {"concept", Filter{Concept: "BUDGET"}, 2, 1, []string{"budget"}},
+    {"concept and min weight", Filter{Concept: "budget", MinWeight: 0.5}, 2, 0, nil},
+    {"concept of one node", Filter{Concept: "Hiring"}, 1, 0, nil},
+    {"unknown concept", Filter{Concept: "sales"}, 0, 0, nil},
+  }
+  for _, tt := range tests {
+    subgraph := graph.Subgraph(tt.filter)
+    var vertices []string
+    for _, vertex := range subgraph.Vertices {
+      vertices = append(vertices, vertex.Concept)
+    }
+    if len(subgraph.Nodes) != tt.nodes || len(subgraph.Edges) != tt.edges || !reflect.DeepEqual(vertices, tt.vertices) {
+      t.Errorf("%s: subgraph has %d nodes, %d edges and vertices %q, want %d, %d and %q",
+        tt.name, len(subgraph.Nodes), len(subgraph.Edges), vertices, tt.nodes, tt.edges, tt.vertices)
+    }
+  }
+
+  // The subgraph does not share the concepts of the graph
+  graph.Subgraph(Filter{}).Nodes[0].Concepts[0] = "changed"
+  if graph.Nodes[0].Concepts[0] != "budget" {
+    t.Error("changing the subgraph changed the graph")
+  }
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+  for _, name := range ImportFormatNames() {
+    t.Run(name, func(t *testing.T) {
+      format, ok := LookupFormat(name)
+      if !ok {
+        t.Fatalf("no export format %s", name)
+      }
+      var exported bytes.Buffer
+      if err := format.Export(&exported, testExportGraph(), Filter{}); err != nil {
+        t.Fatal(err)
+      }
+      file, err := ReadImportFile(bytes.NewReader(exported.Bytes()), "")
+      if err != nil {
+        t.Fatal(err)
+      }
+      if file.Format != name {
+        t.Errorf("detected format %s, want %s", file.Format, name)
+      }
+      want := []string{
+        `concept ["Budget review" "Trip \"plan\" for <next> week & the offsite\nwith C:\\ paths"] "budget"`,
+        `concept ["Offsite agenda" "Trip \"plan\" for <next> week & the offsite\nwith C:\\ paths"] "offsite"`,
+        `edge ["Budget review" "Trip \"plan\" for <next> week & the offsite\nwith C:\\ paths"] 0.3333333333333333 true`,
+        `node "Budget review" ["budget" "finance"]`,
+        `node "Offsite agenda" ["hiring"]`,
+        `node "Trip \"plan\" for <next> week & the offsite\nwith C:\\ paths" ["travel" "budget"]`,
+      }
+      if got := describeImport(file); !reflect.DeepEqual(got, want) {
+        t.Fatalf("read back\n%q\nwant\n%q", got, want)
+      }
+
+      // Importing the file and exporting the result gives the same graph
+      graph, err := LoadGraph(initTestDB(t))
+      if err != nil {
+        t.Fatal(err)
+      }
+      report, err := Import(graph, file, false)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if len(report.Conflicts) != 0 || report.NodesCreated != 3 || report.VerticesCreated != 2 {
+        t.Fatalf("import report = %+v, want 3 nodes and 2 vertices created without conflicts", report)
+      }
+      var reexported bytes.Buffer
+      if err := format.Export(&reexported, graph, Filter{}); err != nil {
+        t.Fatal(err)
+      }
+      file, err = ReadImportFile(&reexported, name)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if got := describeImport(file); !reflect.DeepEqual(got, want) {
+        t.Errorf("exported after importing\n%q\nwant\n%q", got, want)
+      }
+    })
+  }
+}
+
+func TestWriteDOT(t *testing.T) {
+  var b bytes.Buffer
+  format, _ := LookupFormat(FormatDOT)
+  if err := format.Export(&b, testExportGraph(), Filter{}); err != nil {
+    t.Fatal(err)
+  }
+  want := `graph "knowledge-graph" {
+  node [shape=box];
+  n1 [label="Budget review", text="Budget review", concepts="budget; finance", recording_id=7];
+  n2 [label="Trip \"plan\" for <next> week & the offsite with C:\\ paths", text="Trip \"plan\" for <next> week & the offsite\nwith C:\\ paths", concepts="travel; budget", recording_id=8];
+  n3 [label="Offsite agenda", text="Offsite agenda", concepts="hiring", recording_id=0];
+  n2 -- n1 [kind=similarity, similarity=0.3333333333333333, label="0.33", penwidth=2.33];
+  n2 -- n1 [kind=concept, concept="budget", label="budget", style=dashed];
+  n3 -- n2 [kind=concept, concept="offsite", label="offsite", style=dashed];
+}
+`
+  if b.String() != want {
+    t.Errorf("DOT =\n%s\nwant\n%s", b.String(), want)
+  }
+}
+
+func TestDOTQuote(t *testing.T) {
+  tests := []struct {
+    s    string
+    want string
+  }{
+    {"budget", `"budget"`},
+    {`say "hi"`, `"say \"hi\""`},
+    {`C:\notes`, `"C:\\notes"`},
+    {"one\r\ntwo\nthree\rfour", `"one\ntwo\nthree\nfour"`},
+    {"", `""`},
+  }
+  for _, tt := range tests {
+    if got := dotQuote(tt.s); got != tt.want {
+      t.Errorf("dotQuote(%q) = %s, want %s", tt.s, got, tt.want)
+    }
+  }
+}
+
+func TestWriteGEXF(t *testing.T) {
+  var b bytes.Buffer
+  format, _ := LookupFormat(FormatGEXF)
+  if err := format.Export(&b, testExportGraph(), Filter{MinWeight: 0.5}); err != nil {
+    t.Fatal(err)
+  }
+  if !strings.HasPrefix(b.String(), xml.Header) {
+    t.Errorf("GEXF does not start with an XML declaration")
+  }
+
+  var document gexfDocument
+  if err := xml.Unmarshal(b.Bytes(), &document); err != nil {
+    t.Fatal(err)
+  }
+  if len(document.Graph.Nodes) != 3 || document.Graph.Nodes[1].AttValues[0].Value != testExportGraph().Nodes[1].Text {
+    t.Errorf("GEXF nodes = %+v", document.Graph.Nodes)
+  }
+  if edges := document.Graph.Edges; len(edges) != 1 || edges[0].Kind != KindConcept || edges[0].Label != "offsite" || edges[0].ID != "v2" {
+    t.Errorf("GEXF edges = %+v, want only the offsite concept edge", edges)
+  }
+}
+
+func TestLookupFormat(t *testing.T) {
+  for _, name := range []string{"graphml", "GEXF", "dot", "jgf", "json"} {
+    if _, ok := LookupFormat(name); !ok {
+      t.Errorf("LookupFormat(%q) found no format", name)
+    }
+  }
+  if _, ok := LookupFormat("csv"); ok {
+    t.Error("LookupFormat(csv) found a format")
+  }
+}
@@ -0,0 +1,266 @@
+package graph
+
+import (
+  "fmt"
+  "sync"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+)
+
+// Define the Node struct
+type Node struct {
+  ID          int64
+  RecordingID int64
+  Text        string
+  Concepts    []string
+}
+
+// Define the Edge struct
+type Edge struct {
+  SourceID int64
+  TargetID int64
+  Weight   float64
+}
+
+// Define the Vertex struct
+type Vertex struct {
+  ID       int64
+  NodeID   int64
+  TargetID int64
+  Concept  string
+}
+
+// Define the Graph struct, holding the knowledge graph of a single user.
+// A graph is safe for concurrent use through its methods and the functions of this package: updates
+// hold its write lock from computing the new edges until they are applied, so concurrent uploads see
+// each other's nodes. Its fields must not be accessed directly once the graph is shared.
+type Graph struct {
+  mu sync.RWMutex
+
+  UserID   int64
+  Nodes    []Node
+  Edges    []Edge
+  Vertices []Vertex
+
+  // concepts indexes the nodes by concept, positions locates them in Nodes by ID and links
+  // connects them through their edges and vertices. They are built when the graph is loaded or
+  // first changed and kept up to date as nodes are added and deleted.
+  concepts  conceptIndex
+  positions map[int64]int
+  links     linkIndex
+}
+
+// CalculateWeight calculates the weight between two sets of concepts based on Jaccard similarity
+func calculateWeight(concepts1, concepts2 []string) float64 {
+  // Convert concept slices to sets for easier comparison
+  set1 := make(map[string]struct{})
+  set2 := make(map[string]struct{})
+
+  for _, concept := range concepts1 {
+    set1[concept] = struct{}{}
+  }
+
+  for _, concept := range concepts2 {
+    set2[concept] = struct{}{}
+  }
+
+  // Calculate Jaccard similarity
+  intersection := 0
+  for concept := range set1 {
+    if _, exists := set2[concept]; exists {
+      intersection++
+    }
+  }
+
+  union := len(set1) + len(set2) - intersection
+
+  // Prevent division by zero
+  if union == 0 {
+    return 0.0
+  }
+
+  return float64(intersection) / float64(union)
+}
+
+// Contains checks if a string exists in a slice
+func contains(slice []string, item string) bool {
+  for _, s := range slice {
+    if s == item {
+      return true
+    }
+  }
+  return false
+}
+
+// BuildOrUpdateKnowledgeGraph builds the knowledge graph with the provided note text and tags, or updates an existing graph.
+// The new node, its edges and vertices are stored in a single transaction and only added to the
+// in-memory graph once committed, so a failed update leaves both unchanged. It returns the ID of the new node.
+func BuildOrUpdateKnowledgeGraph(graph *Graph, recordingID int64, noteText string, tags []string) (int64, error) {
+  graph.mu.Lock()
+  defer graph.mu.Unlock()
+
+  // Extract concepts from tags (assuming tags represent concepts), once each as they are stored
+  var concepts []string
+  for _, tag := range tags {
+    if !contains(concepts, tag) {
+      concepts = append(concepts, tag)
+    }
+  }
+
+  // Create nodes for the note
+  node := Node{
+    RecordingID: recordingID,
+    Text:        noteText,
+    Concepts:    concepts,
+  }
+
+  // Create edges and vertices to the nodes sharing a concept with the note, which are the only
+  // ones with a non-zero weight
+  edges, vertices := graph.relate(node)
+
+  // Store the node, edges and vertices, letting the database assign their IDs
+  err := sqlite.WithTx(func(tx *sqlite.Tx) error {
+    nodeID, err := tx.InsertNode(graph.UserID, node.Text)
+    if err != nil {
+      return fmt.Errorf("failed to insert node: %v", err)
+    }
+    node.ID = nodeID
+
+    if err := tx.SetNodeRecording(node.ID, node.RecordingID); err != nil {
+      return fmt.Errorf("failed to link node to recording: %v", err)
+    }
+
+    if err := tx.SetNodeConcepts(graph.UserID, node.ID, node.Concepts); err != nil {
+      return fmt.Errorf("failed to insert node concepts: %v", err)
+    }
+
+    for i := range edges {
+      edges[i].SourceID = node.ID
+      if _, err := tx.InsertEdge(edges[i].SourceID, edges[i].TargetID, edges[i].Weight); err != nil {
+        return fmt.Errorf("failed to insert edge: %v", err)
+      }
+    }
+
+    for i := range vertices {
+      vertices[i].NodeID = node.ID
+      vertexID, err := tx.InsertVertex(vertices[i].NodeID, vertices[i].TargetID, vertices[i].Concept)
+      if err != nil {
+        return fmt.Errorf("failed to insert vertex: %v", err)
+      }
+      vertices[i].ID = vertexID
+    }
+
+    return nil
+  })
+  if err != nil {
+    return 0, err
+  }
+
+  graph.addNode(node, edges, vertices)
+
+  return node.ID, nil
+}
+
+// DeleteNode deletes a node of the graph together with every edge and vertex touching it, from the
+// database and then from the in-memory graph. It returns sql.ErrNoRows if the graph has no such node.
+func DeleteNode(graph *Graph, nodeID int64) error {
+  graph.mu.Lock()
+  defer graph.mu.Unlock()
+
+  err := sqlite.WithTx(func(tx *sqlite.Tx) error {
+    return tx.DeleteNode(graph.UserID, nodeID)
+  })
+  if err != nil {
+    return err
+  }
+
+  graph.removeNode(nodeID)
+
+  return nil
+}
+
+// LoadGraph loads the knowledge graph of the user from the database
+func LoadGraph(userID int64) (*Graph, error) {
+  graph := &Graph{UserID: userID}
+
+  nodes, edges, vertices, err := sqlite.GetGraph(userID)
+  if err != nil {
+    return nil, fmt.Errorf("failed to load graph from database: %v", err)
+  }
+
+  for _, node := range nodes {
+    graph.Nodes = append(graph.Nodes, Node{
+      ID:          node.ID,
+      RecordingID: node.RecordingID,
+      Text:        node.Text,
+      Concepts:    node.Concepts,
+    })
+  }
+
+  for _, edge := range edges {
+    graph.Edges = append(graph.Edges, Edge{
+      SourceID: edge.SourceID,
+      TargetID: edge.TargetID,
+      Weight:   edge.Weight,
+    })
+  }
+
+  for _, vertex := range vertices {
+    graph.Vertices = append(graph.Vertices, Vertex{
+      ID:       vertex.ID,
+      NodeID:   vertex.NodeID,
+      TargetID: vertex.TargetID,
+      Concept:  vertex.Concept,
+    })
+  }
+
+  graph.ensureIndex()
+
+  return graph, nil
+}
+
+// UserGraphs holds the knowledge graph of each user, loading it from the database on first use
+type UserGraphs struct {
+  mu     sync.Mutex
+  graphs map[int64]*graphLoad
+}
+
+// graphLoad is the graph of a user, which is ready once done is closed.
+type graphLoad struct {
+  done  chan struct{}
+  graph *Graph
+  err   error
+}
+
+// NewUserGraphs creates an empty set of per-user knowledge graphs
+func NewUserGraphs() *UserGraphs {
+  return &UserGraphs{graphs: make(map[int64]*graphLoad)}
+}
+
+// Get returns the knowledge graph of the user, loading it from the database if needed.
+// Concurrent callers for the same user always share a single graph and wait for one load, without
+// holding up the callers for other users. A failed load is retried by the next call.
+func (g *UserGraphs) Get(userID int64) (*Graph, error) {
+  g.mu.Lock()
+  load, ok := g.graphs[userID]
+  if !ok {
+    load = &graphLoad{done: make(chan struct{})}
+    g.graphs[userID] = load
+  }
+  g.mu.Unlock()
+
+  if ok {
+    <-load.done
+    return load.graph, load.err
+  }
+
+  load.graph, load.err = LoadGraph(userID)
+  if load.err != nil {
+    g.mu.Lock()
+    delete(g.graphs, userID)
+    g.mu.Unlock()
+  }
+  close(load.done)
+
+  return load.graph, load.err
+}
@@ -0,0 +1,207 @@
+package graph
+
+import (
+  "fmt"
+  "math/rand"
+  "reflect"
+  "sort"
+  "sync"
+  "testing"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+)
+
+// graphContents describes a graph in a canonical order, for comparing graphs.
+func graphContents(graph *Graph) []string {
+  var contents []string
+  for _, node := range graph.Nodes {
+    concepts := append([]string(nil), node.Concepts...)
+    sort.Strings(concepts)
+    contents = append(contents, fmt.Sprintf("node %d %d %q %q", node.ID, node.RecordingID, node.Text, concepts))
+  }
+  for _, edge := range graph.Edges {
+    contents = append(contents, fmt.Sprintf("edge %d %d %v", edge.SourceID, edge.TargetID, edge.Weight))
+  }
+  for _, vertex := range graph.Vertices {
+    contents = append(contents, fmt.Sprintf("vertex %d %d %d %q", vertex.ID, vertex.NodeID, vertex.TargetID, vertex.Concept))
+  }
+  sort.Strings(contents)
+  return contents
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+  const (
+    writers = 8
+    notes   = 12
+    readers = 4
+  )
+  userID := initTestDB(t)
+  recordingIDs := make([]int64, writers*notes)
+  for i := range recordingIDs {
+    id, err := sqlite.InsertRecording(userID, fmt.Sprintf("note-%d.mp3", i), "A note.")
+    if err != nil {
+      t.Fatal(err)
+    }
+    recordingIDs[i] = id
+  }
+  concepts := []string{"budget", "travel", "hiring", "finance", "planning", "review"}
+  graphs := NewUserGraphs()
+
+  var mu sync.Mutex
+  created := make(map[int64]bool)
+  deleted := 0
+  shared := make(map[*Graph]bool)
+
+  stop := make(chan struct{})
+  var reading sync.WaitGroup
+  for r := 0; r < readers; r++ {
+    reading.Add(1)
+    go func(r int) {
+      defer reading.Done()
+      random := rand.New(rand.NewSource(int64(r)))
+      graph, err := graphs.Get(userID)
+      if err != nil {
+        t.Error(err)
+        return
+      }
+      for {
+        select {
+        case <-stop:
+          return
+        default:
+        }
+        a, b := int64(random.Intn(writers*notes)+1), int64(random.Intn(writers*notes)+1)
+        if _, err := graph.Neighbors(a); err != nil && err != ErrNodeNotFound {
+          t.Error(err)
+        }
+        if _, _, err := graph.FindPath(a, b, []string{PathShortest, PathStrongest}[r%2]); err != nil && err != ErrNodeNotFound {
+          t.Error(err)
+        }
+        subgraph := graph.Subgraph(Filter{Concept: concepts[random.Intn(len(concepts))]})
+        for _, node := range subgraph.Nodes {
+          if node.ID == 0 {
+            t.Error("subgraph has a node without an ID")
+          }
+        }
+        graph.NodesByConcept(concepts[random.Intn(len(concepts))])
+      }
+    }(r)
+  }
+
+  var writing sync.WaitGroup
+  for w := 0; w < writers; w++ {
+    writing.Add(1)
+    go func(w int) {
+      defer writing.Done()
+      graph, err := graphs.Get(userID)
+      if err != nil {
+        t.Error(err)
+        return
+      }
+      mu.Lock()
+      shared[graph] = true
+      mu.Unlock()
+
+      for i := 0; i < notes; i++ {
+        tags := []string{concepts[(w+i)%len(concepts)], concepts[(w*i+1)%len(concepts)]}
+        nodeID, err := BuildOrUpdateKnowledgeGraph(graph, recordingIDs[w*notes+i], fmt.Sprintf("note %d of writer %d", i, w), tags)
+        if err != nil {
+          t.Error(err)
+          return
+        }
+
+        mu.Lock()
+        if created[nodeID] {
+          t.Errorf("node ID %d was returned twice", nodeID)
+        }
+        created[nodeID] = true
+        mu.Unlock()
+
+        if i%3 == 2 {
+          if err := DeleteNode(graph, nodeID); err != nil {
+            t.Error(err)
+            return
+          }
+          mu.Lock()
+          deleted++
+          mu.Unlock()
+        }
+      }
+    }(w)
+  }
+  writing.Wait()
+  close(stop)
+  reading.Wait()
+  if t.Failed() {
+    return
+  }
+
+  if len(shared) != 1 {
+    t.Fatalf("writers got %d different graphs, want one shared graph", len(shared))
+  }
+  graph, err := graphs.Get(userID)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if !shared[graph] {
+    t.Fatal("the graph of the user changed after it was loaded")
+  }
+  if len(created) != writers*notes || len(graph.Nodes) != writers*notes-deleted {
+    t.Fatalf("created %d nodes and left %d, want %d and %d", len(created), len(graph.Nodes), writers*notes, writers*notes-deleted)
+  }
+
+  stored, err := LoadGraph(userID)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got, want := graphContents(graph), graphContents(stored); !reflect.DeepEqual(got, want) {
+    t.Fatalf("in-memory graph differs from the stored graph:\n%q\nwant\n%q", got, want)
+  }
+
+  // The in-memory indexes match ones built from the stored graph
+  stored.ensureIndex()
+  graph.mu.RLock()
+  defer graph.mu.RUnlock()
+  if len(graph.concepts) != len(stored.concepts) || len(graph.links) != len(stored.links) {
+    t.Errorf("in-memory graph indexes %d concepts and %d linked nodes, want %d and %d",
+      len(graph.concepts), len(graph.links), len(stored.concepts), len(stored.links))
+  }
+}
+
+func TestUserGraphsGet(t *testing.T) {
+  userID := initTestDB(t)
+  otherID, err := sqlite.InsertUser("Ben", "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  graphs := NewUserGraphs()
+
+  results := make([]*Graph, 10)
+  var wg sync.WaitGroup
+  for i := range results {
+    wg.Add(1)
+    go func(i int) {
+      defer wg.Done()
+      id := userID
+      if i%2 == 1 {
+        id = otherID
+      }
+      graph, err := graphs.Get(id)
+      if err != nil {
+        t.Error(err)
+        return
+      }
+      results[i] = graph
+    }(i)
+  }
+  wg.Wait()
+
+  for i, graph := range results {
+    if graph == nil || graph != results[i%2] {
+      t.Fatalf("Get returned different graphs for the same user")
+    }
+  }
+  if results[0] == results[1] || results[0].UserID != userID || results[1].UserID != otherID {
+    t.Errorf("users share a graph or got the graph of another user")
+  }
+}
@@ -0,0 +1,662 @@
+package graph
+
+import (
+  "bufio"
+  "bytes"
+  "encoding/json"
+  "encoding/xml"
+  "fmt"
+  "io"
+  "math"
+  "sort"
+  "strconv"
+  "strings"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/service/tagging"
+)
+
+// Import conflict kinds.
+const (
+  // ConflictText is reported when a node is merged into an existing node with a different text,
+  // which is kept.
+  ConflictText = "text"
+
+  // ConflictWeight is reported when an edge joins nodes already joined with a different weight,
+  // which is kept.
+  ConflictWeight = "weight"
+
+  // ConflictDuplicateNode is reported when a node ID appears more than once; only the first is imported.
+  ConflictDuplicateNode = "duplicate_node"
+
+  // ConflictInvalidNode is reported when a node has no text; it is not imported.
+  ConflictInvalidNode = "invalid_node"
+
+  // ConflictMissingNode is reported when an edge refers to a node that was not imported; it is skipped.
+  ConflictMissingNode = "missing_node"
+
+  // ConflictInvalidEdge is reported when an edge has an unreadable weight, which is then computed
+  // from the concepts of its nodes, or a concept edge has no concept and is skipped.
+  ConflictInvalidEdge = "invalid_edge"
+)
+
+// Conflict is a part of an imported file that could not be imported as is.
+// Key is the ID of the node or edge in the file, or "#n" for the n-th edge when it has no ID, and
+// NodeID the existing node it conflicts with, if any.
+type Conflict struct {
+  Kind    string `json:"kind"`
+  Key     string `json:"key"`
+  NodeID  int64  `json:"node_id,omitempty"`
+  Message string `json:"message"`
+}
+
+// ImportedNode tells what became of a node of an imported file: either merged into an existing
+// node or created. Created nodes have no ID in a dry run.
+type ImportedNode struct {
+  Key    string `json:"key"`
+  NodeID int64  `json:"node_id,omitempty"`
+  Merged bool   `json:"merged"`
+}
+
+// ImportReport describes an import, or what it would do in a dry run.
+type ImportReport struct {
+  DryRun bool   `json:"dry_run"`
+  Format string `json:"format"`
+
+  NodesCreated    int `json:"nodes_created"`
+  NodesMerged     int `json:"nodes_merged"`
+  EdgesCreated    int `json:"edges_created"`
+  EdgesSkipped    int `json:"edges_skipped"`
+  VerticesCreated int `json:"vertices_created"`
+  VerticesSkipped int `json:"vertices_skipped"`
+
+  Nodes     []ImportedNode `json:"nodes"`
+  Conflicts []Conflict     `json:"conflicts"`
+}
+
+// importFormats are the formats graphs can be imported from.
+var importFormats = []string{FormatJGF, FormatGraphML}
+
+// ImportFormatNames returns the names of the formats graphs can be imported from.
+func ImportFormatNames() []string {
+  return append([]string(nil), importFormats...)
+}
+
+// ReadImportFile reads a graph in JSON Graph Format or GraphML, detecting the format from the content
+// when it is empty. Files exported by this application can be read back.
+func ReadImportFile(r io.Reader, format string) (*ImportFile, error) {
+  reader := bufio.NewReader(r)
+  if format == "" {
+    format = detectImportFormat(reader)
+  }
+
+  var file *ImportFile
+  var err error
+  switch strings.ToLower(format) {
+  case FormatJGF, "json":
+    file, err = parseJGF(reader)
+  case FormatGraphML:
+    file, err = parseGraphML(reader)
+  default:
+    return nil, fmt.Errorf("unsupported import format %q, expected one of %s", format, strings.Join(importFormats, ", "))
+  }
+  if err != nil {
+    return nil, err
+  }
+  return file, nil
+}
+
+// Import merges the graph read from a file into the knowledge graph.
+//
+// Each incoming node whose concepts are the same as those of an existing node is merged into it; the
+// others are created with new IDs, without a recording. Incoming edges and concept edges are added
+// between the nodes they now map to unless the nodes are already joined, and each created node is also
+// related to the nodes sharing its concepts like an uploaded note. Everything is stored in a single
+// transaction. In a dry run nothing is stored and the report tells what would happen.
+func Import(graph *Graph, file *ImportFile, dryRun bool) (ImportReport, error) {
+  graph.mu.Lock()
+  defer graph.mu.Unlock()
+
+  plan := graph.planImport(file)
+  plan.report.DryRun = dryRun
+  plan.report.Format = file.Format
+  if dryRun {
+    return plan.report, nil
+  }
+
+  if err := graph.applyImport(plan); err != nil {
+    return ImportReport{}, err
+  }
+  return plan.report, nil
+}
+
+// detectImportFormat guesses the format of a file from its first non-space character.
+func detectImportFormat(reader *bufio.Reader) string {
+  for {
+    b, err := reader.Peek(1)
+    if err != nil {
+      return ""
+    }
+    switch b[0] {
+    case ' ', '\t', '\r', '\n':
+      reader.ReadByte()
+    case '{':
+      return FormatJGF
+    case '<':
+      return FormatGraphML
+    default:
+      return ""
+    }
+  }
+}
+
+// ImportFile is a graph read from a file, with its nodes keyed by the IDs used in the file.
+type ImportFile struct {
+  Format string
+
+  nodes []importedNode
+  edges []importedEdge
+}
+
+type importedNode struct {
+  key      string
+  text     string
+  concepts []string
+}
+
+// importedEdge is a similarity edge, or a concept edge when kind is KindConcept.
+// weight is only used when hasWeight is set; weightErr is set when it could not be read.
+type importedEdge struct {
+  key       string
+  source    string
+  target    string
+  kind      string
+  weight    float64
+  hasWeight bool
+  weightErr bool
+  concept   string
+}
+
+// importPlan holds the changes an import makes. Nodes are referred to by their ID when they exist
+// and by -(i+1) when they are the i-th created node, which has no ID yet.
+type importPlan struct {
+  nodes    []Node
+  edges    []Edge
+  vertices []Vertex
+  report   ImportReport
+}
+
+// planImport maps the incoming graph onto the knowledge graph. It must be called with the write lock held.
+func (g *Graph) planImport(incoming *ImportFile) *importPlan {
+  g.ensureIndex()
+
+  plan := &importPlan{report: ImportReport{Nodes: []ImportedNode{}, Conflicts: []Conflict{}}}
+  conflict := func(kind, key string, nodeID int64, format string, args ...interface{}) {
+    plan.report.Conflicts = append(plan.report.Conflicts, Conflict{Kind: kind, Key: key, NodeID: nodeID, Message: fmt.Sprintf(format, args...)})
+  }
+
+  // Merge or create the nodes
+  refs := make(map[string]int64)
+  concepts := make(map[int64][]string)
+  for _, in := range incoming.nodes {
+    if _, ok := refs[in.key]; ok {
+      conflict(ConflictDuplicateNode, in.key, 0, "node %s appears more than once, only the first is imported", in.key)
+      continue
+    }
+    if in.text == "" {
+      conflict(ConflictInvalidNode, in.key, 0, "node %s has no text", in.key)
+      continue
+    }
+
+    if existing, ok := g.matchNode(in.concepts); ok {
+      refs[in.key] = existing.ID
+      concepts[existing.ID] = existing.Concepts
+      plan.report.NodesMerged++
+      plan.report.Nodes = append(plan.report.Nodes, ImportedNode{Key: in.key, NodeID: existing.ID, Merged: true})
+      if in.text != existing.Text {
+        conflict(ConflictText, in.key, existing.ID, "node %s has the concepts of node %d but a different text, which is kept", in.key, existing.ID)
+      }
+      continue
+    }
+
+    plan.nodes = append(plan.nodes, Node{Text: in.text, Concepts: in.concepts})
+    ref := -int64(len(plan.nodes))
+    refs[in.key] = ref
+    concepts[ref] = in.concepts
+    plan.report.NodesCreated++
+    plan.report.Nodes = append(plan.report.Nodes, ImportedNode{Key: in.key})
+  }
+
+  weights := make(map[pairKey]float64, len(g.Edges))
+  for _, edge := range g.Edges {
+    weights[newPairKey(edge.SourceID, edge.TargetID)] = edge.Weight
+  }
+  type vertexKey struct {
+    pair    pairKey
+    concept string
+  }
+  vertices := make(map[vertexKey]bool, len(g.Vertices))
+  for _, vertex := range g.Vertices {
+    vertices[vertexKey{newPairKey(vertex.NodeID, vertex.TargetID), vertex.Concept}] = true
+  }
+
+  addEdge := func(edge Edge) bool {
+    key := newPairKey(edge.SourceID, edge.TargetID)
+    if _, ok := weights[key]; ok || edge.SourceID == edge.TargetID {
+      return false
+    }
+    weights[key] = edge.Weight
+    plan.edges = append(plan.edges, edge)
+    plan.report.EdgesCreated++
+    return true
+  }
+  addVertex := func(vertex Vertex) bool {
+    key := vertexKey{newPairKey(vertex.NodeID, vertex.TargetID), vertex.Concept}
+    if vertices[key] || vertex.NodeID == vertex.TargetID {
+      return false
+    }
+    vertices[key] = true
+    plan.vertices = append(plan.vertices, vertex)
+    plan.report.VerticesCreated++
+    return true
+  }
+
+  // Add the incoming edges between the nodes they now map to
+  for _, in := range incoming.edges {
+    source, sourceOK := refs[in.source]
+    target, targetOK := refs[in.target]
+    if !sourceOK || !targetOK {
+      conflict(ConflictMissingNode, in.key, 0, "edge %s joins %s and %s, which were not imported", in.key, in.source, in.target)
+      plan.skip(in.kind)
+      continue
+    }
+
+    if in.kind == KindConcept {
+      if in.concept == "" {
+        conflict(ConflictInvalidEdge, in.key, 0, "concept edge %s has no concept", in.key)
+        plan.report.VerticesSkipped++
+        continue
+      }
+      if !addVertex(Vertex{NodeID: source, TargetID: target, Concept: in.concept}) {
+        plan.report.VerticesSkipped++
+      }
+      continue
+    }
+
+    weight := in.weight
+    if in.weightErr {
+      conflict(ConflictInvalidEdge, in.key, 0, "edge %s has a weight that is not a number between 0 and 1, which is computed from the concepts of its nodes", in.key)
+    }
+    if !in.hasWeight {
+      weight = calculateWeight(concepts[source], concepts[target])
+    }
+    if existing, ok := weights[newPairKey(source, target)]; ok && source != target && existing != weight {
+      conflict(ConflictWeight, in.key, 0, "edge %s has weight %s but its nodes are already joined with weight %s, which is kept",
+        in.key, formatWeight(weight), formatWeight(existing))
+    }
+    if !addEdge(Edge{SourceID: source, TargetID: target, Weight: weight}) {
+      plan.report.EdgesSkipped++
+    }
+  }
+
+  // Relate each created node to the existing and created nodes sharing its concepts, like an uploaded note
+  created := make(conceptIndex)
+  for i, node := range plan.nodes {
+    node.ID = -int64(i + 1)
+    edges, related := g.relate(node)
+
+    var earlier []Node
+    for id := range created.candidates(node.Concepts) {
+      earlier = append(earlier, Node{ID: id, Concepts: concepts[id]})
+    }
+    sort.Slice(earlier, func(i, j int) bool { return earlier[i].ID > earlier[j].ID })
+    createdEdges, createdVertices := relateNodes(node, earlier)
+    edges = append(edges, createdEdges...)
+    related = append(related, createdVertices...)
+
+    for _, edge := range edges {
+      edge.SourceID = node.ID
+      addEdge(edge)
+    }
+    for _, vertex := range related {
+      vertex.NodeID = node.ID
+      addVertex(vertex)
+    }
+    created.add(node)
+  }
+
+  return plan
+}
+
+// skip counts an incoming edge of the kind as skipped.
+func (p *importPlan) skip(kind string) {
+  if kind == KindConcept {
+    p.report.VerticesSkipped++
+  } else {
+    p.report.EdgesSkipped++
+  }
+}
+
+// matchNode returns the existing node with the same concepts, the oldest if several have them.
+func (g *Graph) matchNode(concepts []string) (Node, bool) {
+  var match Node
+  found := false
+  for id := range g.concepts.candidates(concepts) {
+    node := g.Nodes[g.positions[id]]
+    if calculateWeight(concepts, node.Concepts) == 1 && (!found || node.ID < match.ID) {
+      match, found = node, true
+    }
+  }
+  return match, found
+}
+
+// applyImport stores the planned changes in a single transaction, then adds them to the graph.
+// It must be called with the write lock held.
+func (g *Graph) applyImport(plan *importPlan) error {
+  ids := make([]int64, len(plan.nodes))
+  resolve := func(ref int64) int64 {
+    if ref < 0 {
+      return ids[-ref-1]
+    }
+    return ref
+  }
+
+  err := sqlite.WithTx(func(tx *sqlite.Tx) error {
+    for i, node := range plan.nodes {
+      nodeID, err := tx.InsertNode(g.UserID, node.Text)
+      if err != nil {
+        return fmt.Errorf("failed to insert node: %v", err)
+      }
+      if err := tx.SetNodeConcepts(g.UserID, nodeID, node.Concepts); err != nil {
+        return fmt.Errorf("failed to insert node concepts: %v", err)
+      }
+      ids[i] = nodeID
+    }
+
+    for i := range plan.edges {
+      plan.edges[i].SourceID = resolve(plan.edges[i].SourceID)
+      plan.edges[i].TargetID = resolve(plan.edges[i].TargetID)
+      if _, err := tx.InsertEdge(plan.edges[i].SourceID, plan.edges[i].TargetID, plan.edges[i].Weight); err != nil {
+        return fmt.Errorf("failed to insert edge: %v", err)
+      }
+    }
+
+    for i := range plan.vertices {
+      plan.vertices[i].NodeID = resolve(plan.vertices[i].NodeID)
+      plan.vertices[i].TargetID = resolve(plan.vertices[i].TargetID)
+      vertexID, err := tx.InsertVertex(plan.vertices[i].NodeID, plan.vertices[i].TargetID, plan.vertices[i].Concept)
+      if err != nil {
+        return fmt.Errorf("failed to insert vertex: %v", err)
+      }
+      plan.vertices[i].ID = vertexID
+    }
+
+    return nil
+  })
+  if err != nil {
+    return err
+  }
+
+  for i, node := range plan.nodes {
+    node.ID = ids[i]
+    g.addNode(node, nil, nil)
+  }
+  g.addLinks(plan.edges, plan.vertices)
+
+  created := 0
+  for i := range plan.report.Nodes {
+    if !plan.report.Nodes[i].Merged {
+      plan.report.Nodes[i].NodeID = ids[created]
+      created++
+    }
+  }
+
+  return nil
+}
+
+// parseJGF reads a single graph in JSON Graph Format, version 2 with nodes keyed by ID or version 1
+// with a list of nodes. Node text and concepts are read from their metadata, as exported by this
+// application, falling back to the label for the text.
+func parseJGF(r io.Reader) (*ImportFile, error) {
+  type jgfInputNode struct {
+    ID       string                 `json:"id"`
+    Label    string                 `json:"label"`
+    Metadata map[string]interface{} `json:"metadata"`
+  }
+  type jgfInputGraph struct {
+    Nodes json.RawMessage `json:"nodes"`
+    Edges []struct {
+      ID       string                 `json:"id"`
+      Source   string                 `json:"source"`
+      Target   string                 `json:"target"`
+      Relation string                 `json:"relation"`
+      Label    string                 `json:"label"`
+      Metadata map[string]interface{} `json:"metadata"`
+    } `json:"edges"`
+  }
+  var document struct {
+    Graph  *jgfInputGraph  `json:"graph"`
+    Graphs []jgfInputGraph `json:"graphs"`
+  }
+  if err := json.NewDecoder(r).Decode(&document); err != nil {
+    return nil, fmt.Errorf("failed to decode JSON Graph Format: %v", err)
+  }
+
+  input := document.Graph
+  if input == nil {
+    if len(document.Graphs) != 1 {
+      return nil, fmt.Errorf("expected a single graph, found %d", len(document.Graphs))
+    }
+    input = &document.Graphs[0]
+  }
+
+  var nodes []jgfInputNode
+  switch trimmed := bytes.TrimSpace(input.Nodes); {
+  case len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")):
+  case trimmed[0] == '[':
+    if err := json.Unmarshal(trimmed, &nodes); err != nil {
+      return nil, fmt.Errorf("failed to decode nodes: %v", err)
+    }
+  default:
+    var keyed map[string]jgfInputNode
+    if err := json.Unmarshal(trimmed, &keyed); err != nil {
+      return nil, fmt.Errorf("failed to decode nodes: %v", err)
+    }
+    for id, node := range keyed {
+      node.ID = id
+      nodes = append(nodes, node)
+    }
+    // JSON objects are unordered, so create the nodes in the order of their IDs, shorter first so that n2 comes before n10
+    sort.Slice(nodes, func(i, j int) bool {
+      if len(nodes[i].ID) != len(nodes[j].ID) {
+        return len(nodes[i].ID) < len(nodes[j].ID)
+      }
+      return nodes[i].ID < nodes[j].ID
+    })
+  }
+
+  incoming := &ImportFile{Format: FormatJGF}
+  for _, node := range nodes {
+    text := metadataString(node.Metadata, "text")
+    if text == "" {
+      text = node.Label
+    }
+    incoming.nodes = append(incoming.nodes, importedNode{
+      key:      node.ID,
+      text:     strings.TrimSpace(text),
+      concepts: parseConcepts(node.Metadata["concepts"]),
+    })
+  }
+
+  for i, edge := range input.Edges {
+    key := edge.ID
+    if key == "" {
+      key = "#" + strconv.Itoa(i+1)
+    }
+    in := importedEdge{key: key, source: edge.Source, target: edge.Target, kind: KindSimilarity}
+    in.concept = metadataString(edge.Metadata, "concept")
+    if edge.Relation == KindConcept {
+      in.kind = KindConcept
+      if in.concept == "" {
+        in.concept = edge.Label
+      }
+    }
+    in.concept = tagging.NormalizeName(in.concept)
+    switch weight := edge.Metadata["weight"].(type) {
+    case nil:
+    case float64:
+      in.weight, in.hasWeight, in.weightErr = checkImportedWeight(weight)
+    case string:
+      in.weight, in.hasWeight, in.weightErr = parseImportedWeight(weight)
+    default:
+      in.weightErr = true
+    }
+    if in.kind == KindSimilarity && in.concept != "" && !in.hasWeight && !in.weightErr {
+      in.kind = KindConcept
+    }
+    incoming.edges = append(incoming.edges, in)
+  }
+
+  return incoming, nil
+}
+
+// metadataString returns a string value of JSON Graph Format metadata.
+func metadataString(metadata map[string]interface{}, key string) string {
+  value, _ := metadata[key].(string)
+  return strings.TrimSpace(value)
+}
+
+// parseGraphML reads the first graph of a GraphML file. Node and edge attributes are matched by the
+// names of their keys, as exported by this application: text (or label) and concepts for nodes, and
+// kind, weight and concept for edges.
+func parseGraphML(r io.Reader) (*ImportFile, error) {
+  var document struct {
+    XMLName xml.Name `xml:"graphml"`
+    Keys    []struct {
+      ID      string `xml:"id,attr"`
+      For     string `xml:"for,attr"`
+      Name    string `xml:"attr.name,attr"`
+      Default string `xml:"default"`
+    } `xml:"key"`
+    Graphs []graphMLGraph `xml:"graph"`
+  }
+  if err := xml.NewDecoder(r).Decode(&document); err != nil {
+    return nil, fmt.Errorf("failed to decode GraphML: %v", err)
+  }
+  if len(document.Graphs) == 0 {
+    return nil, fmt.Errorf("no graph found in GraphML")
+  }
+
+  // Map key IDs to attribute names per element, with their default values
+  names := map[string]map[string]string{"node": {}, "edge": {}}
+  defaults := map[string]map[string]string{"node": {}, "edge": {}}
+  for _, key := range document.Keys {
+    name := key.Name
+    if name == "" {
+      name = key.ID
+    }
+    for element := range names {
+      if key.For == element || key.For == "all" {
+        names[element][key.ID] = name
+        if key.Default != "" {
+          defaults[element][name] = strings.TrimSpace(key.Default)
+        }
+      }
+    }
+  }
+  attributes := func(element string, data []graphMLData) map[string]string {
+    values := make(map[string]string)
+    for name, value := range defaults[element] {
+      values[name] = value
+    }
+    for _, d := range data {
+      if name, ok := names[element][d.Key]; ok {
+        values[name] = strings.TrimSpace(d.Value)
+      }
+    }
+    return values
+  }
+
+  incoming := &ImportFile{Format: FormatGraphML}
+  graph := document.Graphs[0]
+  for _, node := range graph.Nodes {
+    values := attributes("node", node.Data)
+    text := values["text"]
+    if text == "" {
+      text = values["label"]
+    }
+    incoming.nodes = append(incoming.nodes, importedNode{
+      key:      node.ID,
+      text:     text,
+      concepts: parseConcepts(values["concepts"]),
+    })
+  }
+
+  for i, edge := range graph.Edges {
+    key := edge.ID
+    if key == "" {
+      key = "#" + strconv.Itoa(i+1)
+    }
+    values := attributes("edge", edge.Data)
+    in := importedEdge{key: key, source: edge.Source, target: edge.Target, kind: KindSimilarity, concept: tagging.NormalizeName(values["concept"])}
+    if value, ok := values["weight"]; ok {
+      in.weight, in.hasWeight, in.weightErr = parseImportedWeight(value)
+    }
+    if values["kind"] == KindConcept || (values["kind"] == "" && in.concept != "" && !in.hasWeight && !in.weightErr) {
+      in.kind = KindConcept
+    }
+    incoming.edges = append(incoming.edges, in)
+  }
+
+  return incoming, nil
+}
+
+// parseImportedWeight parses an edge weight between 0 and 1, reporting whether it was present and valid.
+func parseImportedWeight(value string) (weight float64, ok bool, invalid bool) {
+  value = strings.TrimSpace(value)
+  if value == "" {
+    return 0, false, false
+  }
+  weight, err := strconv.ParseFloat(value, 64)
+  if err != nil {
+    return 0, false, true
+  }
+  return checkImportedWeight(weight)
+}
+
+// checkImportedWeight accepts an edge weight between 0 and 1, reporting it as invalid otherwise.
+func checkImportedWeight(weight float64) (float64, bool, bool) {
+  if math.IsNaN(weight) || weight < 0 || weight > 1 {
+    return 0, false, true
+  }
+  return weight, true, false
+}
+
+// parseConcepts reads concepts given as a list, or as a string separated by semicolons or commas,
+// normalizing them like generated tags and dropping blanks and duplicates.
+func parseConcepts(value interface{}) []string {
+  var raw []string
+  switch v := value.(type) {
+  case []interface{}:
+    for _, item := range v {
+      if s, ok := item.(string); ok {
+        raw = append(raw, s)
+      }
+    }
+  case string:
+    separator := ";"
+    if !strings.Contains(v, separator) {
+      separator = ","
+    }
+    raw = strings.Split(strings.Trim(strings.TrimSpace(v), "[]"), separator)
+  }
+
+  var concepts []string
+  for _, concept := range raw {
+    concept = tagging.NormalizeName(concept)
+    if concept != "" && !contains(concepts, concept) {
+      concepts = append(concepts, concept)
+    }
+  }
+  return concepts
+}
@@ -0,0 +1,160 @@
+package graph
+
+import (
+  "path/filepath"
+  "reflect"
+  "strings"
+  "testing"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+)
+
+const testJGF = `{
+  "graph": {
+    "nodes": {
+      "n1": {"label": "Budget review", "metadata": {"concepts": ["Budgets", "- Finance"]}},
+      "n2": {"metadata": {"text": "Trip plan", "concepts": "Travel; budget"}},
+      "n3": {"metadata": {"concepts": ["travel"]}}
+    },
+    "edges": [
+      {"source": "n1", "target": "n2", "metadata": {"weight": 1.5}},
+      {"source": "n1", "target": "n3"},
+      {"id": "c1", "source": "n1", "target": "n2", "relation": "concept", "label": "Budgets"}
+    ]
+  }
+}`
+
+const testGraphML = `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="d0" for="node" attr.name="text" attr.type="string"/>
+  <key id="d1" for="node" attr.name="concepts" attr.type="string"/>
+  <key id="d2" for="edge" attr.name="weight" attr.type="double"/>
+  <graph edgedefault="undirected">
+    <node id="a"><data key="d0">Budget review</data><data key="d1">budget;finance</data></node>
+    <node id="b"><data key="d0">Trip plan</data><data key="d1">travel;budget</data></node>
+    <edge source="a" target="b"><data key="d2">2</data></edge>
+    <edge id="e2" source="a" target="b"><data key="d2">0.4</data></edge>
+  </graph>
+</graphml>`
+
+// initTestDB opens a fresh database and returns the ID of a new user.
+func initTestDB(t *testing.T) int64 {
+  t.Helper()
+  if err := sqlite.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+    t.Fatal(err)
+  }
+  userID, err := sqlite.InsertUser("Ana", "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  return userID
+}
+
+func readImport(t *testing.T, content string) *ImportFile {
+  t.Helper()
+  file, err := ReadImportFile(strings.NewReader(content), "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  return file
+}
+
+// conflictKinds returns the kind and key of each conflict, and fails on messages naming an edge twice.
+func conflictKinds(t *testing.T, report ImportReport) []string {
+  t.Helper()
+  var kinds []string
+  for _, conflict := range report.Conflicts {
+    if strings.Contains(conflict.Message, "edge edge") {
+      t.Errorf("conflict message %q repeats the word edge", conflict.Message)
+    }
+    kinds = append(kinds, conflict.Kind+" "+conflict.Key)
+  }
+  return kinds
+}
+
+func TestReadImportFileNormalizesConcepts(t *testing.T) {
+  file := readImport(t, testJGF)
+  if file.Format != FormatJGF {
+    t.Fatalf("format = %q, want %q", file.Format, FormatJGF)
+  }
+  want := [][]string{{"budget", "finance"}, {"travel", "budget"}, {"travel"}}
+  for i, node := range file.nodes {
+    if !reflect.DeepEqual(node.concepts, want[i]) {
+      t.Errorf("node %s concepts = %q, want %q", node.key, node.concepts, want[i])
+    }
+  }
+  if concept := file.edges[2].concept; concept != "budget" {
+    t.Errorf("concept edge concept = %q, want budget", concept)
+  }
+}
+
+func TestImportWeightOutOfRange(t *testing.T) {
+  for _, content := range []string{testJGF, testGraphML} {
+    file := readImport(t, content)
+    edge := file.edges[0]
+    if edge.key != "#1" || edge.hasWeight || !edge.weightErr {
+      t.Errorf("%s: first edge = %+v, want key #1 with an invalid weight", file.Format, edge)
+    }
+  }
+
+  file := readImport(t, testGraphML)
+  if edge := file.edges[1]; edge.key != "e2" || !edge.hasWeight || edge.weight != 0.4 {
+    t.Errorf("graphml: second edge = %+v, want key e2 with weight 0.4", edge)
+  }
+}
+
+func TestImport(t *testing.T) {
+  userID := initTestDB(t)
+  graph, err := LoadGraph(userID)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  report, err := Import(graph, readImport(t, testJGF), true)
+  if err != nil {
+    t.Fatal(err)
+  }
+  wantConflicts := []string{"invalid_node n3", "invalid_edge #1", "missing_node #2"}
+  if kinds := conflictKinds(t, report); !reflect.DeepEqual(kinds, wantConflicts) {
+    t.Errorf("dry run conflicts = %q, want %q", kinds, wantConflicts)
+  }
+  if report.NodesCreated != 2 || len(graph.Nodes) != 0 {
+    t.Fatalf("dry run created %d nodes and left %d in the graph, want 2 and none", report.NodesCreated, len(graph.Nodes))
+  }
+
+  report, err = Import(graph, readImport(t, testJGF), false)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if report.NodesCreated != 2 || report.EdgesCreated != 1 || report.VerticesCreated != 1 {
+    t.Fatalf("import created %d nodes, %d edges and %d vertices, want 2, 1 and 1",
+      report.NodesCreated, report.EdgesCreated, report.VerticesCreated)
+  }
+  // The invalid weight is computed from the concepts of the nodes instead
+  if weight, want := graph.Edges[0].Weight, calculateWeight([]string{"budget", "finance"}, []string{"travel", "budget"}); weight != want {
+    t.Errorf("edge weight = %v, want %v", weight, want)
+  }
+
+  stored, err := LoadGraph(userID)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(stored.Nodes) != 2 || len(stored.Edges) != 1 || len(stored.Vertices) != 1 {
+    t.Fatalf("stored graph has %d nodes, %d edges and %d vertices, want 2, 1 and 1",
+      len(stored.Nodes), len(stored.Edges), len(stored.Vertices))
+  }
+
+  // Importing the GraphML version of the same notes merges into the imported nodes
+  report, err = Import(graph, readImport(t, testGraphML), false)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if report.NodesMerged != 2 || report.NodesCreated != 0 || report.EdgesCreated != 0 {
+    t.Errorf("second import merged %d nodes and created %d nodes and %d edges, want 2, 0 and 0",
+      report.NodesMerged, report.NodesCreated, report.EdgesCreated)
+  }
+  wantConflicts = []string{"invalid_edge #1", "weight e2"}
+  if kinds := conflictKinds(t, report); !reflect.DeepEqual(kinds, wantConflicts) {
+    t.Errorf("second import conflicts = %q, want %q", kinds, wantConflicts)
+  }
+}
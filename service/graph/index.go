@@ -0,0 +1,250 @@
+package graph
+
+import (
+  "sort"
+)
+
+// conceptIndex maps each concept to the IDs of the nodes having it, so a new node only needs to be
+// compared with the nodes it shares at least one concept with instead of the whole graph.
+type conceptIndex map[string]map[int64]struct{}
+
+// add indexes the node under each of its concepts.
+func (idx conceptIndex) add(node Node) {
+  for _, concept := range node.Concepts {
+    ids, ok := idx[concept]
+    if !ok {
+      ids = make(map[int64]struct{})
+      idx[concept] = ids
+    }
+    ids[node.ID] = struct{}{}
+  }
+}
+
+// remove drops the node from the index, forgetting concepts no other node has.
+func (idx conceptIndex) remove(node Node) {
+  for _, concept := range node.Concepts {
+    ids := idx[concept]
+    delete(ids, node.ID)
+    if len(ids) == 0 {
+      delete(idx, concept)
+    }
+  }
+}
+
+// candidates returns the IDs of the nodes having at least one of the concepts.
+func (idx conceptIndex) candidates(concepts []string) map[int64]struct{} {
+  found := make(map[int64]struct{})
+  for _, concept := range concepts {
+    for id := range idx[concept] {
+      found[id] = struct{}{}
+    }
+  }
+  return found
+}
+
+// link is what connects two nodes: the weight of the strongest edge between them, if there is one,
+// and the concepts of the vertices between them.
+type link struct {
+  edge     bool
+  weight   float64
+  concepts []string
+}
+
+// linkIndex maps each node to the nodes it shares an edge or vertex with, so a query only visits
+// the links of the nodes it reaches instead of every edge of the graph. Both ends of a link share it.
+type linkIndex map[int64]map[int64]*link
+
+// newLinkIndex indexes the edges and vertices.
+func newLinkIndex(edges []Edge, vertices []Vertex) linkIndex {
+  idx := make(linkIndex)
+  for _, edge := range edges {
+    idx.addEdge(edge)
+  }
+  for _, vertex := range vertices {
+    idx.addVertex(vertex)
+  }
+  return idx
+}
+
+// get returns the link between two nodes, creating it if they are not linked yet.
+func (idx linkIndex) get(a, b int64) *link {
+  l, ok := idx[a][b]
+  if ok {
+    return l
+  }
+
+  l = &link{}
+  for _, pair := range [][2]int64{{a, b}, {b, a}} {
+    links, ok := idx[pair[0]]
+    if !ok {
+      links = make(map[int64]*link)
+      idx[pair[0]] = links
+    }
+    links[pair[1]] = l
+  }
+  return l
+}
+
+// addEdge links the nodes of the edge, keeping the strongest edge when they are connected more than once.
+// Edges from a node to itself are left out.
+func (idx linkIndex) addEdge(edge Edge) {
+  if edge.SourceID == edge.TargetID {
+    return
+  }
+  l := idx.get(edge.SourceID, edge.TargetID)
+  if !l.edge || edge.Weight > l.weight {
+    l.edge, l.weight = true, edge.Weight
+  }
+}
+
+// addVertex adds the concept of the vertex to the concepts its nodes share.
+func (idx linkIndex) addVertex(vertex Vertex) {
+  l := idx.get(vertex.NodeID, vertex.TargetID)
+  if !contains(l.concepts, vertex.Concept) {
+    l.concepts = append(l.concepts, vertex.Concept)
+  }
+}
+
+// remove drops every link of the node.
+func (idx linkIndex) remove(id int64) {
+  for other := range idx[id] {
+    delete(idx[other], id)
+    if len(idx[other]) == 0 {
+      delete(idx, other)
+    }
+  }
+  delete(idx, id)
+}
+
+// ensureIndex builds the concept index, node positions and link index of the graph if they are
+// missing, such as after the graph was loaded or its nodes were replaced. Like the other methods
+// below that change the graph, it must be called with the write lock held.
+func (g *Graph) ensureIndex() {
+  if g.concepts != nil && g.links != nil && len(g.positions) == len(g.Nodes) {
+    return
+  }
+
+  g.concepts = make(conceptIndex)
+  g.positions = make(map[int64]int, len(g.Nodes))
+  for i, node := range g.Nodes {
+    g.concepts.add(node)
+    g.positions[node.ID] = i
+  }
+  g.links = newLinkIndex(g.Edges, g.Vertices)
+}
+
+// linkIndex returns the link index of the graph. Queries hold only the read lock, so when the graph
+// has not been indexed yet they get a temporary index built from every edge and vertex.
+func (g *Graph) linkIndex() linkIndex {
+  if g.links != nil {
+    return g.links
+  }
+  return newLinkIndex(g.Edges, g.Vertices)
+}
+
+// relate computes the edges and vertices linking a new node to the existing nodes sharing a concept with it.
+// Their source is left unset until the node has an ID. Edges follow the order of the graph's nodes.
+func (g *Graph) relate(node Node) ([]Edge, []Vertex) {
+  g.ensureIndex()
+
+  candidates := g.concepts.candidates(node.Concepts)
+  positions := make([]int, 0, len(candidates))
+  for id := range candidates {
+    positions = append(positions, g.positions[id])
+  }
+  sort.Ints(positions)
+
+  existing := make([]Node, 0, len(positions))
+  for _, position := range positions {
+    existing = append(existing, g.Nodes[position])
+  }
+  return relateNodes(node, existing)
+}
+
+// relateNodes computes the edges and vertices linking a new node to each of the given nodes it shares a concept with.
+func relateNodes(node Node, existing []Node) ([]Edge, []Vertex) {
+  var edges []Edge
+  var vertices []Vertex
+  for _, existingNode := range existing {
+    // Calculate edge weight based on concept similarity
+    weight := calculateWeight(node.Concepts, existingNode.Concepts)
+    if weight > 0 {
+      // Create an edge between the nodes
+      edge := Edge{
+        TargetID: existingNode.ID,
+        Weight:   weight,
+      }
+      edges = append(edges, edge)
+
+      // Create vertices for the concepts shared by the nodes
+      for _, concept := range node.Concepts {
+        if contains(existingNode.Concepts, concept) {
+          vertex := Vertex{
+            TargetID: existingNode.ID,
+            Concept:  concept,
+          }
+          vertices = append(vertices, vertex)
+        }
+      }
+    }
+  }
+  return edges, vertices
+}
+
+// addNode appends a stored node with its edges and vertices to the graph and indexes it.
+func (g *Graph) addNode(node Node, edges []Edge, vertices []Vertex) {
+  g.ensureIndex()
+
+  g.positions[node.ID] = len(g.Nodes)
+  g.concepts.add(node)
+  g.Nodes = append(g.Nodes, node)
+  g.addLinks(edges, vertices)
+}
+
+// addLinks appends stored edges and vertices between nodes of the graph and indexes them.
+func (g *Graph) addLinks(edges []Edge, vertices []Vertex) {
+  g.ensureIndex()
+
+  for _, edge := range edges {
+    g.links.addEdge(edge)
+  }
+  for _, vertex := range vertices {
+    g.links.addVertex(vertex)
+  }
+  g.Edges = append(g.Edges, edges...)
+  g.Vertices = append(g.Vertices, vertices...)
+}
+
+// removeNode drops a deleted node and every edge and vertex touching it from the graph and its index.
+func (g *Graph) removeNode(id int64) {
+  g.ensureIndex()
+
+  position, ok := g.positions[id]
+  if !ok {
+    return
+  }
+  g.concepts.remove(g.Nodes[position])
+  g.links.remove(id)
+  delete(g.positions, id)
+
+  g.Nodes = append(g.Nodes[:position], g.Nodes[position+1:]...)
+  for i := position; i < len(g.Nodes); i++ {
+    g.positions[g.Nodes[i].ID] = i
+  }
+
+  edges := g.Edges[:0]
+  for _, edge := range g.Edges {
+    if edge.SourceID != id && edge.TargetID != id {
+      edges = append(edges, edge)
+    }
+  }
+  g.Edges = edges
+
+  vertices := g.Vertices[:0]
+  for _, vertex := range g.Vertices {
+    if vertex.NodeID != id && vertex.TargetID != id {
+      vertices = append(vertices, vertex)
+    }
+  }
+  g.Vertices = vertices
+}
@@ -0,0 +1,169 @@
+package graph
+
+import (
+  "fmt"
+  "math/rand"
+  "reflect"
+  "strconv"
+  "testing"
+)
+
+// syntheticGraph builds an in-memory graph whose nodes each have perNode of the given number of
+// concepts. A skew above 1 picks concepts from a Zipf distribution, as with real tags where a few
+// are very common, and zero picks them uniformly. It also returns a function making more such nodes.
+func syntheticGraph(nodes, concepts, perNode int, skew float64, seed int64) (*Graph, func(id int64) Node) {
+  random := rand.New(rand.NewSource(seed))
+  pick := func() int { return random.Intn(concepts) }
+  if skew != 0 {
+    zipf := rand.NewZipf(random, skew, 1, uint64(concepts-1))
+    pick = func() int { return int(zipf.Uint64()) }
+  }
+  newNode := func(id int64) Node {
+    node := Node{ID: id, RecordingID: id, Text: "note " + strconv.FormatInt(id, 10)}
+    for len(node.Concepts) < perNode {
+      concept := "concept-" + strconv.Itoa(pick())
+      if !contains(node.Concepts, concept) {
+        node.Concepts = append(node.Concepts, concept)
+      }
+    }
+    return node
+  }
+
+  graph := &Graph{Nodes: make([]Node, 0, nodes+1)}
+  for i := 1; i <= nodes; i++ {
+    graph.Nodes = append(graph.Nodes, newNode(int64(i)))
+  }
+  return graph, newNode
+}
+
+func TestRelateMatchesScan(t *testing.T) {
+  for _, skew := range []float64{0, 1.2} {
+    graph, newNode := syntheticGraph(2000, 200, 4, skew, 1)
+    for id := int64(2001); id <= 2100; id++ {
+      node := newNode(id)
+      scanEdges, scanVertices := relateNodes(node, graph.Nodes)
+      edges, vertices := graph.relate(node)
+      if !reflect.DeepEqual(edges, scanEdges) || !reflect.DeepEqual(vertices, scanVertices) {
+        t.Fatalf("skew %v: relating node %d through the index differs from scanning every node", skew, id)
+      }
+    }
+  }
+}
+
+func TestRemoveNodeKeepsIndexInSync(t *testing.T) {
+  graph, _ := syntheticGraph(500, 50, 3, 0, 2)
+  graph.ensureIndex()
+  random := rand.New(rand.NewSource(3))
+  for i := 0; i < 100; i++ {
+    node := graph.Nodes[random.Intn(len(graph.Nodes))]
+    graph.removeNode(node.ID)
+    if i%2 == 0 {
+      edges, vertices := graph.relate(node)
+      for j := range edges {
+        edges[j].SourceID = node.ID
+      }
+      for j := range vertices {
+        vertices[j].NodeID = node.ID
+      }
+      graph.addNode(node, edges, vertices)
+    }
+  }
+
+  rebuilt := &Graph{Nodes: graph.Nodes, Edges: graph.Edges, Vertices: graph.Vertices}
+  rebuilt.ensureIndex()
+  if !reflect.DeepEqual(graph.positions, rebuilt.positions) || !reflect.DeepEqual(graph.concepts, rebuilt.concepts) {
+    t.Fatal("index after removing and adding nodes differs from one built from scratch")
+  }
+  if len(graph.links) == 0 || !reflect.DeepEqual(graph.links, rebuilt.links) {
+    t.Fatal("links after removing and adding nodes differ from ones built from scratch")
+  }
+}
+
+// benchmarkSizes are the numbers of nodes of the graphs the benchmarks run against, which have
+// 5000 concepts and 5 concepts per node.
+var benchmarkSizes = []int{1000, 10000, 100000}
+
+// BenchmarkRelateBuildIndex builds the concept index of a whole graph, as after it is loaded.
+func BenchmarkRelateBuildIndex(b *testing.B) {
+  for _, size := range benchmarkSizes {
+    b.Run(fmt.Sprintf("nodes=%d", size), func(b *testing.B) {
+      graph, _ := syntheticGraph(size, 5000, 5, 0, 1)
+      b.ResetTimer()
+      for i := 0; i < b.N; i++ {
+        graph.concepts = nil
+        graph.ensureIndex()
+      }
+    })
+  }
+}
+
+// BenchmarkRelateScan relates new nodes by comparing them with every node of the graph.
+func BenchmarkRelateScan(b *testing.B) {
+  for _, size := range benchmarkSizes {
+    b.Run(fmt.Sprintf("nodes=%d", size), func(b *testing.B) {
+      graph, newNode := syntheticGraph(size, 5000, 5, 0, 1)
+      node := newNode(int64(size + 1))
+      b.ResetTimer()
+      for i := 0; i < b.N; i++ {
+        relateNodes(node, graph.Nodes)
+      }
+    })
+  }
+}
+
+// BenchmarkRelateIndex relates new nodes only with the nodes sharing a concept, found through the index.
+func BenchmarkRelateIndex(b *testing.B) {
+  for _, size := range benchmarkSizes {
+    b.Run(fmt.Sprintf("nodes=%d", size), func(b *testing.B) {
+      graph, newNode := syntheticGraph(size, 5000, 5, 0, 1)
+      graph.ensureIndex()
+      node := newNode(int64(size + 1))
+      b.ResetTimer()
+      for i := 0; i < b.N; i++ {
+        graph.relate(node)
+      }
+    })
+  }
+}
+
+// BenchmarkRelateInsert adds a node to the graph and its index.
+func BenchmarkRelateInsert(b *testing.B) {
+  for _, size := range benchmarkSizes {
+    b.Run(fmt.Sprintf("nodes=%d", size), func(b *testing.B) {
+      graph, newNode := syntheticGraph(size, 5000, 5, 0, 1)
+      graph.ensureIndex()
+      node := newNode(int64(size + 1))
+      b.ResetTimer()
+      for i := 0; i < b.N; i++ {
+        graph.addNode(node, nil, nil)
+
+        b.StopTimer()
+        graph.removeNode(node.ID)
+        b.StartTimer()
+      }
+    })
+  }
+}
+
+// BenchmarkRelateDelete deletes random nodes from the graph and its index.
+func BenchmarkRelateDelete(b *testing.B) {
+  for _, size := range benchmarkSizes {
+    b.Run(fmt.Sprintf("nodes=%d", size), func(b *testing.B) {
+      graph, _ := syntheticGraph(size, 5000, 5, 0, 1)
+      graph.ensureIndex()
+      random := rand.New(rand.NewSource(1))
+      b.ResetTimer()
+      for i := 0; i < b.N; i++ {
+        b.StopTimer()
+        node := graph.Nodes[random.Intn(len(graph.Nodes))]
+        b.StartTimer()
+
+        graph.removeNode(node.ID)
+
+        b.StopTimer()
+        graph.addNode(node, nil, nil)
+        b.StartTimer()
+      }
+    })
+  }
+}
@@ -0,0 +1,275 @@
+package graph
+
+import (
+  "container/heap"
+  "errors"
+  "math"
+  "sort"
+  "strings"
+)
+
+// Path modes.
+const (
+  // PathShortest finds the path with the fewest edges, preferring the strongest among equally short ones.
+  PathShortest = "shortest"
+
+  // PathStrongest finds the path whose edge weights have the highest product, however long it is.
+  PathStrongest = "strongest"
+)
+
+// ErrNodeNotFound is returned when a node is not part of the graph.
+var ErrNodeNotFound = errors.New("node not found")
+
+// Neighbor is a node connected to another by an edge, with the concepts the two share.
+type Neighbor struct {
+  Node     Node
+  Weight   float64
+  Concepts []string
+}
+
+// Link is an edge followed by a path, with the concepts its two nodes share.
+type Link struct {
+  SourceID int64
+  TargetID int64
+  Weight   float64
+  Concepts []string
+}
+
+// Path connects two nodes. Strength is the product of the weights of its links.
+type Path struct {
+  Nodes    []Node
+  Links    []Link
+  Strength float64
+}
+
+// Node returns the node with the ID.
+func (g *Graph) Node(id int64) (Node, bool) {
+  g.mu.RLock()
+  defer g.mu.RUnlock()
+
+  return g.node(id)
+}
+
+// node returns the node with the ID, through the index when it has been built.
+func (g *Graph) node(id int64) (Node, bool) {
+  if g.positions == nil {
+    for _, node := range g.Nodes {
+      if node.ID == id {
+        return node, true
+      }
+    }
+    return Node{}, false
+  }
+
+  position, ok := g.positions[id]
+  if !ok {
+    return Node{}, false
+  }
+  return g.Nodes[position], true
+}
+
+// Neighbors returns the nodes connected to the node by an edge in either direction,
+// strongest first. It only visits the links of the node.
+func (g *Graph) Neighbors(id int64) ([]Neighbor, error) {
+  g.mu.RLock()
+  defer g.mu.RUnlock()
+
+  if _, ok := g.node(id); !ok {
+    return nil, ErrNodeNotFound
+  }
+
+  var neighbors []Neighbor
+  for otherID, l := range g.linkIndex()[id] {
+    node, ok := g.node(otherID)
+    if !l.edge || !ok {
+      continue
+    }
+    neighbors = append(neighbors, Neighbor{
+      Node:     node,
+      Weight:   l.weight,
+      Concepts: append([]string(nil), l.concepts...),
+    })
+  }
+
+  sort.Slice(neighbors, func(i, j int) bool {
+    if neighbors[i].Weight != neighbors[j].Weight {
+      return neighbors[i].Weight > neighbors[j].Weight
+    }
+    return neighbors[i].Node.ID < neighbors[j].Node.ID
+  })
+  return neighbors, nil
+}
+
+// NodesByConcept returns the nodes sharing the concept with at least one other node, as recorded
+// by the vertices of the graph, ordered by ID. Concepts are compared case-insensitively.
+func (g *Graph) NodesByConcept(concept string) []Node {
+  g.mu.RLock()
+  defer g.mu.RUnlock()
+
+  ids := make(map[int64]bool)
+  for _, vertex := range g.Vertices {
+    if strings.EqualFold(vertex.Concept, concept) {
+      ids[vertex.NodeID] = true
+      ids[vertex.TargetID] = true
+    }
+  }
+
+  var nodes []Node
+  for _, node := range g.Nodes {
+    if ids[node.ID] {
+      nodes = append(nodes, node)
+    }
+  }
+  sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+  return nodes
+}
+
+// FindPath finds a path between two nodes in the given mode, treating edges as undirected and
+// skipping edges without weight. Ties are broken by node ID, so the same graph always gives the same
+// path. It returns false when the nodes are not connected. It visits
+// the links of the nodes closer to fromID than toID, and in the worst case the whole graph.
+func (g *Graph) FindPath(fromID, toID int64, mode string) (Path, bool, error) {
+  g.mu.RLock()
+  defer g.mu.RUnlock()
+
+  if _, ok := g.node(fromID); !ok {
+    return Path{}, false, ErrNodeNotFound
+  }
+  if _, ok := g.node(toID); !ok {
+    return Path{}, false, ErrNodeNotFound
+  }
+  if mode != PathShortest && mode != PathStrongest {
+    return Path{}, false, errors.New("path mode must be " + PathShortest + " or " + PathStrongest)
+  }
+
+  // Dijkstra over the cost of a path: its number of links and the negative log of its strength,
+  // so maximizing the product of weights becomes minimizing a sum of non-negative costs
+  links := g.linkIndex()
+  best := map[int64]pathCost{fromID: {}}
+  previous := make(map[int64]int64)
+  done := make(map[int64]bool)
+  queue := &pathQueue{mode: mode}
+  heap.Push(queue, pathItem{id: fromID})
+
+  for queue.Len() > 0 {
+    item := heap.Pop(queue).(pathItem)
+    if done[item.id] {
+      continue
+    }
+    done[item.id] = true
+    if item.id == toID {
+      break
+    }
+
+    otherIDs := make([]int64, 0, len(links[item.id]))
+    for otherID := range links[item.id] {
+      otherIDs = append(otherIDs, otherID)
+    }
+    sort.Slice(otherIDs, func(i, j int) bool { return otherIDs[i] < otherIDs[j] })
+    for _, otherID := range otherIDs {
+      l := links[item.id][otherID]
+      if done[otherID] || !l.edge || l.weight <= 0 {
+        continue
+      }
+      if _, ok := g.node(otherID); !ok {
+        continue
+      }
+      cost := pathCost{hops: item.cost.hops + 1, logCost: item.cost.logCost - math.Log(l.weight)}
+      if current, ok := best[otherID]; ok && !cost.less(current, mode) {
+        continue
+      }
+      best[otherID] = cost
+      previous[otherID] = item.id
+      heap.Push(queue, pathItem{id: otherID, cost: cost})
+    }
+  }
+
+  if !done[toID] {
+    return Path{}, false, nil
+  }
+
+  ids := []int64{toID}
+  for id := toID; id != fromID; {
+    id = previous[id]
+    ids = append(ids, id)
+  }
+  for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+    ids[i], ids[j] = ids[j], ids[i]
+  }
+
+  path := Path{Strength: 1}
+  for i, id := range ids {
+    node, _ := g.node(id)
+    path.Nodes = append(path.Nodes, node)
+    if i == 0 {
+      continue
+    }
+    l := links[ids[i-1]][id]
+    path.Links = append(path.Links, Link{
+      SourceID: ids[i-1],
+      TargetID: id,
+      Weight:   l.weight,
+      Concepts: append([]string(nil), l.concepts...),
+    })
+    path.Strength *= l.weight
+  }
+
+  return path, true, nil
+}
+
+// pairKey identifies an unordered pair of nodes.
+type pairKey struct {
+  low, high int64
+}
+
+func newPairKey(a, b int64) pairKey {
+  if a > b {
+    a, b = b, a
+  }
+  return pairKey{low: a, high: b}
+}
+
+// pathCost is the cost of reaching a node: the number of links and the sum of -log(weight).
+type pathCost struct {
+  hops    int
+  logCost float64
+}
+
+// less reports whether the cost is better than the other in the given mode.
+func (c pathCost) less(other pathCost, mode string) bool {
+  if mode == PathShortest && c.hops != other.hops {
+    return c.hops < other.hops
+  }
+  if c.logCost != other.logCost {
+    return c.logCost < other.logCost
+  }
+  return c.hops < other.hops
+}
+
+// pathItem is a node waiting in the Dijkstra queue.
+type pathItem struct {
+  id   int64
+  cost pathCost
+}
+
+// pathQueue is a priority queue of nodes ordered by the cost of reaching them.
+type pathQueue struct {
+  mode  string
+  items []pathItem
+}
+
+func (q *pathQueue) Len() int { return len(q.items) }
+func (q *pathQueue) Less(i, j int) bool {
+  a, b := q.items[i], q.items[j]
+  if a.cost.less(b.cost, q.mode) || b.cost.less(a.cost, q.mode) {
+    return a.cost.less(b.cost, q.mode)
+  }
+  return a.id < b.id
+}
+func (q *pathQueue) Swap(i, j int)      { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *pathQueue) Push(x interface{}) { q.items = append(q.items, x.(pathItem)) }
+func (q *pathQueue) Pop() interface{} {
+  item := q.items[len(q.items)-1]
+  q.items = q.items[:len(q.items)-1]
+  return item
+}
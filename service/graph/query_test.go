@@ -0,0 +1,169 @@
+package graph
+
+import (
+  "math"
+  "reflect"
+  "testing"
+)
+
+// testQueryGraph returns a graph where node 1 reaches node 3 in two weak hops through node 2 or
+// in three strong ones through nodes 4 and 5, or 11 instead of 5. Node 6 connects them in two
+// hops too, through an edge without weight, and node 7 is not connected at all.
+func testQueryGraph(indexed bool) *Graph {
+  graph := &Graph{UserID: 1}
+  for id := int64(1); id <= 11; id++ {
+    graph.Nodes = append(graph.Nodes, Node{ID: id, Text: "note"})
+  }
+  graph.Edges = []Edge{
+    {SourceID: 1, TargetID: 2, Weight: 0.5},
+    {SourceID: 2, TargetID: 1, Weight: 0.3},
+    {SourceID: 2, TargetID: 3, Weight: 0.5},
+    {SourceID: 1, TargetID: 4, Weight: 0.9},
+    {SourceID: 5, TargetID: 4, Weight: 0.9},
+    {SourceID: 5, TargetID: 3, Weight: 0.9},
+    {SourceID: 4, TargetID: 11, Weight: 0.9},
+    {SourceID: 11, TargetID: 3, Weight: 0.9},
+    {SourceID: 4, TargetID: 8, Weight: 0.9},
+    {SourceID: 1, TargetID: 6, Weight: 0},
+    {SourceID: 6, TargetID: 3, Weight: 1},
+    {SourceID: 9, TargetID: 9, Weight: 1},
+  }
+  graph.Vertices = []Vertex{
+    {NodeID: 2, TargetID: 1, Concept: "budget"},
+    {NodeID: 4, TargetID: 5, Concept: "Travel"},
+    {NodeID: 5, TargetID: 3, Concept: "travel"},
+    {NodeID: 5, TargetID: 3, Concept: "hiring"},
+    {NodeID: 5, TargetID: 3, Concept: "travel"},
+    {NodeID: 9, TargetID: 10, Concept: "hiring"},
+  }
+  if indexed {
+    graph.ensureIndex()
+  }
+  return graph
+}
+
+// pathIDs returns the IDs of the nodes of a path.
+func pathIDs(path Path) []int64 {
+  var ids []int64
+  for _, node := range path.Nodes {
+    ids = append(ids, node.ID)
+  }
+  return ids
+}
+
+func TestFindPath(t *testing.T) {
+  tests := []struct {
+    name      string
+    from, to  int64
+    mode      string
+    want      []int64
+    strength  float64
+    connected bool
+  }{
+    {"fewest hops", 1, 3, PathShortest, []int64{1, 2, 3}, 0.25, true},
+    {"strongest product of weights", 1, 3, PathStrongest, []int64{1, 4, 5, 3}, 0.729, true},
+    {"reverse", 3, 1, PathShortest, []int64{3, 2, 1}, 0.25, true},
+    {"strongest duplicate edge", 2, 1, PathStrongest, []int64{2, 1}, 0.5, true},
+    {"tie broken by node ID", 4, 3, PathShortest, []int64{4, 5, 3}, 0.81, true},
+    {"tie broken by node ID when strongest", 4, 3, PathStrongest, []int64{4, 5, 3}, 0.81, true},
+    {"zero weight edge skipped", 1, 6, PathShortest, []int64{1, 2, 3, 6}, 0.25, true},
+    {"same node", 7, 7, PathShortest, []int64{7}, 1, true},
+    {"unconnected", 1, 7, PathShortest, nil, 0, false},
+    {"unconnected when strongest", 7, 1, PathStrongest, nil, 0, false},
+    {"only a vertex", 9, 10, PathShortest, nil, 0, false},
+  }
+  for _, indexed := range []bool{false, true} {
+    graph := testQueryGraph(indexed)
+    for _, tt := range tests {
+      path, connected, err := graph.FindPath(tt.from, tt.to, tt.mode)
+      if err != nil {
+        t.Fatalf("%s: FindPath: %v", tt.name, err)
+      }
+      if connected != tt.connected || !reflect.DeepEqual(pathIDs(path), tt.want) {
+        t.Errorf("%s (indexed %v): FindPath = %v, %v, want %v, %v", tt.name, indexed, pathIDs(path), connected, tt.want, tt.connected)
+        continue
+      }
+      if connected && (math.Abs(path.Strength-tt.strength) > 1e-9 || len(path.Links) != len(path.Nodes)-1) {
+        t.Errorf("%s (indexed %v): strength %v with %d links, want %v", tt.name, indexed, path.Strength, len(path.Links), tt.strength)
+      }
+    }
+
+    path, _, _ := graph.FindPath(1, 3, PathStrongest)
+    if link := path.Links[2]; link.SourceID != 5 || link.TargetID != 3 || link.Weight != 0.9 || !reflect.DeepEqual(link.Concepts, []string{"travel", "hiring"}) {
+      t.Errorf("indexed %v: last link = %+v, want 5 to 3 sharing travel and hiring", indexed, link)
+    }
+
+    for _, ids := range [][2]int64{{1, 99}, {99, 1}} {
+      if _, _, err := graph.FindPath(ids[0], ids[1], PathShortest); err != ErrNodeNotFound {
+        t.Errorf("FindPath(%d, %d) error = %v, want ErrNodeNotFound", ids[0], ids[1], err)
+      }
+    }
+    if _, _, err := graph.FindPath(1, 3, "longest"); err == nil || err == ErrNodeNotFound {
+      t.Errorf("FindPath in an unknown mode error = %v", err)
+    }
+  }
+}
+
+func TestNeighbors(t *testing.T) {
+  for _, indexed := range []bool{false, true} {
+    graph := testQueryGraph(indexed)
+
+    // Equally strong neighbors are ordered by ID
+    neighbors, err := graph.Neighbors(4)
+    if err != nil {
+      t.Fatal(err)
+    }
+    var ids []int64
+    for _, neighbor := range neighbors {
+      ids = append(ids, neighbor.Node.ID)
+    }
+    if want := []int64{1, 5, 8, 11}; !reflect.DeepEqual(ids, want) {
+      t.Errorf("indexed %v: Neighbors(4) = %v, want %v", indexed, ids, want)
+    }
+    if !reflect.DeepEqual(neighbors[1].Concepts, []string{"Travel"}) || neighbors[0].Concepts != nil {
+      t.Errorf("indexed %v: concepts of the neighbors of 4 = %v and %v", indexed, neighbors[0].Concepts, neighbors[1].Concepts)
+    }
+
+    // The strongest of duplicate edges counts, edges without weight are listed last and vertices
+    // without an edge or edges to the node itself are not neighbors
+    neighbors, err = graph.Neighbors(1)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if len(neighbors) != 3 || neighbors[0].Node.ID != 4 || neighbors[1].Node.ID != 2 || neighbors[1].Weight != 0.5 || neighbors[2].Node.ID != 6 {
+      t.Errorf("indexed %v: Neighbors(1) = %+v", indexed, neighbors)
+    }
+    for _, id := range []int64{7, 9, 10} {
+      if neighbors, err := graph.Neighbors(id); err != nil || len(neighbors) != 0 {
+        t.Errorf("indexed %v: Neighbors(%d) = %v, %v, want none", indexed, id, neighbors, err)
+      }
+    }
+
+    if _, err := graph.Neighbors(99); err != ErrNodeNotFound {
+      t.Errorf("Neighbors(99) error = %v, want ErrNodeNotFound", err)
+    }
+  }
+}
+
+func TestNodesByConcept(t *testing.T) {
+  graph := testQueryGraph(true)
+  tests := []struct {
+    concept string
+    want    []int64
+  }{
+    {"travel", []int64{3, 4, 5}},
+    {"TRAVEL", []int64{3, 4, 5}},
+    {"Hiring", []int64{3, 5, 9, 10}},
+    {"budget", []int64{1, 2}},
+    {"finance", nil},
+  }
+  for _, tt := range tests {
+    var ids []int64
+    for _, node := range graph.NodesByConcept(tt.concept) {
+      ids = append(ids, node.ID)
+    }
+    if !reflect.DeepEqual(ids, tt.want) {
+      t.Errorf("NodesByConcept(%q) = %v, want %v", tt.concept, ids, tt.want)
+    }
+  }
+}
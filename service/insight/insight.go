@@ -3,81 +3,145 @@ package insight
 import (
   "context"
   "fmt"
-  "github.com/sashabaranov/go-openai"
-  "log"
+  "sort"
   "strings"
+  "time"
+
+  "voice-notetaking-app/service/llm"
+  "voice-notetaking-app/service/summarization"
 )
 
+// Generator generates insights from notes with a language model.
+type Generator struct {
+  LLM llm.Client
+
+  // MaxInputTokens bounds the notes sent to the model by GenerateInsightByTime.
+  MaxInputTokens int
+}
+
+// New creates an insight generator using the given language model client.
+func New(client llm.Client) *Generator {
+  return &Generator{LLM: client}
+}
 
-// GenerateInsight generates insights from grouped notes using OpenAI API.
-func GenerateInsight(groupedNotes []string) (string, error) {
+// GenerateInsight generates insights from grouped notes. Notes may be
+// speaker-attributed, with one "Speaker X: ..." line per utterance.
+func (g *Generator) GenerateInsight(ctx context.Context, groupedNotes []string) (string, error) {
   systemPrompt := `
     You are an AI assistant tasked with generating insights from grouped notes. 
     Provide insights based on the provided grouped notes.
+    If lines are prefixed with a speaker label such as "Speaker A:", say which
+    speaker an insight, commitment or disagreement comes from.
   `
 
-  client := openai.NewClient("your_token_here")
-
   // Prepare messages
-  messages := []openai.ChatCompletionMessage{
+  messages := []llm.Message{
     {
-      Role:    openai.ChatMessageRoleSystem,
+      Role:    llm.RoleSystem,
       Content: systemPrompt,
     },
     {
-      Role:    openai.ChatMessageRoleUser,
+      Role:    llm.RoleUser,
       Content: strings.Join(groupedNotes, "\n"),
     },
   }
 
-  // Create Chat completion request
-  resp, err := client.CreateChatCompletion(
-    context.Background(),
-    openai.ChatCompletionRequest{
-      Model:    openai.GPT3Dot5Turbo,
-      Messages: messages,
-    },
-  )
-
-  if err != nil {
-    log.Printf("ChatCompletion error: %v\n", err)
-    return "", err
-  }
+  return g.LLM.Chat(ctx, messages)
+}
 
-  return resp.Choices[0].Message.Content, nil
+// Note is a note recorded at a known time, for generating insights over a period.
+type Note struct {
+  RecordedAt time.Time
+  Text       string
 }
 
-// GenerateInsightByTime generates insights from notes within a specified timeframe using OpenAI API.
-func GenerateInsightByTime(notes []string, startTime, endTime string) (string, error) {
-  client := openai.NewClient("your_token_here")
+// DefaultMaxInputTokens bounds the notes sent to the model by GenerateInsightByTime.
+const DefaultMaxInputTokens = 6000
 
-  // Prepare messages
-  messages := []openai.ChatCompletionMessage{}
+// MinNoteTokens is the smallest share of the input budget a note is shortened to. When the
+// notes of a period do not fit at that size, the oldest ones are left out instead.
+const MinNoteTokens = 150
+
+// GenerateInsightByTime generates insights from the notes recorded between start and end,
+// looking for themes, decisions and open questions across them. When the notes exceed the
+// input budget each one is shortened to an equal share of it, and blank notes are skipped.
+func (g *Generator) GenerateInsightByTime(ctx context.Context, notes []Note, start, end time.Time) (string, error) {
+  systemPrompt := `
+    You are an AI assistant reviewing a person's voice notes from a period of time.
+    Each note starts with the date and time it was recorded.
+    Write insights about the period as a whole: recurring themes, decisions made,
+    commitments and open questions, and how topics developed over time.
+    Refer to notes by their date when it helps, and do not invent details that are not in the notes.
+  `
+
+  maxTokens := g.MaxInputTokens
+  if maxTokens <= 0 {
+    maxTokens = DefaultMaxInputTokens
+  }
+  notes, left := selectNotes(notes, maxTokens)
+  perNote := maxTokens
+  if len(notes) > 0 {
+    perNote = maxTokens / len(notes)
+  }
+
+  var b strings.Builder
+  fmt.Fprintf(&b, "Period: %s to %s\n", start.Format(time.RFC1123), end.Format(time.RFC1123))
+  if left > 0 {
+    fmt.Fprintf(&b, "The %d oldest notes of the period are left out.\n", left)
+  }
   for _, note := range notes {
-    messages = append(messages, openai.ChatCompletionMessage{
-      Role:    openai.ChatMessageRoleUser,
-      Content: note,
-    })
+    text := strings.TrimSpace(note.Text)
+    if summarization.EstimateTokens(text) > perNote {
+      if chunks := summarization.Chunk(text, perNote, 0); len(chunks) > 0 {
+        text = chunks[0] + " …"
+      }
+    }
+    fmt.Fprintf(&b, "\n[%s]\n%s\n", note.RecordedAt.Format("Mon 2006-01-02 15:04"), text)
   }
-  timeframe := fmt.Sprintf("Start Time: %s\nEnd Time: %s", startTime, endTime)
-  messages = append(messages, openai.ChatCompletionMessage{
-    Role:    openai.ChatMessageRoleUser,
-    Content: timeframe,
-  })
-
-  // Create Chat completion request
-  resp, err := client.CreateChatCompletion(
-    context.Background(),
-    openai.ChatCompletionRequest{
-      Model:    openai.GPT3Dot5Turbo,
-      Messages: messages,
+
+  messages := []llm.Message{
+    {
+      Role:    llm.RoleSystem,
+      Content: systemPrompt,
+    },
+    {
+      Role:    llm.RoleUser,
+      Content: b.String(),
     },
-  )
+  }
+
+  return g.LLM.Chat(ctx, messages)
+}
+
+// selectNotes returns the notes that are not blank, in the order given, leaving out the oldest
+// when there are more than fit in maxTokens at MinNoteTokens each. It also returns how many
+// notes were left out that way.
+func selectNotes(notes []Note, maxTokens int) ([]Note, int) {
+  selected := make([]Note, 0, len(notes))
+  for _, note := range notes {
+    if strings.TrimSpace(note.Text) != "" {
+      selected = append(selected, note)
+    }
+  }
 
-  if err != nil {
-    log.Printf("ChatCompletion error: %v\n", err)
-    return "", err
+  limit := maxTokens / MinNoteTokens
+  if limit < 1 {
+    limit = 1
+  }
+  if len(selected) <= limit {
+    return selected, 0
   }
 
-  return resp.Choices[0].Message.Content, nil
+  // Keep the most recent notes, whatever order they came in
+  newest := make([]Note, len(selected))
+  copy(newest, selected)
+  sort.SliceStable(newest, func(i, j int) bool { return newest[i].RecordedAt.After(newest[j].RecordedAt) })
+  cutoff := newest[limit-1].RecordedAt
+  kept := selected[:0]
+  for _, note := range selected {
+    if len(kept) < limit && !note.RecordedAt.Before(cutoff) {
+      kept = append(kept, note)
+    }
+  }
+  return kept, len(selected) - len(kept)
 }
@@ -0,0 +1,99 @@
+package insight
+
+import (
+  "context"
+  "strings"
+  "testing"
+  "time"
+
+  "voice-notetaking-app/service/llm"
+)
+
+// fakeLLM records the notes sent to it and answers with a fixed insight.
+type fakeLLM struct {
+  prompt string
+}
+
+func (f *fakeLLM) Chat(ctx context.Context, messages []llm.Message, opts ...llm.Option) (string, error) {
+  f.prompt = messages[len(messages)-1].Content
+  return "An insight.", nil
+}
+
+// notesFrom returns n notes of text recorded an hour apart from start.
+func notesFrom(start time.Time, n int, text string) []Note {
+  notes := make([]Note, n)
+  for i := range notes {
+    notes[i] = Note{RecordedAt: start.Add(time.Duration(i) * time.Hour), Text: text}
+  }
+  return notes
+}
+
+func TestGenerateInsightByTimeSkipsBlankNotes(t *testing.T) {
+  fake := &fakeLLM{}
+  generator := &Generator{LLM: fake, MaxInputTokens: 300}
+  start := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+
+  // Silent recordings can transcribe to nothing but whitespace
+  notes := notesFrom(start, 40, strings.Repeat(" ", 5000))
+  notes[7].Text = "The budget is due Friday."
+  text, err := generator.GenerateInsightByTime(context.Background(), notes, start, start.AddDate(0, 0, 7))
+  if err != nil || text != "An insight." {
+    t.Fatalf("GenerateInsightByTime = %q, %v", text, err)
+  }
+  if strings.Count(fake.prompt, "\n[") != 1 || !strings.Contains(fake.prompt, "[Mon 2026-10-12 16:00]\nThe budget is due Friday.") {
+    t.Errorf("prompt = %q, want only the note that is not blank", fake.prompt)
+  }
+}
+
+func TestGenerateInsightByTimeLeavesOutOldestNotes(t *testing.T) {
+  fake := &fakeLLM{}
+  generator := &Generator{LLM: fake, MaxInputTokens: 3 * MinNoteTokens}
+  start := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+
+  long := strings.Repeat("We talked about the quarterly budget and the hiring plan. ", 100)
+  notes := notesFrom(start, 10, long)
+  if _, err := generator.GenerateInsightByTime(context.Background(), notes, start, start.AddDate(0, 0, 1)); err != nil {
+    t.Fatal(err)
+  }
+  if strings.Count(fake.prompt, "\n[") != 3 {
+    t.Errorf("prompt has %d notes, want 3:\n%s", strings.Count(fake.prompt, "\n["), fake.prompt)
+  }
+  for _, recorded := range []string{"[Mon 2026-10-12 16:00]", "[Mon 2026-10-12 17:00]", "[Mon 2026-10-12 18:00]"} {
+    if !strings.Contains(fake.prompt, recorded) {
+      t.Errorf("prompt has no note %s", recorded)
+    }
+  }
+  if !strings.Contains(fake.prompt, "The 7 oldest notes of the period are left out.") {
+    t.Errorf("prompt does not say notes were left out:\n%s", fake.prompt)
+  }
+
+  // Each note is shortened to its share of the budget, not below MinNoteTokens
+  for _, note := range strings.Split(fake.prompt, "\n[")[1:] {
+    if !strings.HasSuffix(note, " …\n") {
+      t.Errorf("note was not shortened: %q", note)
+    }
+  }
+}
+
+func TestSelectNotes(t *testing.T) {
+  start := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+  notes := notesFrom(start, 5, "A note.")
+
+  // Notes may come newest first; the oldest are left out and the order is kept
+  reversed := make([]Note, len(notes))
+  for i, note := range notes {
+    reversed[len(notes)-1-i] = note
+  }
+  selected, left := selectNotes(reversed, 2*MinNoteTokens)
+  if left != 3 || len(selected) != 2 || !selected[0].RecordedAt.Equal(notes[4].RecordedAt) || !selected[1].RecordedAt.Equal(notes[3].RecordedAt) {
+    t.Errorf("selectNotes = %v, %d, want the 2 newest notes, newest first, and 3 left out", selected, left)
+  }
+
+  // A budget smaller than MinNoteTokens still keeps a note
+  if selected, left := selectNotes(notes, 10); len(selected) != 1 || left != 4 {
+    t.Errorf("selectNotes with a small budget = %v, %d, want 1 note", selected, left)
+  }
+  if selected, left := selectNotes(nil, DefaultMaxInputTokens); len(selected) != 0 || left != 0 {
+    t.Errorf("selectNotes of no notes = %v, %d", selected, left)
+  }
+}
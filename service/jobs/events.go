@@ -0,0 +1,91 @@
+package jobs
+
+import (
+  "encoding/json"
+  "fmt"
+  "sync"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+)
+
+// Job lifecycle event types. Stage events are published by the Runner with its own types.
+const (
+  EventQueued    = "queued"
+  EventStarted   = "started"
+  EventCompleted = "completed"
+  EventFailed    = "failed"
+)
+
+// IsTerminalEvent reports whether no further events follow an event of the given type.
+func IsTerminalEvent(eventType string) bool {
+  return eventType == EventCompleted || eventType == EventFailed
+}
+
+// notifier wakes up subscribers of a job whenever a new event is stored for it.
+// Events themselves are read back from the database, so a slow subscriber never misses one.
+type notifier struct {
+  mu          sync.Mutex
+  subscribers map[string]map[chan struct{}]struct{}
+}
+
+func newNotifier() *notifier {
+  return &notifier{subscribers: make(map[string]map[chan struct{}]struct{})}
+}
+
+// subscribe registers for notifications about a job and returns a function to unregister.
+func (n *notifier) subscribe(jobID string) (<-chan struct{}, func()) {
+  ch := make(chan struct{}, 1)
+
+  n.mu.Lock()
+  if n.subscribers[jobID] == nil {
+    n.subscribers[jobID] = make(map[chan struct{}]struct{})
+  }
+  n.subscribers[jobID][ch] = struct{}{}
+  n.mu.Unlock()
+
+  return ch, func() {
+    n.mu.Lock()
+    delete(n.subscribers[jobID], ch)
+    if len(n.subscribers[jobID]) == 0 {
+      delete(n.subscribers, jobID)
+    }
+    n.mu.Unlock()
+  }
+}
+
+// notify wakes up every subscriber of the job without blocking.
+func (n *notifier) notify(jobID string) {
+  n.mu.Lock()
+  defer n.mu.Unlock()
+
+  for ch := range n.subscribers[jobID] {
+    select {
+    case ch <- struct{}{}:
+    default:
+    }
+  }
+}
+
+// Subscribe returns a channel that receives a signal whenever a new event is stored for the job,
+// and a function that must be called to stop receiving them.
+func (m *Manager) Subscribe(jobID string) (<-chan struct{}, func()) {
+  return m.events.subscribe(jobID)
+}
+
+// publish stores an event for the job and notifies its subscribers.
+func (m *Manager) publish(jobID, eventType string, data interface{}) error {
+  encoded, err := json.Marshal(data)
+  if err != nil {
+    return fmt.Errorf("failed to marshal event data: %v", err)
+  }
+  if _, err := sqlite.InsertJobEvent(jobID, eventType, string(encoded)); err != nil {
+    return fmt.Errorf("failed to insert job event: %v", err)
+  }
+  m.events.notify(jobID)
+  return nil
+}
+
+// Publish stores a typed progress event with its data for the job and notifies its subscribers.
+func (j *Job) Publish(eventType string, data interface{}) error {
+  return j.manager.publish(j.ID, eventType, data)
+}
@@ -0,0 +1,277 @@
+package jobs
+
+import (
+  "context"
+  "crypto/rand"
+  "encoding/hex"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "log"
+  "strings"
+  "sync"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+)
+
+// Job statuses.
+const (
+  StatusQueued    = "queued"
+  StatusRunning   = "running"
+  StatusCompleted = "completed"
+  StatusPartial   = "partial"
+  StatusFailed    = "failed"
+)
+
+// Stage statuses.
+const (
+  StagePending   = "pending"
+  StageRunning   = "running"
+  StageCompleted = "completed"
+  StageFailed    = "failed"
+  StageSkipped   = "skipped"
+)
+
+// ErrQueueFull is returned by Submit when no more jobs can be queued.
+var ErrQueueFull = errors.New("job queue is full")
+
+// PartialError is returned by a Runner whose job finished but had some stages fail.
+// The job is recorded as partial instead of failed, keeping the results of the other stages.
+type PartialError struct {
+  Stages []string
+}
+
+func (e *PartialError) Error() string {
+  return fmt.Sprintf("stages failed: %s", strings.Join(e.Stages, ", "))
+}
+
+// Job is the handle a Runner uses to read its input and report the progress of each stage.
+// CreatedAt is when the audio was uploaded, which is taken as when it was recorded.
+type Job struct {
+  ID        string
+  UserID    int64
+  AudioPath string
+  CreatedAt time.Time
+
+  manager *Manager
+}
+
+// Runner processes a single job, reporting stage progress through the job handle.
+type Runner func(ctx context.Context, job *Job) error
+
+// Manager queues jobs and runs them on a fixed pool of workers.
+type Manager struct {
+  stages  []string
+  workers int
+  run     Runner
+  queue   chan string
+  events  *notifier
+  wg      sync.WaitGroup
+}
+
+// NewManager creates a manager for jobs made of the given stages.
+func NewManager(stages []string, workers, queueSize int, run Runner) *Manager {
+  if workers < 1 {
+    workers = 1
+  }
+  if queueSize < 1 {
+    queueSize = 1
+  }
+  return &Manager{
+    stages:  stages,
+    workers: workers,
+    run:     run,
+    queue:   make(chan string, queueSize),
+    events:  newNotifier(),
+  }
+}
+
+// NewID generates a random job ID.
+func NewID() (string, error) {
+  b := make([]byte, 16)
+  if _, err := rand.Read(b); err != nil {
+    return "", fmt.Errorf("failed to generate job ID: %v", err)
+  }
+  return hex.EncodeToString(b), nil
+}
+
+// Start recovers jobs left over from a previous run and starts the workers.
+// Queued jobs are requeued; jobs that were running are marked as failed.
+func (m *Manager) Start(ctx context.Context) error {
+  interrupted, err := sqlite.GetJobIDsByStatus(StatusRunning)
+  if err != nil {
+    return fmt.Errorf("failed to load running jobs: %v", err)
+  }
+  for _, id := range interrupted {
+    m.finish(id, fmt.Errorf("interrupted by server restart"))
+  }
+
+  queued, err := sqlite.GetJobIDsByStatus(StatusQueued)
+  if err != nil {
+    return fmt.Errorf("failed to load queued jobs: %v", err)
+  }
+
+  for i := 0; i < m.workers; i++ {
+    m.wg.Add(1)
+    go m.worker(ctx)
+  }
+
+  if len(queued) > 0 {
+    log.Printf("Requeueing %d unfinished jobs", len(queued))
+    go func() {
+      for _, id := range queued {
+        select {
+        case m.queue <- id:
+        case <-ctx.Done():
+          return
+        }
+      }
+    }()
+  }
+
+  return nil
+}
+
+// Submit persists a new job for the audio file and queues it for processing.
+func (m *Manager) Submit(id string, userID int64, audioPath string) error {
+  if err := sqlite.InsertJob(id, userID, audioPath, StatusQueued, m.stages, StagePending); err != nil {
+    return fmt.Errorf("failed to insert job: %v", err)
+  }
+  if err := m.publish(id, EventQueued, map[string]interface{}{"job_id": id, "stages": m.stages}); err != nil {
+    log.Printf("Failed to publish queued event for job %s: %v", id, err)
+  }
+
+  select {
+  case m.queue <- id:
+    return nil
+  default:
+    m.finish(id, ErrQueueFull)
+    return ErrQueueFull
+  }
+}
+
+// Wait blocks until all workers have exited after the start context is cancelled.
+func (m *Manager) Wait() {
+  m.wg.Wait()
+}
+
+// worker processes queued jobs until the context is cancelled.
+func (m *Manager) worker(ctx context.Context) {
+  defer m.wg.Done()
+
+  for {
+    select {
+    case <-ctx.Done():
+      return
+    case id := <-m.queue:
+      m.process(ctx, id)
+    }
+  }
+}
+
+// process runs a single job and records its final status.
+func (m *Manager) process(ctx context.Context, id string) {
+  stored, err := sqlite.GetJobByID(id)
+  if err != nil {
+    log.Printf("Failed to load job %s: %v", id, err)
+    return
+  }
+
+  if err := sqlite.UpdateJobStatus(id, StatusRunning, ""); err != nil {
+    log.Printf("Failed to mark job %s as running: %v", id, err)
+    return
+  }
+  if err := m.publish(id, EventStarted, map[string]interface{}{"job_id": id}); err != nil {
+    log.Printf("Failed to publish started event for job %s: %v", id, err)
+  }
+  log.Printf("Job %s started", id)
+
+  job := &Job{
+    ID:        stored.ID,
+    UserID:    stored.UserID,
+    AudioPath: stored.AudioPath,
+    CreatedAt: stored.CreatedAt,
+    manager:   m,
+  }
+
+  m.finish(id, m.runSafely(ctx, job))
+}
+
+// finish records the final status of a job and publishes the matching terminal event.
+func (m *Manager) finish(id string, jobErr error) {
+  status, eventType, message := StatusCompleted, EventCompleted, ""
+  var partial *PartialError
+  if errors.As(jobErr, &partial) {
+    status, message = StatusPartial, jobErr.Error()
+    log.Printf("Job %s completed with errors: %v", id, jobErr)
+  } else if jobErr != nil {
+    status, eventType, message = StatusFailed, EventFailed, jobErr.Error()
+    log.Printf("Job %s failed: %v", id, jobErr)
+  } else {
+    log.Printf("Job %s completed", id)
+  }
+
+  if err := sqlite.UpdateJobStatus(id, status, message); err != nil {
+    log.Printf("Failed to mark job %s as %s: %v", id, status, err)
+  }
+
+  data := map[string]interface{}{"job_id": id, "status": status}
+  if message != "" {
+    data["error"] = message
+  }
+  if err := m.publish(id, eventType, data); err != nil {
+    log.Printf("Failed to publish %s event for job %s: %v", eventType, id, err)
+  }
+}
+
+// runSafely runs the job, turning a panic into an error so one bad job cannot stop a worker.
+func (m *Manager) runSafely(ctx context.Context, job *Job) (err error) {
+  defer func() {
+    if r := recover(); r != nil {
+      err = fmt.Errorf("panic: %v", r)
+    }
+  }()
+  return m.run(ctx, job)
+}
+
+// StartStage marks the stage as running.
+func (j *Job) StartStage(name string) error {
+  return sqlite.StartJobStage(j.ID, name, StageRunning)
+}
+
+// CompleteStage marks the stage as completed and stores its result as JSON.
+func (j *Job) CompleteStage(name string, result interface{}) error {
+  encoded, err := encodeResult(result)
+  if err != nil {
+    return err
+  }
+  return sqlite.FinishJobStage(j.ID, name, StageCompleted, "", encoded)
+}
+
+// FailStage marks the stage as failed with the given error.
+func (j *Job) FailStage(name string, stageErr error) error {
+  return sqlite.FinishJobStage(j.ID, name, StageFailed, stageErr.Error(), "")
+}
+
+// SkipStage marks the stage as skipped, for example because an earlier stage failed.
+func (j *Job) SkipStage(name, reason string) error {
+  return sqlite.FinishJobStage(j.ID, name, StageSkipped, reason, "")
+}
+
+// SetRecording links the job to the recording it produced.
+func (j *Job) SetRecording(recordingID int64) error {
+  return sqlite.SetJobRecording(j.ID, recordingID)
+}
+
+// encodeResult marshals a stage result to JSON, leaving empty results empty.
+func encodeResult(result interface{}) (string, error) {
+  if result == nil {
+    return "", nil
+  }
+  encoded, err := json.Marshal(result)
+  if err != nil {
+    return "", fmt.Errorf("failed to marshal stage result: %v", err)
+  }
+  return string(encoded), nil
+}
@@ -0,0 +1,236 @@
+package jobs
+
+import (
+  "context"
+  "errors"
+  "path/filepath"
+  "reflect"
+  "testing"
+  "time"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+)
+
+var testStages = []string{"transcribe", "summarize", "tag"}
+
+func initTestDB(t *testing.T) int64 {
+  t.Helper()
+  if err := sqlite.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+    t.Fatal(err)
+  }
+  userID, err := sqlite.InsertUser("Ana", "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  return userID
+}
+
+// startManager starts a manager with a single worker, stopping it when the test ends.
+func startManager(t *testing.T, queueSize int, run Runner) *Manager {
+  t.Helper()
+  m := NewManager(testStages, 1, queueSize, run)
+  ctx, cancel := context.WithCancel(context.Background())
+  if err := m.Start(ctx); err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() {
+    cancel()
+    m.Wait()
+  })
+  return m
+}
+
+// waitForJob waits until a terminal event is stored for the job and returns the types of its events.
+func waitForJob(t *testing.T, m *Manager, id string) []string {
+  t.Helper()
+  notifications, unsubscribe := m.Subscribe(id)
+  defer unsubscribe()
+
+  timeout := time.After(5 * time.Second)
+  for {
+    events, err := sqlite.GetJobEventsAfter(id, 0)
+    if err != nil {
+      t.Fatal(err)
+    }
+    var types []string
+    for _, event := range events {
+      types = append(types, event.Type)
+    }
+    if len(types) > 0 && IsTerminalEvent(types[len(types)-1]) {
+      return types
+    }
+    select {
+    case <-notifications:
+    case <-timeout:
+      t.Fatalf("job %s did not finish, events so far: %v", id, types)
+    }
+  }
+}
+
+func getJob(t *testing.T, id string) sqlite.Job {
+  t.Helper()
+  job, err := sqlite.GetJobByID(id)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return job
+}
+
+func TestSubmitQueueFull(t *testing.T) {
+  userID := initTestDB(t)
+
+  // Without workers nothing takes jobs off the queue, so the second one does not fit
+  m := NewManager(testStages, 1, 1, func(ctx context.Context, job *Job) error { return nil })
+  if err := m.Submit("first", userID, "first.mp3"); err != nil {
+    t.Fatalf("Submit(first) = %v", err)
+  }
+  if err := m.Submit("second", userID, "second.mp3"); err != ErrQueueFull {
+    t.Fatalf("Submit(second) = %v, want %v", err, ErrQueueFull)
+  }
+
+  if job := getJob(t, "first"); job.Status != StatusQueued {
+    t.Errorf("queued job has status %q, want %q", job.Status, StatusQueued)
+  }
+  job := getJob(t, "second")
+  if job.Status != StatusFailed || job.Error != ErrQueueFull.Error() {
+    t.Errorf("rejected job has status %q and error %q, want %q and %q", job.Status, job.Error, StatusFailed, ErrQueueFull)
+  }
+  events, err := sqlite.GetJobEventsAfter("second", 0)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(events) != 2 || events[0].Type != EventQueued || events[1].Type != EventFailed {
+    t.Errorf("rejected job has events %+v, want queued and failed", events)
+  }
+}
+
+func TestJobStatus(t *testing.T) {
+  tests := []struct {
+    name   string
+    run    Runner
+    status string
+    err    string
+    event  string
+  }{
+    {"completed", func(ctx context.Context, job *Job) error { return nil }, StatusCompleted, "", EventCompleted},
+    {"partial", func(ctx context.Context, job *Job) error {
+      return &PartialError{Stages: []string{"summarize", "tag"}}
+    }, StatusPartial, "stages failed: summarize, tag", EventCompleted},
+    {"failed", func(ctx context.Context, job *Job) error { return errors.New("transcription failed") }, StatusFailed, "transcription failed", EventFailed},
+    {"panic", func(ctx context.Context, job *Job) error { panic("nil map") }, StatusFailed, "panic: nil map", EventFailed},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      userID := initTestDB(t)
+      m := startManager(t, 4, tt.run)
+      if err := m.Submit(tt.name, userID, tt.name+".mp3"); err != nil {
+        t.Fatal(err)
+      }
+
+      want := []string{EventQueued, EventStarted, tt.event}
+      if got := waitForJob(t, m, tt.name); !reflect.DeepEqual(got, want) {
+        t.Errorf("events = %v, want %v", got, want)
+      }
+      job := getJob(t, tt.name)
+      if job.Status != tt.status || job.Error != tt.err {
+        t.Errorf("job has status %q and error %q, want %q and %q", job.Status, job.Error, tt.status, tt.err)
+      }
+    })
+  }
+}
+
+func TestJobStages(t *testing.T) {
+  userID := initTestDB(t)
+  recordingID, err := sqlite.InsertRecording(userID, "job.mp3", "")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  var got *Job
+  m := startManager(t, 4, func(ctx context.Context, job *Job) error {
+    got = job
+    for _, err := range []error{
+      job.StartStage("transcribe"),
+      job.CompleteStage("transcribe", map[string]int{"words": 3}),
+      job.SetRecording(recordingID),
+      job.StartStage("summarize"),
+      job.FailStage("summarize", errors.New("model unavailable")),
+      job.SkipStage("tag", "no summary"),
+      job.Publish("stage", map[string]string{"name": "tag"}),
+    } {
+      if err != nil {
+        return err
+      }
+    }
+    return nil
+  })
+  if err := m.Submit("job", userID, "job.mp3"); err != nil {
+    t.Fatal(err)
+  }
+
+  want := []string{EventQueued, EventStarted, "stage", EventCompleted}
+  if events := waitForJob(t, m, "job"); !reflect.DeepEqual(events, want) {
+    t.Errorf("events = %v, want %v", events, want)
+  }
+  if got.ID != "job" || got.UserID != userID || got.AudioPath != "job.mp3" || got.CreatedAt.IsZero() {
+    t.Errorf("runner got job %+v", got)
+  }
+
+  job := getJob(t, "job")
+  if job.RecordingID != recordingID {
+    t.Errorf("job has recording %d, want %d", job.RecordingID, recordingID)
+  }
+  stages := []struct {
+    name, status, err, result string
+    started                   bool
+  }{
+    {"transcribe", StageCompleted, "", `{"words":3}`, true},
+    {"summarize", StageFailed, "model unavailable", "", true},
+    {"tag", StageSkipped, "no summary", "", false},
+  }
+  if len(job.Stages) != len(stages) {
+    t.Fatalf("job has %d stages, want %d", len(job.Stages), len(stages))
+  }
+  for i, want := range stages {
+    stage := job.Stages[i]
+    if stage.Name != want.name || stage.Status != want.status || stage.Error != want.err || stage.Result != want.result {
+      t.Errorf("stage %d = %s %s %q %q, want %s %s %q %q", i, stage.Name, stage.Status, stage.Error, stage.Result, want.name, want.status, want.err, want.result)
+    }
+    if (stage.StartedAt != nil) != want.started || stage.FinishedAt == nil {
+      t.Errorf("stage %s started at %v and finished at %v", stage.Name, stage.StartedAt, stage.FinishedAt)
+    }
+  }
+}
+
+func TestStartRecoversJobs(t *testing.T) {
+  userID := initTestDB(t)
+  if err := sqlite.InsertJob("running", userID, "running.mp3", StatusRunning, testStages, StagePending); err != nil {
+    t.Fatal(err)
+  }
+  if err := sqlite.InsertJob("queued", userID, "queued.mp3", StatusQueued, testStages, StagePending); err != nil {
+    t.Fatal(err)
+  }
+
+  ran := make(chan string, 2)
+  m := startManager(t, 4, func(ctx context.Context, job *Job) error {
+    ran <- job.ID
+    return nil
+  })
+  waitForJob(t, m, "queued")
+
+  // A job that was running when the server stopped is failed rather than run again
+  if job := getJob(t, "running"); job.Status != StatusFailed || job.Error != "interrupted by server restart" {
+    t.Errorf("interrupted job has status %q and error %q", job.Status, job.Error)
+  }
+  if job := getJob(t, "queued"); job.Status != StatusCompleted {
+    t.Errorf("requeued job has status %q, want %q", job.Status, StatusCompleted)
+  }
+  close(ran)
+  var ids []string
+  for id := range ran {
+    ids = append(ids, id)
+  }
+  if !reflect.DeepEqual(ids, []string{"queued"}) {
+    t.Errorf("ran jobs %v, want only the queued one", ids)
+  }
+}
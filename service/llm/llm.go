@@ -0,0 +1,138 @@
+package llm
+
+import (
+  "context"
+  "errors"
+  "log"
+  "math"
+  "strings"
+
+  openai "github.com/sashabaranov/go-openai"
+)
+
+// Chat message roles.
+const (
+  RoleSystem    = "system"
+  RoleUser      = "user"
+  RoleAssistant = "assistant"
+)
+
+// DefaultModel is the model used when neither the client nor the request names one.
+const DefaultModel = openai.GPT3Dot5Turbo
+
+// Message is a single message of a chat conversation.
+type Message struct {
+  Role    string
+  Content string
+}
+
+// Options are the per-request settings of a chat completion.
+type Options struct {
+  Model       string
+  Temperature *float32
+  MaxTokens   int
+  JSON        bool
+}
+
+// Option changes the settings of a chat completion.
+type Option func(*Options)
+
+// WithModel selects the model to use instead of the client default.
+func WithModel(model string) Option {
+  return func(o *Options) { o.Model = model }
+}
+
+// WithTemperature sets the sampling temperature.
+func WithTemperature(temperature float32) Option {
+  return func(o *Options) { o.Temperature = &temperature }
+}
+
+// WithMaxTokens limits the number of tokens generated.
+func WithMaxTokens(maxTokens int) Option {
+  return func(o *Options) { o.MaxTokens = maxTokens }
+}
+
+// WithJSON asks the model to answer with a single JSON object.
+func WithJSON() Option {
+  return func(o *Options) { o.JSON = true }
+}
+
+// Client generates chat completions.
+type Client interface {
+  Chat(ctx context.Context, messages []Message, opts ...Option) (string, error)
+}
+
+// ErrNoChoices is returned when the model answers without any completion.
+var ErrNoChoices = errors.New("chat completion returned no choices")
+
+// OpenAI is a Client for OpenAI-compatible chat completion APIs, including local
+// stand-ins and self-hosted models that expose the same endpoints.
+type OpenAI struct {
+  client *openai.Client
+  model  string
+}
+
+// NewOpenAI creates a client for the API at baseURL using model unless a request overrides it.
+// An empty baseURL uses the OpenAI API and an empty model uses DefaultModel.
+func NewOpenAI(apiKey, baseURL, model string) *OpenAI {
+  config := openai.DefaultConfig(apiKey)
+  if baseURL != "" {
+    config.BaseURL = strings.TrimRight(baseURL, "/")
+  }
+  if model == "" {
+    model = DefaultModel
+  }
+  return &OpenAI{
+    client: openai.NewClientWithConfig(config),
+    model:  model,
+  }
+}
+
+// Model returns the default model of the client.
+func (c *OpenAI) Model() string {
+  return c.model
+}
+
+// Chat sends the messages and returns the content of the first completion.
+func (c *OpenAI) Chat(ctx context.Context, messages []Message, opts ...Option) (string, error) {
+  options := Options{Model: c.model}
+  for _, opt := range opts {
+    opt(&options)
+  }
+
+  request := openai.ChatCompletionRequest{
+    Model:     options.Model,
+    Messages:  make([]openai.ChatCompletionMessage, 0, len(messages)),
+    MaxTokens: options.MaxTokens,
+  }
+  for _, message := range messages {
+    request.Messages = append(request.Messages, openai.ChatCompletionMessage{
+      Role:    message.Role,
+      Content: message.Content,
+    })
+  }
+  if options.Temperature != nil {
+    request.Temperature = *options.Temperature
+    // A temperature of 0 is left out of the request, which then runs at the API default of 1,
+    // so ask for the smallest temperature above it instead
+    if request.Temperature == 0 {
+      request.Temperature = math.SmallestNonzeroFloat32
+    }
+  }
+  if options.JSON {
+    request.ResponseFormat = &openai.ChatCompletionResponseFormat{
+      Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+    }
+  }
+
+  resp, err := c.client.CreateChatCompletion(ctx, request)
+  if err != nil {
+    log.Printf("ChatCompletion error: %v\n", err)
+    return "", err
+  }
+  if len(resp.Choices) == 0 {
+    return "", ErrNoChoices
+  }
+
+  return resp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,80 @@
+package llm
+
+import (
+  "context"
+  "encoding/json"
+  "net/http"
+  "net/http/httptest"
+  "testing"
+)
+
+// fakeChat serves chat completions, recording the body of the last request.
+func fakeChat(t *testing.T, request *map[string]interface{}) *httptest.Server {
+  server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if r.URL.Path != "/chat/completions" {
+      http.NotFound(w, r)
+      return
+    }
+    *request = nil
+    if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+      http.Error(w, err.Error(), http.StatusBadRequest)
+      return
+    }
+    json.NewEncoder(w).Encode(map[string]interface{}{
+      "choices": []map[string]interface{}{{"message": map[string]string{"role": RoleAssistant, "content": "Hi"}}},
+    })
+  }))
+  t.Cleanup(server.Close)
+  return server
+}
+
+func TestChatTemperature(t *testing.T) {
+  var request map[string]interface{}
+  client := NewOpenAI("test-key", fakeChat(t, &request).URL, "test-model")
+  messages := []Message{{Role: RoleUser, Content: "Hello"}}
+
+  tests := []struct {
+    name string
+    opts []Option
+    // want is the temperature sent, or below 0 if none is
+    want float64
+  }{
+    {"default", nil, -1},
+    {"zero", []Option{WithTemperature(0)}, 0},
+    {"nonzero", []Option{WithTemperature(0.2)}, 0.2},
+  }
+  for _, tt := range tests {
+    reply, err := client.Chat(context.Background(), messages, tt.opts...)
+    if err != nil || reply != "Hi" {
+      t.Fatalf("%s: Chat = %q, %v", tt.name, reply, err)
+    }
+    temperature, sent := request["temperature"].(float64)
+    switch {
+    case tt.want < 0 && sent:
+      t.Errorf("%s: sent temperature %v, want none", tt.name, temperature)
+    case tt.want >= 0 && !sent:
+      t.Errorf("%s: sent no temperature, want %v", tt.name, tt.want)
+    case tt.want >= 0 && (temperature < tt.want || temperature > tt.want+1e-6):
+      t.Errorf("%s: sent temperature %v, want %v", tt.name, temperature, tt.want)
+    }
+  }
+}
+
+func TestChatRequest(t *testing.T) {
+  var request map[string]interface{}
+  client := NewOpenAI("test-key", fakeChat(t, &request).URL+"/", "")
+  _, err := client.Chat(context.Background(), []Message{{Role: RoleSystem, Content: "Be brief"}, {Role: RoleUser, Content: "Hello"}},
+    WithModel("other-model"), WithMaxTokens(50), WithJSON())
+  if err != nil {
+    t.Fatal(err)
+  }
+  if request["model"] != "other-model" || request["max_tokens"] != float64(50) {
+    t.Errorf("model and max tokens = %v, %v, want other-model and 50", request["model"], request["max_tokens"])
+  }
+  if format, _ := request["response_format"].(map[string]interface{}); format["type"] != "json_object" {
+    t.Errorf("response format = %v, want a JSON object", request["response_format"])
+  }
+  if messages, _ := request["messages"].([]interface{}); len(messages) != 2 {
+    t.Errorf("messages = %v, want 2", request["messages"])
+  }
+}
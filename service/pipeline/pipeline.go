@@ -0,0 +1,115 @@
+package pipeline
+
+import (
+  "context"
+  "fmt"
+  "sync"
+  "time"
+)
+
+// Stage is a named unit of work of a pipeline. Its value is recorded as the stage result.
+type Stage struct {
+  Name string
+  Run  func(ctx context.Context) (interface{}, error)
+}
+
+// Result is the outcome of a single stage.
+type Result struct {
+  Name     string
+  Value    interface{}
+  Err      error
+  Duration time.Duration
+}
+
+// Orchestrator runs independent stages concurrently with a bounded number in flight.
+// A failing stage never cancels the others; every stage reports its own result.
+type Orchestrator struct {
+  // Limit is the maximum number of stages running at once. Values below 1 mean 1.
+  Limit int
+
+  // OnStart, if set, is called before each stage runs.
+  OnStart func(name string)
+
+  // OnFinish, if set, is called with the result of each stage as soon as it finishes.
+  OnFinish func(result Result)
+}
+
+// Run runs the stages and returns their results in the same order as the stages.
+// Stages that have not started when the context is cancelled fail with the context error.
+func (o *Orchestrator) Run(ctx context.Context, stages ...Stage) []Result {
+  limit := o.Limit
+  if limit < 1 {
+    limit = 1
+  }
+
+  results := make([]Result, len(stages))
+  slots := make(chan struct{}, limit)
+
+  var wg sync.WaitGroup
+  for i, stage := range stages {
+    // Both cases may be ready once a slot frees up after cancellation, so check the context again
+    acquired := false
+    select {
+    case slots <- struct{}{}:
+      acquired = true
+    case <-ctx.Done():
+    }
+    if err := ctx.Err(); err != nil {
+      if acquired {
+        <-slots
+      }
+      results[i] = Result{Name: stage.Name, Err: err}
+      o.finish(results[i])
+      continue
+    }
+
+    wg.Add(1)
+    go func(i int, stage Stage) {
+      defer wg.Done()
+      defer func() { <-slots }()
+
+      results[i] = o.runStage(ctx, stage)
+      o.finish(results[i])
+    }(i, stage)
+  }
+  wg.Wait()
+
+  return results
+}
+
+// runStage runs a single stage, turning a panic into an error.
+func (o *Orchestrator) runStage(ctx context.Context, stage Stage) (result Result) {
+  if o.OnStart != nil {
+    o.OnStart(stage.Name)
+  }
+
+  start := time.Now()
+  result.Name = stage.Name
+  defer func() {
+    if r := recover(); r != nil {
+      result.Err = fmt.Errorf("panic: %v", r)
+    }
+    result.Duration = time.Since(start)
+  }()
+
+  result.Value, result.Err = stage.Run(ctx)
+  return result
+}
+
+// finish reports a stage result to the OnFinish hook.
+func (o *Orchestrator) finish(result Result) {
+  if o.OnFinish != nil {
+    o.OnFinish(result)
+  }
+}
+
+// Failed returns the names of the stages whose results carry an error.
+func Failed(results []Result) []string {
+  var names []string
+  for _, result := range results {
+    if result.Err != nil {
+      names = append(names, result.Name)
+    }
+  }
+  return names
+}
@@ -0,0 +1,154 @@
+package pipeline
+
+import (
+  "context"
+  "errors"
+  "reflect"
+  "strconv"
+  "strings"
+  "sync"
+  "sync/atomic"
+  "testing"
+  "time"
+)
+
+func TestRunLimit(t *testing.T) {
+  for _, limit := range []int{-1, 1, 3} {
+    var running, peak int32
+    stages := make([]Stage, 10)
+    for i := range stages {
+      stages[i] = Stage{Name: "stage " + strconv.Itoa(i), Run: func(ctx context.Context) (interface{}, error) {
+        now := atomic.AddInt32(&running, 1)
+        defer atomic.AddInt32(&running, -1)
+        for {
+          old := atomic.LoadInt32(&peak)
+          if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+            break
+          }
+        }
+        time.Sleep(5 * time.Millisecond)
+        return nil, nil
+      }}
+    }
+
+    results := (&Orchestrator{Limit: limit}).Run(context.Background(), stages...)
+    want := int32(limit)
+    if limit < 1 {
+      want = 1
+    }
+    if peak != want {
+      t.Errorf("limit %d: at most %d stages ran at once, want %d", limit, peak, want)
+    }
+    for i, result := range results {
+      if result.Name != stages[i].Name || result.Err != nil {
+        t.Errorf("limit %d: result %d = %+v, want %s without error", limit, i, result, stages[i].Name)
+      }
+    }
+  }
+}
+
+func TestRunCollectsResults(t *testing.T) {
+  failure := errors.New("model unavailable")
+  results := (&Orchestrator{Limit: 4}).Run(context.Background(),
+    Stage{Name: "summarize", Run: func(ctx context.Context) (interface{}, error) { return "a summary", nil }},
+    Stage{Name: "insight", Run: func(ctx context.Context) (interface{}, error) { return nil, failure }},
+    Stage{Name: "tag", Run: func(ctx context.Context) (interface{}, error) { panic("index out of range") }},
+    Stage{Name: "extract", Run: func(ctx context.Context) (interface{}, error) { return 3, nil }},
+  )
+
+  // A failing or panicking stage does not keep the others from completing
+  if results[0].Value != "a summary" || results[0].Err != nil || results[3].Value != 3 || results[3].Err != nil {
+    t.Errorf("results of the stages that succeeded = %+v and %+v", results[0], results[3])
+  }
+  if results[1].Err != failure {
+    t.Errorf("error of the failing stage = %v, want %v", results[1].Err, failure)
+  }
+  if results[2].Err == nil || !strings.Contains(results[2].Err.Error(), "panic: index out of range") {
+    t.Errorf("error of the panicking stage = %v, want the panic", results[2].Err)
+  }
+  for _, result := range results {
+    if result.Duration <= 0 {
+      t.Errorf("stage %s has no duration", result.Name)
+    }
+  }
+  if failed := Failed(results); !reflect.DeepEqual(failed, []string{"insight", "tag"}) {
+    t.Errorf("Failed = %q, want insight and tag", failed)
+  }
+  if failed := Failed(results[:1]); failed != nil {
+    t.Errorf("Failed without failures = %q, want none", failed)
+  }
+}
+
+func TestRunCallbacks(t *testing.T) {
+  var mu sync.Mutex
+  var events []string
+  orchestrator := &Orchestrator{
+    Limit: 1,
+    OnStart: func(name string) {
+      mu.Lock()
+      defer mu.Unlock()
+      events = append(events, "start "+name)
+    },
+    OnFinish: func(result Result) {
+      mu.Lock()
+      defer mu.Unlock()
+      events = append(events, "finish "+result.Name+" "+strconv.FormatBool(result.Err == nil))
+    },
+  }
+  orchestrator.Run(context.Background(),
+    Stage{Name: "a", Run: func(ctx context.Context) (interface{}, error) { return nil, nil }},
+    Stage{Name: "b", Run: func(ctx context.Context) (interface{}, error) { return nil, errors.New("failed") }},
+  )
+
+  want := []string{"start a", "finish a true", "start b", "finish b false"}
+  if !reflect.DeepEqual(events, want) {
+    t.Errorf("callbacks = %q, want %q", events, want)
+  }
+}
+
+func TestRunCancelled(t *testing.T) {
+  ctx, cancel := context.WithCancel(context.Background())
+  started := make(chan struct{})
+  var finished []string
+  var mu sync.Mutex
+  orchestrator := &Orchestrator{
+    Limit: 1,
+    OnFinish: func(result Result) {
+      mu.Lock()
+      defer mu.Unlock()
+      finished = append(finished, result.Name)
+    },
+  }
+
+  // The first stage cancels the context while it holds the only slot, so the others never start
+  var ran int32
+  results := orchestrator.Run(ctx,
+    Stage{Name: "first", Run: func(ctx context.Context) (interface{}, error) {
+      close(started)
+      cancel()
+      <-ctx.Done()
+      return nil, ctx.Err()
+    }},
+    Stage{Name: "second", Run: func(ctx context.Context) (interface{}, error) {
+      atomic.AddInt32(&ran, 1)
+      return nil, nil
+    }},
+    Stage{Name: "third", Run: func(ctx context.Context) (interface{}, error) {
+      atomic.AddInt32(&ran, 1)
+      return nil, nil
+    }},
+  )
+  <-started
+
+  if ran != 0 {
+    t.Errorf("%d stages ran after the context was cancelled", ran)
+  }
+  for _, result := range results {
+    if result.Err != context.Canceled {
+      t.Errorf("stage %s error = %v, want context.Canceled", result.Name, result.Err)
+    }
+  }
+  if len(finished) != 3 {
+    t.Errorf("OnFinish was called for %q, want every stage", finished)
+  }
+}
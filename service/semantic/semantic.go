@@ -0,0 +1,248 @@
+package semantic
+
+import (
+  "context"
+  "fmt"
+  "strings"
+  "sync"
+
+  "voice-notetaking-app/pkg/database/sqlite"
+  "voice-notetaking-app/service/embeddings"
+  "voice-notetaking-app/service/summarization"
+  "voice-notetaking-app/service/vectorindex"
+)
+
+// DefaultChunkTokens is the estimated size of the passages recordings are split into.
+const DefaultChunkTokens = 200
+
+// Result is a passage of a recording matching a semantic search, with its cosine similarity.
+type Result struct {
+  ChunkID     int64   `json:"chunk_id"`
+  RecordingID int64   `json:"recording_id"`
+  Text        string  `json:"text"`
+  StartMs     int64   `json:"start_ms"`
+  EndMs       int64   `json:"end_ms"`
+  Score       float32 `json:"score"`
+}
+
+// Searcher embeds the passages of recordings and finds the ones closest in meaning to a query.
+// Each user's vectors are loaded from the database into an exact and an approximate index the
+// first time they search.
+type Searcher struct {
+  Embedder    embeddings.Embedder
+  ChunkTokens int
+
+  mu    sync.Mutex
+  users map[int64]*userIndex
+}
+
+// userIndex holds the vectors of a single user in both kinds of index.
+type userIndex struct {
+  mu     sync.RWMutex
+  exact  vectorindex.Index
+  approx vectorindex.Index
+  chunks map[int64]sqlite.RecordingChunk
+}
+
+// New creates a searcher using the embedder.
+func New(embedder embeddings.Embedder) *Searcher {
+  return &Searcher{
+    Embedder:    embedder,
+    ChunkTokens: DefaultChunkTokens,
+    users:       make(map[int64]*userIndex),
+  }
+}
+
+// IndexRecording splits a recording into passages, embeds and stores them, replacing the passages
+// stored before. Passages follow the transcript segments so they keep their timing; without
+// segments the text is chunked instead. It returns the number of passages stored.
+func (s *Searcher) IndexRecording(ctx context.Context, userID, recordingID int64, segments []sqlite.Segment, text string) (int, error) {
+  chunks := s.chunk(segments, text)
+  if len(chunks) == 0 {
+    return 0, nil
+  }
+
+  texts := make([]string, len(chunks))
+  for i, chunk := range chunks {
+    texts[i] = chunk.Text
+  }
+  vectors, err := s.Embedder.Embed(ctx, texts)
+  if err != nil {
+    return 0, err
+  }
+  if len(vectors) != len(chunks) {
+    return 0, fmt.Errorf("got %d embeddings for %d passages", len(vectors), len(chunks))
+  }
+  for i := range chunks {
+    chunks[i].Embedding = vectors[i]
+  }
+
+  stored, err := sqlite.ReplaceRecordingChunks(userID, recordingID, s.Embedder.Model(), chunks)
+  if err != nil {
+    return 0, fmt.Errorf("failed to store passages: %v", err)
+  }
+
+  // Update the user's indexes if they are already loaded; otherwise they are loaded with these passages.
+  // Holding the lock means an index being loaded concurrently is updated once it is ready.
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  if index, ok := s.users[userID]; ok {
+    if err := index.replace(recordingID, stored); err != nil {
+      return 0, err
+    }
+  }
+
+  return len(stored), nil
+}
+
+// Search returns up to k passages of the user's recordings closest in meaning to the query.
+// With exact set every passage is compared; otherwise the approximate index is used.
+func (s *Searcher) Search(ctx context.Context, userID int64, query string, k int, exact bool) ([]Result, error) {
+  index, err := s.userIndex(userID)
+  if err != nil {
+    return nil, err
+  }
+
+  vectors, err := s.Embedder.Embed(ctx, []string{query})
+  if err != nil {
+    return nil, err
+  }
+  if len(vectors) != 1 {
+    return nil, fmt.Errorf("got %d embeddings for the query", len(vectors))
+  }
+
+  return index.search(vectors[0], k, exact), nil
+}
+
+// userIndex returns the indexes of the user, loading their vectors from the database if needed.
+func (s *Searcher) userIndex(userID int64) (*userIndex, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if index, ok := s.users[userID]; ok {
+    return index, nil
+  }
+
+  chunks, err := sqlite.GetRecordingChunksByUserID(userID, s.Embedder.Model())
+  if err != nil {
+    return nil, fmt.Errorf("failed to load passages: %v", err)
+  }
+
+  index := &userIndex{
+    exact:  vectorindex.NewBruteForce(),
+    approx: vectorindex.NewHNSW(0, 0, 0),
+    chunks: make(map[int64]sqlite.RecordingChunk, len(chunks)),
+  }
+  for _, chunk := range chunks {
+    if err := index.add(chunk); err != nil {
+      return nil, err
+    }
+  }
+  s.users[userID] = index
+
+  return index, nil
+}
+
+// chunk groups transcript segments into passages of about ChunkTokens, or chunks the text when
+// there are no segments.
+func (s *Searcher) chunk(segments []sqlite.Segment, text string) []sqlite.RecordingChunk {
+  maxTokens := s.ChunkTokens
+  if maxTokens <= 0 {
+    maxTokens = DefaultChunkTokens
+  }
+
+  var chunks []sqlite.RecordingChunk
+  if len(segments) == 0 {
+    for _, part := range summarization.Chunk(text, maxTokens, 0) {
+      chunks = append(chunks, sqlite.RecordingChunk{Text: part})
+    }
+    return chunks
+  }
+
+  var current []string
+  var start, end int64
+  tokens := 0
+  flush := func() {
+    if len(current) > 0 {
+      chunks = append(chunks, sqlite.RecordingChunk{Text: strings.Join(current, " "), StartMs: start, EndMs: end})
+    }
+    current, tokens = nil, 0
+  }
+  for _, segment := range segments {
+    segmentTokens := summarization.EstimateTokens(segment.Text)
+    if tokens+segmentTokens > maxTokens {
+      flush()
+    }
+    if len(current) == 0 {
+      start = segment.StartMs
+    }
+    current = append(current, segment.Text)
+    end = segment.EndMs
+    tokens += segmentTokens
+  }
+  flush()
+
+  return chunks
+}
+
+// add inserts a passage into both indexes.
+func (u *userIndex) add(chunk sqlite.RecordingChunk) error {
+  if err := u.exact.Add(chunk.ID, chunk.Embedding); err != nil {
+    return err
+  }
+  if err := u.approx.Add(chunk.ID, chunk.Embedding); err != nil {
+    return err
+  }
+  chunk.Embedding = nil
+  u.chunks[chunk.ID] = chunk
+  return nil
+}
+
+// replace swaps the passages of a recording for the ones given.
+func (u *userIndex) replace(recordingID int64, chunks []sqlite.RecordingChunk) error {
+  u.mu.Lock()
+  defer u.mu.Unlock()
+
+  for id, chunk := range u.chunks {
+    if chunk.RecordingID == recordingID {
+      u.exact.Remove(id)
+      u.approx.Remove(id)
+      delete(u.chunks, id)
+    }
+  }
+  for _, chunk := range chunks {
+    if err := u.add(chunk); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// search finds the passages closest to the query vector.
+func (u *userIndex) search(query []float32, k int, exact bool) []Result {
+  u.mu.RLock()
+  defer u.mu.RUnlock()
+
+  index := u.approx
+  if exact {
+    index = u.exact
+  }
+
+  matches := index.Search(query, k)
+  results := make([]Result, 0, len(matches))
+  for _, match := range matches {
+    chunk, ok := u.chunks[match.ID]
+    if !ok {
+      continue
+    }
+    results = append(results, Result{
+      ChunkID:     chunk.ID,
+      RecordingID: chunk.RecordingID,
+      Text:        chunk.Text,
+      StartMs:     chunk.StartMs,
+      EndMs:       chunk.EndMs,
+      Score:       match.Score,
+    })
+  }
+  return results
+}
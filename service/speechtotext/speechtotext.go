@@ -0,0 +1,268 @@
+package speechtotext
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "fmt"
+  "io"
+  "net/http"
+  "strings"
+  "time"
+
+  "voice-notetaking-app/config"
+)
+
+// Transcriber converts recorded audio into text.
+type Transcriber interface {
+  Transcribe(ctx context.Context, audio []byte) (*Transcript, error)
+}
+
+// Word is a single recognised word. Start and End are offsets into the audio in milliseconds.
+type Word struct {
+  Text       string  `json:"text"`
+  Start      int64   `json:"start"`
+  End        int64   `json:"end"`
+  Confidence float64 `json:"confidence"`
+  Speaker    string  `json:"speaker,omitempty"`
+}
+
+// Segment is a sentence of the transcript together with the words it is made of.
+type Segment struct {
+  Text       string  `json:"text"`
+  Start      int64   `json:"start"`
+  End        int64   `json:"end"`
+  Confidence float64 `json:"confidence"`
+  Speaker    string  `json:"speaker,omitempty"`
+  Words      []Word  `json:"words"`
+}
+
+// Utterance is an uninterrupted stretch of speech by a single speaker.
+type Utterance struct {
+  Speaker    string  `json:"speaker"`
+  Text       string  `json:"text"`
+  Start      int64   `json:"start"`
+  End        int64   `json:"end"`
+  Confidence float64 `json:"confidence"`
+  Words      []Word  `json:"words"`
+}
+
+// Transcript is the structured result of a transcription.
+type Transcript struct {
+  ID         string      `json:"id"`
+  Text       string      `json:"text"`
+  Confidence float64     `json:"confidence"`
+  Duration   int64       `json:"duration"`
+  Segments   []Segment   `json:"segments"`
+  Words      []Word      `json:"words"`
+  Utterances []Utterance `json:"utterances,omitempty"`
+}
+
+// SpeakerAttributed returns the transcript as one "Speaker X: ..." line per utterance,
+// or the plain text when no speakers were detected.
+func (t *Transcript) SpeakerAttributed() string {
+  if len(t.Utterances) == 0 {
+    return t.Text
+  }
+
+  var b strings.Builder
+  for _, utterance := range t.Utterances {
+    fmt.Fprintf(&b, "Speaker %s: %s\n", utterance.Speaker, utterance.Text)
+  }
+  return strings.TrimRight(b.String(), "\n")
+}
+
+// DefaultPollInterval is how long AssemblyAI waits between transcript status checks.
+const DefaultPollInterval = 3 * time.Second
+
+// AssemblyAI transcribes audio using the AssemblyAI REST API.
+type AssemblyAI struct {
+  APIKey       string
+  BaseURL      string
+  HTTPClient   *http.Client
+  PollInterval time.Duration
+
+  // SpeakerLabels enables speaker diarization, filling in Transcript.Utterances.
+  SpeakerLabels bool
+}
+
+// NewAssemblyAI creates an AssemblyAI transcriber talking to the given base URL.
+func NewAssemblyAI(apiKey, baseURL string) *AssemblyAI {
+  return &AssemblyAI{
+    APIKey:        apiKey,
+    BaseURL:       strings.TrimRight(baseURL, "/"),
+    HTTPClient:    http.DefaultClient,
+    PollInterval:  DefaultPollInterval,
+    SpeakerLabels: true,
+  }
+}
+
+// UploadToAssemblyAI transcribes audio with the AssemblyAI settings from the environment.
+func UploadToAssemblyAI(audio []byte) (*Transcript, error) {
+  cfg := config.Load()
+  return NewAssemblyAI(cfg.AssemblyAIKey, cfg.AssemblyAIBaseURL).Transcribe(context.Background(), audio)
+}
+
+// Transcribe uploads the audio, creates a transcript and polls until it is completed.
+func (a *AssemblyAI) Transcribe(ctx context.Context, audio []byte) (*Transcript, error) {
+  uploadURL, err := a.upload(ctx, audio)
+  if err != nil {
+    return nil, err
+  }
+
+  transcriptID, err := a.createTranscript(ctx, uploadURL)
+  if err != nil {
+    return nil, err
+  }
+
+  transcript, err := a.waitForTranscript(ctx, transcriptID)
+  if err != nil {
+    return nil, err
+  }
+
+  transcript.Segments, err = a.sentences(ctx, transcriptID)
+  if err != nil {
+    return nil, err
+  }
+
+  return transcript, nil
+}
+
+// upload sends the raw audio to AssemblyAI and returns the URL it was stored at.
+func (a *AssemblyAI) upload(ctx context.Context, audio []byte) (string, error) {
+  var response struct {
+    UploadURL string `json:"upload_url"`
+  }
+  err := a.do(ctx, http.MethodPost, "/v2/upload", "application/octet-stream", bytes.NewReader(audio), &response)
+  if err != nil {
+    return "", fmt.Errorf("failed to upload audio: %v", err)
+  }
+  if response.UploadURL == "" {
+    return "", fmt.Errorf("failed to upload audio: upload_url missing in response")
+  }
+
+  return response.UploadURL, nil
+}
+
+// createTranscript requests a transcript for previously uploaded audio and returns its ID.
+func (a *AssemblyAI) createTranscript(ctx context.Context, uploadURL string) (string, error) {
+  request := map[string]interface{}{
+    "audio_url":      uploadURL,
+    "language_code":  "en_us",
+    "speaker_labels": a.SpeakerLabels,
+  }
+  body, err := json.Marshal(request)
+  if err != nil {
+    return "", fmt.Errorf("failed to marshal transcript request: %v", err)
+  }
+
+  var response struct {
+    ID string `json:"id"`
+  }
+  err = a.do(ctx, http.MethodPost, "/v2/transcript", "application/json", bytes.NewReader(body), &response)
+  if err != nil {
+    return "", fmt.Errorf("failed to create transcript: %v", err)
+  }
+  if response.ID == "" {
+    return "", fmt.Errorf("failed to create transcript: id missing in response")
+  }
+
+  return response.ID, nil
+}
+
+// waitForTranscript polls the transcript until it completes, fails or the context is done.
+func (a *AssemblyAI) waitForTranscript(ctx context.Context, transcriptID string) (*Transcript, error) {
+  ticker := time.NewTicker(a.pollInterval())
+  defer ticker.Stop()
+
+  for {
+    var response struct {
+      Status     string      `json:"status"`
+      Text       string      `json:"text"`
+      Confidence float64     `json:"confidence"`
+      Duration   float64     `json:"audio_duration"`
+      Words      []Word      `json:"words"`
+      Utterances []Utterance `json:"utterances"`
+      Error      string      `json:"error"`
+    }
+    err := a.do(ctx, http.MethodGet, "/v2/transcript/"+transcriptID, "", nil, &response)
+    if err != nil {
+      return nil, fmt.Errorf("failed to get transcript: %v", err)
+    }
+
+    switch response.Status {
+    case "completed":
+      return &Transcript{
+        ID:         transcriptID,
+        Text:       response.Text,
+        Confidence: response.Confidence,
+        Duration:   int64(response.Duration * 1000),
+        Words:      response.Words,
+        Utterances: response.Utterances,
+      }, nil
+    case "error":
+      return nil, fmt.Errorf("transcription failed: %s", response.Error)
+    }
+
+    select {
+    case <-ctx.Done():
+      return nil, ctx.Err()
+    case <-ticker.C:
+    }
+  }
+}
+
+// sentences fetches the completed transcript split into sentences with word timings.
+func (a *AssemblyAI) sentences(ctx context.Context, transcriptID string) ([]Segment, error) {
+  var response struct {
+    Sentences []Segment `json:"sentences"`
+  }
+  err := a.do(ctx, http.MethodGet, "/v2/transcript/"+transcriptID+"/sentences", "", nil, &response)
+  if err != nil {
+    return nil, fmt.Errorf("failed to get transcript sentences: %v", err)
+  }
+
+  return response.Sentences, nil
+}
+
+// do performs an authenticated request against the API and decodes the JSON response into out.
+func (a *AssemblyAI) do(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) error {
+  req, err := http.NewRequestWithContext(ctx, method, a.BaseURL+path, body)
+  if err != nil {
+    return err
+  }
+  req.Header.Set("Authorization", a.APIKey)
+  if contentType != "" {
+    req.Header.Set("Content-Type", contentType)
+  }
+
+  client := a.HTTPClient
+  if client == nil {
+    client = http.DefaultClient
+  }
+
+  resp, err := client.Do(req)
+  if err != nil {
+    return err
+  }
+  defer resp.Body.Close()
+
+  if resp.StatusCode != http.StatusOK {
+    message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+    return fmt.Errorf("unexpected response status code: %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+  }
+
+  if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+    return fmt.Errorf("failed to decode response JSON: %v", err)
+  }
+
+  return nil
+}
+
+// pollInterval returns the configured poll interval or the default.
+func (a *AssemblyAI) pollInterval() time.Duration {
+  if a.PollInterval <= 0 {
+    return DefaultPollInterval
+  }
+  return a.PollInterval
+}
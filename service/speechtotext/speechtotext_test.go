@@ -0,0 +1,182 @@
+package speechtotext
+
+import (
+  "context"
+  "encoding/json"
+  "io"
+  "net/http"
+  "net/http/httptest"
+  "strings"
+  "sync"
+  "testing"
+  "time"
+)
+
+// fakeAssemblyAI serves the AssemblyAI endpoints used by the transcriber. The transcript reports
+// each of statuses in turn on every poll, staying on the last one.
+type fakeAssemblyAI struct {
+  t        *testing.T
+  statuses []string
+
+  mu    sync.Mutex
+  polls int
+  audio string
+  // request is the body of the transcript request.
+  request map[string]interface{}
+}
+
+func (f *fakeAssemblyAI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  if r.Header.Get("Authorization") != "test-key" {
+    http.Error(w, "invalid API key", http.StatusUnauthorized)
+    return
+  }
+
+  f.mu.Lock()
+  defer f.mu.Unlock()
+  switch {
+  case r.Method == http.MethodPost && r.URL.Path == "/v2/upload":
+    body, _ := io.ReadAll(r.Body)
+    f.audio = string(body)
+    json.NewEncoder(w).Encode(map[string]string{"upload_url": "https://cdn.example.com/audio-1"})
+  case r.Method == http.MethodPost && r.URL.Path == "/v2/transcript":
+    if err := json.NewDecoder(r.Body).Decode(&f.request); err != nil {
+      http.Error(w, err.Error(), http.StatusBadRequest)
+      return
+    }
+    json.NewEncoder(w).Encode(map[string]string{"id": "tr-1", "status": "queued"})
+  case r.Method == http.MethodGet && r.URL.Path == "/v2/transcript/tr-1":
+    status := f.statuses[len(f.statuses)-1]
+    if f.polls < len(f.statuses) {
+      status = f.statuses[f.polls]
+    }
+    f.polls++
+    response := map[string]interface{}{"id": "tr-1", "status": status}
+    switch status {
+    case "completed":
+      response["text"] = "Hello there. General Kenobi."
+      response["confidence"] = 0.93
+      response["audio_duration"] = 2.5
+      response["words"] = []Word{{Text: "Hello", Start: 0, End: 400, Confidence: 0.9, Speaker: "A"}}
+      response["utterances"] = []Utterance{
+        {Speaker: "A", Text: "Hello there.", Start: 0, End: 900},
+        {Speaker: "B", Text: "General Kenobi.", Start: 1000, End: 2400},
+      }
+    case "error":
+      response["error"] = "audio is too short"
+    }
+    json.NewEncoder(w).Encode(response)
+  case r.Method == http.MethodGet && r.URL.Path == "/v2/transcript/tr-1/sentences":
+    json.NewEncoder(w).Encode(map[string]interface{}{"sentences": []Segment{
+      {Text: "Hello there.", Start: 0, End: 900, Speaker: "A"},
+      {Text: "General Kenobi.", Start: 1000, End: 2400, Speaker: "B"},
+    }})
+  default:
+    f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+    http.NotFound(w, r)
+  }
+}
+
+func (f *fakeAssemblyAI) pollCount() int {
+  f.mu.Lock()
+  defer f.mu.Unlock()
+  return f.polls
+}
+
+// newTestTranscriber starts a fake AssemblyAI server and returns a transcriber using it.
+func newTestTranscriber(t *testing.T, statuses ...string) (*AssemblyAI, *fakeAssemblyAI) {
+  fake := &fakeAssemblyAI{t: t, statuses: statuses}
+  server := httptest.NewServer(fake)
+  t.Cleanup(server.Close)
+
+  transcriber := NewAssemblyAI("test-key", server.URL+"/")
+  transcriber.PollInterval = time.Millisecond
+  return transcriber, fake
+}
+
+func TestAssemblyAITranscribe(t *testing.T) {
+  transcriber, fake := newTestTranscriber(t, "queued", "processing", "completed")
+
+  transcript, err := transcriber.Transcribe(context.Background(), []byte("fake audio"))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if fake.audio != "fake audio" {
+    t.Errorf("uploaded audio = %q, want %q", fake.audio, "fake audio")
+  }
+  if fake.request["audio_url"] != "https://cdn.example.com/audio-1" || fake.request["speaker_labels"] != true {
+    t.Errorf("transcript request = %v, want the upload URL with speaker labels", fake.request)
+  }
+  if polls := fake.pollCount(); polls != 3 {
+    t.Errorf("polled %d times, want 3", polls)
+  }
+
+  if transcript.ID != "tr-1" || transcript.Text != "Hello there. General Kenobi." || transcript.Confidence != 0.93 {
+    t.Errorf("transcript = %+v", transcript)
+  }
+  if transcript.Duration != 2500 {
+    t.Errorf("duration = %d ms, want 2500", transcript.Duration)
+  }
+  if len(transcript.Words) != 1 || len(transcript.Segments) != 2 || len(transcript.Utterances) != 2 {
+    t.Errorf("got %d words, %d segments and %d utterances, want 1, 2 and 2",
+      len(transcript.Words), len(transcript.Segments), len(transcript.Utterances))
+  }
+  if want := "Speaker A: Hello there.\nSpeaker B: General Kenobi."; transcript.SpeakerAttributed() != want {
+    t.Errorf("speaker attributed text = %q, want %q", transcript.SpeakerAttributed(), want)
+  }
+}
+
+func TestAssemblyAITranscribeFailed(t *testing.T) {
+  transcriber, _ := newTestTranscriber(t, "processing", "error")
+
+  _, err := transcriber.Transcribe(context.Background(), []byte("fake audio"))
+  if err == nil || !strings.Contains(err.Error(), "audio is too short") {
+    t.Fatalf("error = %v, want the transcription error", err)
+  }
+}
+
+func TestAssemblyAITranscribeUnauthorized(t *testing.T) {
+  transcriber, _ := newTestTranscriber(t, "completed")
+  transcriber.APIKey = "wrong-key"
+
+  _, err := transcriber.Transcribe(context.Background(), []byte("fake audio"))
+  if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "failed to upload audio") {
+    t.Fatalf("error = %v, want a failed upload with status 401", err)
+  }
+}
+
+func TestAssemblyAITranscribeCancel(t *testing.T) {
+  transcriber, fake := newTestTranscriber(t, "processing")
+  transcriber.PollInterval = 20 * time.Millisecond
+
+  ctx, cancel := context.WithCancel(context.Background())
+  go func() {
+    for fake.pollCount() < 2 {
+      time.Sleep(time.Millisecond)
+    }
+    cancel()
+  }()
+
+  done := make(chan error, 1)
+  go func() {
+    _, err := transcriber.Transcribe(ctx, []byte("fake audio"))
+    done <- err
+  }()
+
+  select {
+  case err := <-done:
+    // The context may be canceled while waiting to poll again or during a request
+    if err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+      t.Fatalf("error = %v, want context.Canceled", err)
+    }
+  case <-time.After(5 * time.Second):
+    t.Fatal("Transcribe kept polling after its context was canceled")
+  }
+
+  // No more polls are made once Transcribe has returned
+  polls := fake.pollCount()
+  time.Sleep(100 * time.Millisecond)
+  if fake.pollCount() != polls {
+    t.Errorf("polled %d more times after cancellation", fake.pollCount()-polls)
+  }
+}
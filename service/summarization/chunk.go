@@ -0,0 +1,156 @@
+package summarization
+
+import (
+  "strings"
+  "unicode/utf8"
+)
+
+// charsPerToken approximates how many characters of English text make up one model token.
+const charsPerToken = 4
+
+// EstimateTokens approximates the number of model tokens in text without a tokenizer,
+// taking the larger of a character based and a word based estimate.
+func EstimateTokens(text string) int {
+  byChars := (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+  byWords := len(strings.Fields(text)) * 4 / 3
+  if byWords > byChars {
+    return byWords
+  }
+  return byChars
+}
+
+// Chunk splits text into chunks of at most maxTokens estimated tokens, breaking between
+// sentences or speaker lines where possible. Each chunk after the first starts with the last
+// sentences of the previous one, up to overlapTokens.
+func Chunk(text string, maxTokens, overlapTokens int) []string {
+  if maxTokens < 1 {
+    maxTokens = 1
+  }
+
+  var pieces []sentence
+  for _, s := range splitSentences(text) {
+    for i, text := range splitLong(s.text, maxTokens) {
+      pieces = append(pieces, sentence{text: text, lineStart: s.lineStart && i == 0})
+    }
+  }
+
+  var chunks []string
+  var current []sentence
+  currentTokens := 0
+  for _, piece := range pieces {
+    tokens := EstimateTokens(piece.text)
+    if currentTokens+tokens > maxTokens && len(current) > 0 {
+      chunks = append(chunks, join(current))
+      current = overlap(current, overlapTokens, maxTokens-tokens)
+      currentTokens = EstimateTokens(join(current))
+    }
+    current = append(current, piece)
+    currentTokens += tokens
+  }
+  if len(current) > 0 {
+    chunks = append(chunks, join(current))
+  }
+
+  return chunks
+}
+
+// sentence is a piece of text to chunk, remembering whether it started a line of the input.
+type sentence struct {
+  text      string
+  lineStart bool
+}
+
+// join joins sentences back into text, restoring the line breaks between them.
+func join(sentences []sentence) string {
+  var b strings.Builder
+  for i, s := range sentences {
+    if i > 0 {
+      if s.lineStart {
+        b.WriteString("\n")
+      } else {
+        b.WriteString(" ")
+      }
+    }
+    b.WriteString(s.text)
+  }
+  return b.String()
+}
+
+// overlap returns the trailing sentences of a chunk that fit in overlapTokens and,
+// together with the next sentence, in the remaining room of the chunk budget.
+func overlap(sentences []sentence, overlapTokens, room int) []sentence {
+  if overlapTokens > room {
+    overlapTokens = room
+  }
+
+  tokens := 0
+  start := len(sentences)
+  for start > 0 {
+    next := EstimateTokens(sentences[start-1].text)
+    if tokens+next > overlapTokens {
+      break
+    }
+    tokens += next
+    start--
+  }
+
+  carried := make([]sentence, len(sentences)-start)
+  copy(carried, sentences[start:])
+  return carried
+}
+
+// splitSentences splits text into lines, then each line into sentences ending in ., ! or ?.
+// Speaker labels stay with the first sentence of their line.
+func splitSentences(text string) []sentence {
+  var sentences []sentence
+  for _, line := range strings.Split(text, "\n") {
+    line = strings.TrimSpace(line)
+    if line == "" {
+      continue
+    }
+
+    start := 0
+    lineStart := true
+    for i, r := range line {
+      if r != '.' && r != '!' && r != '?' {
+        continue
+      }
+      end := i + utf8.RuneLen(r)
+      if end < len(line) && line[end] != ' ' {
+        continue
+      }
+      if text := strings.TrimSpace(line[start:end]); text != "" {
+        sentences = append(sentences, sentence{text: text, lineStart: lineStart})
+        lineStart = false
+      }
+      start = end
+    }
+    if text := strings.TrimSpace(line[start:]); text != "" {
+      sentences = append(sentences, sentence{text: text, lineStart: lineStart})
+    }
+  }
+  return sentences
+}
+
+// splitLong breaks a sentence that exceeds maxTokens on its own into pieces of whole words.
+func splitLong(sentence string, maxTokens int) []string {
+  if EstimateTokens(sentence) <= maxTokens {
+    return []string{sentence}
+  }
+
+  var pieces []string
+  var current []string
+  for _, word := range strings.Fields(sentence) {
+    candidate := append(current, word)
+    if len(current) > 0 && EstimateTokens(strings.Join(candidate, " ")) > maxTokens {
+      pieces = append(pieces, strings.Join(current, " "))
+      current = []string{word}
+      continue
+    }
+    current = candidate
+  }
+  if len(current) > 0 {
+    pieces = append(pieces, strings.Join(current, " "))
+  }
+  return pieces
+}
@@ -0,0 +1,122 @@
+package summarization
+
+import (
+  "fmt"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+// transcript returns a speaker attributed transcript of n lines of two sentences each.
+func transcript(n int) string {
+  var lines []string
+  for i := 1; i <= n; i++ {
+    speaker := "A"
+    if i%2 == 0 {
+      speaker = "B"
+    }
+    lines = append(lines, fmt.Sprintf("Speaker %s: We talked about item number %d today. It needs a follow up by Friday!", speaker, i))
+  }
+  return strings.Join(lines, "\n")
+}
+
+func TestEstimateTokens(t *testing.T) {
+  tests := []struct {
+    text string
+    want int
+  }{
+    {"", 0},
+    {"abcd", 1},
+    {"abcde", 2},
+    // Many short words count more than their characters suggest
+    {"a b c d e f", 8},
+    {"héllo", 2},
+  }
+  for _, tt := range tests {
+    if got := EstimateTokens(tt.text); got != tt.want {
+      t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+    }
+  }
+}
+
+func TestChunkShortText(t *testing.T) {
+  if chunks := Chunk("", 100, 10); len(chunks) != 0 {
+    t.Errorf("Chunk of empty text = %q, want no chunks", chunks)
+  }
+
+  text := "Speaker A: Hello there.\nSpeaker B: Hi! How are you?"
+  if chunks := Chunk(text, 100, 10); !reflect.DeepEqual(chunks, []string{text}) {
+    t.Errorf("Chunk = %q, want the text as a single chunk", chunks)
+  }
+}
+
+func TestChunkRespectsBudget(t *testing.T) {
+  text := transcript(60)
+  for _, maxTokens := range []int{20, 50, 200} {
+    chunks := Chunk(text, maxTokens, maxTokens/4)
+    if len(chunks) < 2 {
+      t.Fatalf("maxTokens %d: got %d chunks, want several", maxTokens, len(chunks))
+    }
+    for i, chunk := range chunks {
+      if tokens := EstimateTokens(chunk); tokens > maxTokens {
+        t.Errorf("maxTokens %d: chunk %d has %d tokens", maxTokens, i, tokens)
+      }
+    }
+  }
+}
+
+func TestChunkBreaksBetweenSentences(t *testing.T) {
+  text := transcript(20)
+  chunks := Chunk(text, 60, 0)
+
+  // Without overlap the chunks put back together are the original text
+  var b strings.Builder
+  for i, chunk := range chunks {
+    if !strings.HasSuffix(chunk, ".") && !strings.HasSuffix(chunk, "!") {
+      t.Errorf("chunk %d does not end a sentence: %q", i, chunk)
+    }
+    if i > 0 {
+      if strings.HasPrefix(chunk, "Speaker") {
+        b.WriteString("\n")
+      } else {
+        b.WriteString(" ")
+      }
+    }
+    b.WriteString(chunk)
+  }
+  if b.String() != text {
+    t.Errorf("chunks joined back = %q, want the original text", b.String())
+  }
+}
+
+func TestChunkOverlap(t *testing.T) {
+  chunks := Chunk(transcript(20), 60, 15)
+  for i := 1; i < len(chunks); i++ {
+    previous := splitSentences(chunks[i-1])
+    last := previous[len(previous)-1].text
+    if !strings.HasPrefix(chunks[i], last) {
+      t.Errorf("chunk %d does not start with the last sentence of chunk %d, %q: %q", i, i-1, last, chunks[i])
+    }
+  }
+}
+
+func TestChunkSplitsLongSentences(t *testing.T) {
+  words := make([]string, 200)
+  for i := range words {
+    words[i] = fmt.Sprintf("word%d", i)
+  }
+  text := strings.Join(words, " ")
+
+  chunks := Chunk(text, 30, 0)
+  if len(chunks) < 2 {
+    t.Fatalf("got %d chunks, want the sentence split", len(chunks))
+  }
+  for i, chunk := range chunks {
+    if tokens := EstimateTokens(chunk); tokens > 30 {
+      t.Errorf("chunk %d has %d tokens", i, tokens)
+    }
+  }
+  if joined := strings.Join(chunks, " "); joined != text {
+    t.Errorf("chunks lost words: %q", joined)
+  }
+}
@@ -2,45 +2,174 @@ package summarization
 
 import (
   "context"
-  "log"
-  "github.com/sashabaranov/go-openai"
+  "fmt"
+  "strings"
+
+  "voice-notetaking-app/service/llm"
 )
 
+// DefaultChunkTokens is the chunk budget for models without an entry in ModelChunkTokens.
+const DefaultChunkTokens = 3000
+
+// DefaultOverlapTokens is how much of the end of a chunk is repeated at the start of the next,
+// so statements that straddle a boundary keep their context.
+const DefaultOverlapTokens = 200
+
+// maxReduceDepth bounds how many times partial summaries are summarized again.
+const maxReduceDepth = 4
+
+// ModelChunkTokens is the number of transcript tokens sent in a single request, per model.
+// The budgets leave room in the context window for the prompt and the generated summary.
+var ModelChunkTokens = map[string]int{
+  "gpt-3.5-turbo":     12000,
+  "gpt-3.5-turbo-16k": 12000,
+  "gpt-4":             6000,
+  "gpt-4-32k":         24000,
+  "gpt-4-turbo":       96000,
+  "gpt-4o":            96000,
+  "gpt-4o-mini":       96000,
+}
+
+// Summarizer summarizes transcriptions with a language model. Transcriptions that do not fit
+// in a single request are split into overlapping chunks that are summarized separately (map)
+// and then combined into one summary (reduce).
+type Summarizer struct {
+  LLM llm.Client
+
+  // Model selects the chunk budget from ModelChunkTokens. It is also sent with each request when set.
+  Model string
+
+  // ChunkTokens overrides the chunk budget of the model when greater than zero.
+  ChunkTokens int
+
+  // OverlapTokens is how many tokens consecutive chunks share. Zero uses DefaultOverlapTokens.
+  OverlapTokens int
+}
+
+// New creates a summarizer using the given language model client.
+func New(client llm.Client) *Summarizer {
+  return &Summarizer{LLM: client}
+}
 
-// SummarizeText summarizes text using OpenAI API.
-func SummarizeText(text string) (string, error) {
+// SummarizeText summarizes text. The text may be speaker-attributed,
+// with one "Speaker X: ..." line per utterance.
+func (s *Summarizer) SummarizeText(ctx context.Context, text string) (string, error) {
   systemPrompt := `
     You are an AI assistant tasked with summarizing a transcription. 
     Give a concise summary of the provided transcription.
+    If lines are prefixed with a speaker label such as "Speaker A:", attribute
+    commitments, decisions and opinions to the speaker who made them.
   `
 
-  client := openai.NewClient("your_token_here")
+  budget := s.chunkTokens()
+  if EstimateTokens(text) <= budget {
+    return s.chat(ctx, systemPrompt, text)
+  }
+
+  // Map: summarize each chunk of the transcription on its own
+  chunks := Chunk(text, budget, s.overlapTokens())
+  summaries := make([]string, 0, len(chunks))
+  for i, chunk := range chunks {
+    chunkPrompt := fmt.Sprintf(`
+    You are an AI assistant tasked with summarizing part %d of %d of a long transcription.
+    Summarize this part concisely, keeping every decision, commitment, name, number and date.
+    If lines are prefixed with a speaker label such as "Speaker A:", attribute
+    commitments, decisions and opinions to the speaker who made them.
+  `, i+1, len(chunks))
+
+    summary, err := s.chat(ctx, chunkPrompt, chunk)
+    if err != nil {
+      return "", fmt.Errorf("failed to summarize chunk %d of %d: %v", i+1, len(chunks), err)
+    }
+    summaries = append(summaries, summary)
+  }
+
+  // Reduce: combine the partial summaries into one
+  return s.reduce(ctx, summaries, budget, 0)
+}
+
+// reduce combines partial summaries into a single summary. When the partial summaries are
+// themselves too long for one request they are grouped and reduced again.
+func (s *Summarizer) reduce(ctx context.Context, summaries []string, budget, depth int) (string, error) {
+  reducePrompt := `
+    You are an AI assistant tasked with combining partial summaries of one long transcription,
+    given in order, into a single concise summary. Remove repetition between the parts and
+    keep every decision, commitment, name, number and date, with the speaker it belongs to.
+  `
 
+  combined := joinSummaries(summaries)
+  if EstimateTokens(combined) <= budget || depth >= maxReduceDepth || len(summaries) < 2 {
+    return s.chat(ctx, reducePrompt, combined)
+  }
+
+  groups := Chunk(combined, budget, 0)
+  if len(groups) >= len(summaries) {
+    // Grouping would not shrink the input, so reduce what fits in one request
+    return s.chat(ctx, reducePrompt, combined)
+  }
+
+  reduced := make([]string, 0, len(groups))
+  for i, group := range groups {
+    summary, err := s.chat(ctx, reducePrompt, group)
+    if err != nil {
+      return "", fmt.Errorf("failed to combine summaries %d of %d: %v", i+1, len(groups), err)
+    }
+    reduced = append(reduced, summary)
+  }
+
+  return s.reduce(ctx, reduced, budget, depth+1)
+}
+
+// chat sends a single system prompt and user message to the model.
+func (s *Summarizer) chat(ctx context.Context, systemPrompt, text string) (string, error) {
   // Prepare messages
-  messages := []openai.ChatCompletionMessage{
+  messages := []llm.Message{
     {
-      Role:    openai.ChatMessageRoleSystem,
+      Role:    llm.RoleSystem,
       Content: systemPrompt,
     },
     {
-      Role:    openai.ChatMessageRoleUser,
+      Role:    llm.RoleUser,
       Content: text,
     },
   }
 
-  // Create Chat completion request
-  resp, err := client.CreateChatCompletion(
-    context.Background(),
-    openai.ChatCompletionRequest{
-      Model:    openai.GPT3Dot5Turbo,
-      Messages: messages,
-    },
-  )
+  var opts []llm.Option
+  if s.Model != "" {
+    opts = append(opts, llm.WithModel(s.Model))
+  }
 
-  if err != nil {
-    log.Printf("ChatCompletion error: %v\n", err)
-    return "", err
+  return s.LLM.Chat(ctx, messages, opts...)
+}
+
+// chunkTokens returns the chunk budget for the configured model.
+func (s *Summarizer) chunkTokens() int {
+  if s.ChunkTokens > 0 {
+    return s.ChunkTokens
+  }
+  if budget, ok := ModelChunkTokens[s.Model]; ok {
+    return budget
   }
+  return DefaultChunkTokens
+}
 
-  return resp.Choices[0].Message.Content, nil
+// overlapTokens returns the configured overlap, never more than half the chunk budget.
+func (s *Summarizer) overlapTokens() int {
+  overlap := s.OverlapTokens
+  if overlap <= 0 {
+    overlap = DefaultOverlapTokens
+  }
+  if budget := s.chunkTokens(); overlap > budget/2 {
+    overlap = budget / 2
+  }
+  return overlap
+}
+
+// joinSummaries numbers partial summaries so the model keeps their order.
+func joinSummaries(summaries []string) string {
+  parts := make([]string, len(summaries))
+  for i, summary := range summaries {
+    parts[i] = fmt.Sprintf("Part %d:\n%s", i+1, strings.TrimSpace(summary))
+  }
+  return strings.Join(parts, "\n\n")
 }
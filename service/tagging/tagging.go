@@ -2,49 +2,300 @@ package tagging
 
 import (
 	"context"
-	openai "github.com/sashabaranov/go-openai"
-	"log"
+	"encoding/json"
+	"fmt"
+	"sort"
 	"strings"
+	"unicode"
+
+	"voice-notetaking-app/service/llm"
 )
 
-// TagText tags text with topics using OpenAI API.
-func TagText(text string) ([]string, error) {
+// Tag is a topic extracted from a transcription, with the model's confidence between 0 and 1.
+type Tag struct {
+	Name       string  `json:"name"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Tagger extracts tags from transcriptions with a language model.
+type Tagger struct {
+	LLM llm.Client
+
+	// MinConfidence drops tags the model is less confident about.
+	MinConfidence float64
+}
+
+// New creates a tagger using the given language model client.
+func New(client llm.Client) *Tagger {
+	return &Tagger{LLM: client}
+}
+
+// tagSchema is the JSON schema the model's answer must follow.
+const tagSchema = `{
+  "type": "object",
+  "properties": {
+    "tags": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "description": "a short topic, one to three words"},
+          "confidence": {"type": "number", "minimum": 0, "maximum": 1}
+        },
+        "required": ["name", "confidence"]
+      }
+    }
+  },
+  "required": ["tags"]
+}`
+
+// TagText tags text with topics, returning normalized tags ordered by confidence.
+func (t *Tagger) TagText(ctx context.Context, text string) ([]Tag, error) {
 	systemPrompt := `
       You are an AI assistant tasked with extracting tags or topics from a transcription. 
       List the relevant tags or topics based on the provided transcription.
-    `
-
-	client := openai.NewClient("your_token_here")
+      Answer with a single JSON object that matches this JSON schema, and nothing else:
+    ` + tagSchema
 
 	// Prepare messages
-	messages := []openai.ChatCompletionMessage{
+	messages := []llm.Message{
 		{
-			Role:    openai.ChatMessageRoleSystem,
+			Role:    llm.RoleSystem,
 			Content: systemPrompt,
 		},
 		{
-			Role:    openai.ChatMessageRoleUser,
+			Role:    llm.RoleUser,
 			Content: text,
 		},
 	}
 
-	// Create Chat completion request
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model:    openai.GPT3Dot5Turbo,
-			Messages: messages,
-		},
-	)
-
+	rawTags, err := t.LLM.Chat(ctx, messages, llm.WithJSON(), llm.WithTemperature(0.2))
 	if err != nil {
-		log.Printf("ChatCompletion error: %v\n", err)
 		return nil, err
 	}
 
 	// Extracting tags from the response
-	rawTags := resp.Choices[0].Message.Content
-	tags := strings.Split(rawTags, "\n")
+	tags, err := ParseTags(rawTags)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := Normalize(tags)
+	filtered := normalized[:0]
+	for _, tag := range normalized {
+		if tag.Confidence >= t.MinConfidence {
+			filtered = append(filtered, tag)
+		}
+	}
+
+	return filtered, nil
+}
+
+// ParseTags parses the model's JSON answer. Answers that are not JSON are read as one tag per
+// line, with no confidence, so a model ignoring the format still yields usable tags.
+func ParseTags(raw string) ([]Tag, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "{") {
+		var response struct {
+			Tags []Tag `json:"tags"`
+		}
+		if err := json.Unmarshal([]byte(raw), &response); err != nil {
+			return nil, fmt.Errorf("failed to parse tags JSON: %v", err)
+		}
+		return response.Tags, nil
+	}
 
+	var tags []Tag
+	for _, line := range strings.Split(raw, "\n") {
+		for _, name := range strings.Split(line, ",") {
+			tags = append(tags, Tag{Name: name})
+		}
+	}
 	return tags, nil
 }
+
+// Normalize normalizes the name of every tag, drops empty ones and merges duplicates,
+// keeping the highest confidence. The result is ordered by confidence, then name.
+func Normalize(tags []Tag) []Tag {
+	merged := make(map[string]float64)
+	for _, tag := range tags {
+		name := NormalizeName(tag.Name)
+		if name == "" {
+			continue
+		}
+		confidence := clamp(tag.Confidence)
+		if existing, ok := merged[name]; !ok || confidence > existing {
+			merged[name] = confidence
+		}
+	}
+
+	normalized := make([]Tag, 0, len(merged))
+	for name, confidence := range merged {
+		normalized = append(normalized, Tag{Name: name, Confidence: confidence})
+	}
+	sort.Slice(normalized, func(i, j int) bool {
+		if normalized[i].Confidence != normalized[j].Confidence {
+			return normalized[i].Confidence > normalized[j].Confidence
+		}
+		return normalized[i].Name < normalized[j].Name
+	})
+
+	return normalized
+}
+
+// NormalizeName folds case, strips list bullets, numbering and surrounding punctuation,
+// collapses whitespace and singularizes the last word, so "- Budgets" and "1. budget" match.
+func NormalizeName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = stripListMarker(name)
+	name = strings.TrimFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return ""
+	}
+	words[len(words)-1] = Singularize(words[len(words)-1])
+
+	return strings.Join(words, " ")
+}
+
+// stripListMarker removes a leading bullet ("-", "*", "•", "+") or number ("1.", "2)", "#3").
+func stripListMarker(name string) string {
+	for {
+		trimmed := strings.TrimLeft(name, "-*•+> \t")
+		if strings.HasPrefix(trimmed, "#") {
+			trimmed = strings.TrimLeftFunc(trimmed[1:], unicode.IsDigit)
+		}
+		digits := strings.TrimLeftFunc(trimmed, unicode.IsDigit)
+		if len(digits) < len(trimmed) && (strings.HasPrefix(digits, ".") || strings.HasPrefix(digits, ")")) {
+			trimmed = digits[1:]
+		}
+		trimmed = strings.TrimSpace(trimmed)
+		if trimmed == name {
+			return name
+		}
+		name = trimmed
+	}
+}
+
+// irregularPlurals maps plurals that do not follow the suffix rules to their singular.
+var irregularPlurals = map[string]string{
+	"people":   "person",
+	"children": "child",
+	"men":      "man",
+	"women":    "woman",
+	"feet":     "foot",
+	"teeth":    "tooth",
+	"mice":     "mouse",
+	"criteria": "criterion",
+	"indices":  "index",
+	"aches":    "ache",
+	"caches":   "cache",
+	"niches":   "niche",
+
+	// Plurals of nouns ending in "ie", which the "ies" rule would turn into "y"
+	"movies":    "movie",
+	"cookies":   "cookie",
+	"calories":  "calorie",
+	"zombies":   "zombie",
+	"selfies":   "selfie",
+	"rookies":   "rookie",
+	"hoodies":   "hoodie",
+	"brownies":  "brownie",
+	"smoothies": "smoothie",
+	"freebies":  "freebie",
+	"lingeries": "lingerie",
+	"prairies":  "prairie",
+
+	// Plurals of nouns ending in "s" or "sis", which the "s" rule would leave ending in "se"
+	"buses":      "bus",
+	"gases":      "gas",
+	"lenses":     "lens",
+	"bonuses":    "bonus",
+	"viruses":    "virus",
+	"campuses":   "campus",
+	"statuses":   "status",
+	"censuses":   "census",
+	"surpluses":  "surplus",
+	"analyses":   "analysis",
+	"crises":     "crisis",
+	"diagnoses":  "diagnosis",
+	"hypotheses": "hypothesis",
+	"theses":     "thesis",
+}
+
+// invariantNouns end in "s" but are the same in the singular, so they are left unchanged.
+var invariantNouns = map[string]bool{
+	"news":         true,
+	"series":       true,
+	"species":      true,
+	"means":        true,
+	"sales":        true,
+	"physics":      true,
+	"mathematics":  true,
+	"economics":    true,
+	"politics":     true,
+	"ethics":       true,
+	"logistics":    true,
+	"analytics":    true,
+	"statistics":   true,
+	"electronics":  true,
+	"athletics":    true,
+	"diabetes":     true,
+	"headquarters": true,
+}
+
+// Singularize returns the singular of an English noun using common suffix rules.
+// Words that are already singular, such as "status" or "analysis", and nouns that are the
+// same in the singular, such as "news" or "series", are left unchanged.
+func Singularize(word string) string {
+	if singular, ok := irregularPlurals[word]; ok {
+		return singular
+	}
+	if invariantNouns[word] {
+		return word
+	}
+
+	switch {
+	case len(word) <= 3:
+		return word
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"),
+		strings.HasSuffix(word, "ches"),
+		strings.HasSuffix(word, "shes"),
+		strings.HasSuffix(word, "xes"),
+		strings.HasSuffix(word, "zzes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ss"),
+		strings.HasSuffix(word, "us"),
+		strings.HasSuffix(word, "is"):
+		return word
+	case strings.HasSuffix(word, "s"):
+		return strings.TrimSuffix(word, "s")
+	}
+
+	return word
+}
+
+// Names returns the names of the tags, in order.
+func Names(tags []Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// clamp limits a confidence to the range 0 to 1.
+func clamp(confidence float64) float64 {
+	if confidence < 0 {
+		return 0
+	}
+	if confidence > 1 {
+		return 1
+	}
+	return confidence
+}
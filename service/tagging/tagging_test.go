@@ -0,0 +1,145 @@
+package tagging
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSingularize(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		// Suffix rules
+		{"budgets", "budget"},
+		{"meetings", "meeting"},
+		{"categories", "category"},
+		{"companies", "company"},
+		{"classes", "class"},
+		{"processes", "process"},
+		{"matches", "match"},
+		{"wishes", "wish"},
+		{"boxes", "box"},
+		{"buzzes", "buzz"},
+		{"pies", "pie"},
+
+		// Already singular
+		{"status", "status"},
+		{"analysis", "analysis"},
+		{"business", "business"},
+		{"bus", "bus"},
+		{"gas", "gas"},
+		{"tax", "tax"},
+
+		// Same in the singular
+		{"news", "news"},
+		{"series", "series"},
+		{"species", "species"},
+		{"sales", "sales"},
+		{"physics", "physics"},
+		{"statistics", "statistics"},
+
+		// Plurals of nouns ending in "ie"
+		{"movies", "movie"},
+		{"cookies", "cookie"},
+		{"calories", "calorie"},
+
+		// Plurals of nouns ending in "s" or "sis"
+		{"buses", "bus"},
+		{"bonuses", "bonus"},
+		{"viruses", "virus"},
+		{"statuses", "status"},
+		{"analyses", "analysis"},
+		{"crises", "crisis"},
+
+		// Plurals ending in "ses" that only lose the "s"
+		{"cases", "case"},
+		{"courses", "course"},
+		{"expenses", "expense"},
+		{"databases", "database"},
+		{"houses", "house"},
+
+		// Irregular
+		{"people", "person"},
+		{"children", "child"},
+		{"indices", "index"},
+		{"caches", "cache"},
+	}
+	for _, tt := range tests {
+		if got := Singularize(tt.word); got != tt.want {
+			t.Errorf("Singularize(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Budget", "budget"},
+		{"- Budgets", "budget"},
+		{"1. budget", "budget"},
+		{"2) Budgets", "budget"},
+		{"#3 Budgets", "budget"},
+		{"* • Budgets", "budget"},
+		{"\"Quarterly   Budgets.\"", "quarterly budget"},
+		{"  Project Deadlines!  ", "project deadline"},
+		{"News", "news"},
+		{"TV Series", "tv series"},
+		{"Movies", "movie"},
+		{"City Buses", "city bus"},
+		{"Q3 OKRs", "q3 okr"},
+		{"2024 Goals", "2024 goal"},
+		{"Café Visits", "café visit"},
+		{"---", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeName(tt.name); got != tt.want {
+			t.Errorf("NormalizeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tags := []Tag{
+		{Name: "Budgets", Confidence: 0.6},
+		{Name: "- budget", Confidence: 0.9},
+		{Name: "News", Confidence: 1.4},
+		{Name: "  ", Confidence: 1},
+		{Name: "Travel", Confidence: -1},
+		{Name: "Hiring", Confidence: 0.9},
+	}
+	want := []Tag{
+		{Name: "news", Confidence: 1},
+		{Name: "budget", Confidence: 0.9},
+		{Name: "hiring", Confidence: 0.9},
+		{Name: "travel", Confidence: 0},
+	}
+	if got := Normalize(tags); !reflect.DeepEqual(got, want) {
+		t.Errorf("Normalize = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tags, err := ParseTags(`{"tags": [{"name": "Budget", "confidence": 0.8}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Tag{{Name: "Budget", Confidence: 0.8}}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("ParseTags of JSON = %+v, want %+v", tags, want)
+	}
+
+	tags, err = ParseTags("budget, travel\nhiring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"budget", " travel", "hiring"}; !reflect.DeepEqual(Names(tags), want) {
+		t.Errorf("ParseTags of lines = %q, want %q", Names(tags), want)
+	}
+
+	if _, err := ParseTags(`{"tags": [`); err == nil {
+		t.Error("ParseTags accepted malformed JSON")
+	}
+}
@@ -0,0 +1,365 @@
+package vectorindex
+
+import (
+  "container/heap"
+  "math"
+  "math/rand"
+  "sync"
+)
+
+// HNSW parameters used when NewHNSW is given zero values.
+const (
+  DefaultM              = 16
+  DefaultEfConstruction = 200
+  DefaultEfSearch       = 64
+)
+
+// HNSW is an approximate index based on Hierarchical Navigable Small World graphs
+// (Malkov & Yashunin, 2016). Each vector is linked to its nearest neighbours on a random
+// number of layers; searches descend greedily from the sparse top layer and explore the
+// dense bottom layer, visiting only a small part of the index.
+//
+// Removed vectors stay in the graph as tombstones so that it remains connected; searches
+// still pass through them but never return them.
+type HNSW struct {
+  mu sync.RWMutex
+
+  m              int
+  mMax0          int
+  efConstruction int
+  efSearch       int
+  levelMult      float64
+  rng            *rand.Rand
+
+  dimensions int
+  nodes      []*hnswNode
+  ids        map[int64]int
+  entry      int
+  maxLevel   int
+  live       int
+}
+
+// hnswNode is a vector in the graph with its neighbours on each of its layers.
+type hnswNode struct {
+  id        int64
+  vector    []float32
+  neighbors [][]int
+  deleted   bool
+}
+
+// NewHNSW creates an empty approximate index. m is the number of neighbours per node on the
+// upper layers (twice that on the bottom one), efConstruction and efSearch the size of the
+// candidate lists while inserting and searching; larger values are slower but more accurate.
+func NewHNSW(m, efConstruction, efSearch int) *HNSW {
+  if m < 2 {
+    m = DefaultM
+  }
+  if efConstruction < 1 {
+    efConstruction = DefaultEfConstruction
+  }
+  if efSearch < 1 {
+    efSearch = DefaultEfSearch
+  }
+  return &HNSW{
+    m:              m,
+    mMax0:          2 * m,
+    efConstruction: efConstruction,
+    efSearch:       efSearch,
+    levelMult:      1 / math.Log(float64(m)),
+    rng:            rand.New(rand.NewSource(1)),
+    ids:            make(map[int64]int),
+    entry:          -1,
+  }
+}
+
+// Add inserts the vector under the ID. A vector already stored under the ID is removed first.
+func (h *HNSW) Add(id int64, vector []float32) error {
+  h.mu.Lock()
+  defer h.mu.Unlock()
+
+  if err := checkDimensions(&h.dimensions, vector); err != nil {
+    return err
+  }
+  h.remove(id)
+
+  level := int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+  node := &hnswNode{
+    id:        id,
+    vector:    vector,
+    neighbors: make([][]int, level+1),
+  }
+  index := len(h.nodes)
+  h.nodes = append(h.nodes, node)
+  h.ids[id] = index
+  h.live++
+
+  if h.entry < 0 {
+    h.entry, h.maxLevel = index, level
+    return nil
+  }
+
+  // Descend greedily through the layers above the new node's top layer
+  current := h.entry
+  for layer := h.maxLevel; layer > level; layer-- {
+    current = h.greedy(vector, current, layer)
+  }
+
+  // Link the node to its nearest neighbours on each of its layers
+  entries := []int{current}
+  top := level
+  if h.maxLevel < top {
+    top = h.maxLevel
+  }
+  for layer := top; layer >= 0; layer-- {
+    candidates := h.searchLayer(vector, entries, h.efConstruction, layer, false)
+    neighbors := h.selectNeighbors(candidates, h.m)
+    node.neighbors[layer] = neighbors
+
+    for _, neighbor := range neighbors {
+      h.link(neighbor, index, layer)
+    }
+
+    entries = entries[:0]
+    for _, candidate := range candidates {
+      entries = append(entries, candidate.node)
+    }
+  }
+
+  if level > h.maxLevel {
+    h.entry, h.maxLevel = index, level
+  }
+  return nil
+}
+
+// Remove marks the vector stored under the ID as deleted, if any.
+func (h *HNSW) Remove(id int64) {
+  h.mu.Lock()
+  defer h.mu.Unlock()
+
+  h.remove(id)
+}
+
+// Search returns up to k approximate nearest neighbours of the query.
+func (h *HNSW) Search(query []float32, k int) []Match {
+  h.mu.RLock()
+  defer h.mu.RUnlock()
+
+  if k < 1 || h.entry < 0 || len(query) != h.dimensions {
+    return nil
+  }
+
+  current := h.entry
+  for layer := h.maxLevel; layer > 0; layer-- {
+    current = h.greedy(query, current, layer)
+  }
+
+  ef := h.efSearch
+  if ef < k {
+    ef = k
+  }
+
+  candidates := h.searchLayer(query, []int{current}, ef, 0, true)
+  matches := make([]Match, 0, k)
+  for _, candidate := range candidates {
+    matches = append(matches, Match{ID: h.nodes[candidate.node].id, Score: candidate.score})
+  }
+  sortMatches(matches)
+  if len(matches) > k {
+    matches = matches[:k]
+  }
+  return matches
+}
+
+// Len returns the number of vectors that have not been removed.
+func (h *HNSW) Len() int {
+  h.mu.RLock()
+  defer h.mu.RUnlock()
+
+  return h.live
+}
+
+// remove marks the node of the ID as deleted. The caller must hold the write lock.
+func (h *HNSW) remove(id int64) {
+  index, ok := h.ids[id]
+  if !ok {
+    return
+  }
+  h.nodes[index].deleted = true
+  delete(h.ids, id)
+  h.live--
+}
+
+// greedy follows the most similar neighbour on the layer until no neighbour is closer.
+func (h *HNSW) greedy(query []float32, current, layer int) int {
+  best := dot(query, h.nodes[current].vector)
+  for changed := true; changed; {
+    changed = false
+    for _, neighbor := range h.neighbors(current, layer) {
+      if score := dot(query, h.nodes[neighbor].vector); score > best {
+        best, current, changed = score, neighbor, true
+      }
+    }
+  }
+  return current
+}
+
+// searchLayer returns up to ef nodes of the layer most similar to the query, most similar first,
+// exploring outwards from the entry nodes. With skipDeleted set, removed nodes are explored but
+// left out of the results.
+func (h *HNSW) searchLayer(query []float32, entries []int, ef, layer int, skipDeleted bool) []candidate {
+  visited := make(map[int]bool, ef*4)
+  candidates := &candidateHeap{max: true}
+  results := &candidateHeap{}
+
+  for _, entry := range entries {
+    if visited[entry] {
+      continue
+    }
+    visited[entry] = true
+    c := candidate{node: entry, score: dot(query, h.nodes[entry].vector)}
+    heap.Push(candidates, c)
+    if !skipDeleted || !h.nodes[entry].deleted {
+      heap.Push(results, c)
+    }
+  }
+  for results.Len() > ef {
+    heap.Pop(results)
+  }
+
+  for candidates.Len() > 0 {
+    closest := heap.Pop(candidates).(candidate)
+    if results.Len() >= ef && closest.score < results.items[0].score {
+      break
+    }
+
+    for _, neighbor := range h.neighbors(closest.node, layer) {
+      if visited[neighbor] {
+        continue
+      }
+      visited[neighbor] = true
+
+      score := dot(query, h.nodes[neighbor].vector)
+      if results.Len() < ef || score > results.items[0].score {
+        c := candidate{node: neighbor, score: score}
+        heap.Push(candidates, c)
+        if skipDeleted && h.nodes[neighbor].deleted {
+          continue
+        }
+        heap.Push(results, c)
+        if results.Len() > ef {
+          heap.Pop(results)
+        }
+      }
+    }
+  }
+
+  // Pop the worst first to build the list from the back
+  found := make([]candidate, results.Len())
+  for i := len(found) - 1; i >= 0; i-- {
+    found[i] = heap.Pop(results).(candidate)
+  }
+  return found
+}
+
+// selectNeighbors picks up to m neighbours from candidates sorted most similar first, preferring
+// candidates that are closer to the new node than to any neighbour already picked, which keeps
+// links spread out across clusters. Remaining slots are filled with the closest candidates.
+func (h *HNSW) selectNeighbors(candidates []candidate, m int) []int {
+  selected := make([]int, 0, m)
+  skipped := make([]int, 0, len(candidates))
+  for _, c := range candidates {
+    if len(selected) >= m {
+      break
+    }
+    diverse := true
+    for _, s := range selected {
+      if dot(h.nodes[c.node].vector, h.nodes[s].vector) > c.score {
+        diverse = false
+        break
+      }
+    }
+    if diverse {
+      selected = append(selected, c.node)
+    } else {
+      skipped = append(skipped, c.node)
+    }
+  }
+  for _, node := range skipped {
+    if len(selected) >= m {
+      break
+    }
+    selected = append(selected, node)
+  }
+  return selected
+}
+
+// link adds a connection from node to target on the layer, pruning the node's neighbours back
+// to the layer's maximum when it has too many.
+func (h *HNSW) link(node, target, layer int) {
+  n := h.nodes[node]
+  n.neighbors[layer] = append(n.neighbors[layer], target)
+
+  limit := h.m
+  if layer == 0 {
+    limit = h.mMax0
+  }
+  if len(n.neighbors[layer]) <= limit {
+    return
+  }
+
+  candidates := make([]candidate, 0, len(n.neighbors[layer]))
+  for _, neighbor := range n.neighbors[layer] {
+    candidates = append(candidates, candidate{node: neighbor, score: dot(n.vector, h.nodes[neighbor].vector)})
+  }
+  sortCandidates(candidates)
+  n.neighbors[layer] = h.selectNeighbors(candidates, limit)
+}
+
+// neighbors returns the neighbours of the node on the layer, or none if the node is not on it.
+func (h *HNSW) neighbors(node, layer int) []int {
+  n := h.nodes[node]
+  if layer >= len(n.neighbors) {
+    return nil
+  }
+  return n.neighbors[layer]
+}
+
+// candidate is a node of the graph with its similarity to the query being searched.
+type candidate struct {
+  node  int
+  score float32
+}
+
+// sortCandidates orders candidates most similar first.
+func sortCandidates(candidates []candidate) {
+  for i := 1; i < len(candidates); i++ {
+    for j := i; j > 0 && candidates[j].score > candidates[j-1].score; j-- {
+      candidates[j], candidates[j-1] = candidates[j-1], candidates[j]
+    }
+  }
+}
+
+// candidateHeap is a heap of candidates, with the least similar on top unless max is set.
+type candidateHeap struct {
+  items []candidate
+  max   bool
+}
+
+func (c *candidateHeap) Len() int { return len(c.items) }
+
+func (c *candidateHeap) Less(i, j int) bool {
+  if c.max {
+    return c.items[i].score > c.items[j].score
+  }
+  return c.items[i].score < c.items[j].score
+}
+
+func (c *candidateHeap) Swap(i, j int) { c.items[i], c.items[j] = c.items[j], c.items[i] }
+
+func (c *candidateHeap) Push(x interface{}) { c.items = append(c.items, x.(candidate)) }
+
+func (c *candidateHeap) Pop() interface{} {
+  last := c.items[len(c.items)-1]
+  c.items = c.items[:len(c.items)-1]
+  return last
+}
@@ -0,0 +1,174 @@
+package vectorindex
+
+import (
+  "math"
+  "math/rand"
+  "testing"
+)
+
+// topics returns the centers of 20 random topics.
+func topics(random *rand.Rand, dimensions int) [][]float32 {
+  centers := make([][]float32, 20)
+  for i := range centers {
+    centers[i] = randomUnit(random, nil, dimensions, 1)
+  }
+  return centers
+}
+
+// randomVectors returns n random unit vectors scattered by spread around the topics, like
+// embeddings of notes. The larger the spread, the closer to uniform and harder to index they are.
+func randomVectors(random *rand.Rand, centers [][]float32, n int, spread float64) [][]float32 {
+  vectors := make([][]float32, n)
+  for i := range vectors {
+    vectors[i] = randomUnit(random, centers[random.Intn(len(centers))], len(centers[0]), spread)
+  }
+  return vectors
+}
+
+// randomUnit returns a unit vector in a random direction from center, scattered by spread.
+func randomUnit(random *rand.Rand, center []float32, dimensions int, spread float64) []float32 {
+  vector := make([]float32, dimensions)
+  var norm float64
+  for i := range vector {
+    value := random.NormFloat64() * spread
+    if center != nil {
+      value += float64(center[i])
+    }
+    vector[i] = float32(value)
+    norm += value * value
+  }
+  norm = math.Sqrt(norm)
+  for i := range vector {
+    vector[i] /= float32(norm)
+  }
+  return vector
+}
+
+// recall returns the share of the exact top k results the approximate index also returned.
+func recall(t *testing.T, exact, approximate Index, queries [][]float32, k int, removed map[int64]bool) float64 {
+  t.Helper()
+  found, total := 0, 0
+  for _, query := range queries {
+    want := make(map[int64]bool)
+    for _, match := range exact.Search(query, k) {
+      want[match.ID] = true
+    }
+    matches := approximate.Search(query, k)
+    if len(matches) != k {
+      t.Fatalf("got %d matches, want %d", len(matches), k)
+    }
+    for i, match := range matches {
+      if removed[match.ID] {
+        t.Fatalf("search returned removed vector %d", match.ID)
+      }
+      if i > 0 && match.Score > matches[i-1].Score {
+        t.Fatalf("matches are not ordered by score: %v", matches)
+      }
+      if want[match.ID] {
+        found++
+      }
+    }
+    total += len(want)
+  }
+  return float64(found) / float64(total)
+}
+
+func TestHNSWRecall(t *testing.T) {
+  const (
+    n          = 5000
+    dimensions = 64
+    k          = 10
+  )
+  tests := []struct {
+    name      string
+    spread    float64
+    efSearch  int
+    minRecall float64
+  }{
+    {"topics", 0.2, DefaultEfSearch, 0.97},
+    {"near uniform", 0.6, 200, 0.98},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      random := rand.New(rand.NewSource(7))
+      centers := topics(random, dimensions)
+      vectors := randomVectors(random, centers, n, tt.spread)
+      queries := randomVectors(random, centers, 200, tt.spread)
+
+      exact := NewBruteForce()
+      approximate := NewHNSW(0, 0, tt.efSearch)
+      for i, vector := range vectors {
+        if err := exact.Add(int64(i+1), vector); err != nil {
+          t.Fatal(err)
+        }
+        if err := approximate.Add(int64(i+1), vector); err != nil {
+          t.Fatal(err)
+        }
+      }
+      if approximate.Len() != n {
+        t.Fatalf("Len = %d, want %d", approximate.Len(), n)
+      }
+      if r := recall(t, exact, approximate, queries, k, nil); r < tt.minRecall {
+        t.Errorf("recall@%d = %.3f, want at least %.2f", k, r, tt.minRecall)
+      }
+
+      // Removing a fifth of the vectors leaves tombstones that are never returned
+      removed := make(map[int64]bool)
+      for id := int64(1); id <= n; id += 5 {
+        exact.Remove(id)
+        approximate.Remove(id)
+        removed[id] = true
+      }
+      if approximate.Len() != n-len(removed) {
+        t.Fatalf("Len after removing = %d, want %d", approximate.Len(), n-len(removed))
+      }
+      if r := recall(t, exact, approximate, queries, k, removed); r < tt.minRecall {
+        t.Errorf("recall@%d after removing = %.3f, want at least %.2f", k, r, tt.minRecall)
+      }
+    })
+  }
+}
+
+func TestHNSWReplace(t *testing.T) {
+  index := NewHNSW(4, 20, 20)
+  random := rand.New(rand.NewSource(1))
+  for i, vector := range randomVectors(random, topics(random, 8), 100, 0.5) {
+    if err := index.Add(int64(i+1), vector); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  query := randomUnit(random, nil, 8, 1)
+  if err := index.Add(50, query); err != nil {
+    t.Fatal(err)
+  }
+  if index.Len() != 100 {
+    t.Errorf("Len after replacing = %d, want 100", index.Len())
+  }
+  matches := index.Search(query, 3)
+  if len(matches) == 0 || matches[0].ID != 50 || math.Abs(float64(matches[0].Score)-1) > 1e-5 {
+    t.Errorf("Search for the replaced vector = %v, want ID 50 first with score 1", matches)
+  }
+}
+
+func TestHNSWDimensions(t *testing.T) {
+  index := NewHNSW(0, 0, 0)
+  if matches := index.Search([]float32{1, 0}, 5); matches != nil {
+    t.Errorf("Search of an empty index = %v, want nil", matches)
+  }
+  if err := index.Add(1, nil); err == nil {
+    t.Error("Add accepted an empty vector")
+  }
+  if err := index.Add(1, []float32{1, 0}); err != nil {
+    t.Fatal(err)
+  }
+  if err := index.Add(2, []float32{0, 0, 1}); err == nil {
+    t.Error("Add accepted a vector of another size")
+  }
+  if matches := index.Search([]float32{0, 0, 1}, 5); matches != nil {
+    t.Errorf("Search with a query of another size = %v, want nil", matches)
+  }
+  if matches := index.Search([]float32{1, 0}, 5); len(matches) != 1 || matches[0].ID != 1 {
+    t.Errorf("Search = %v, want only vector 1", matches)
+  }
+}
@@ -0,0 +1,120 @@
+package vectorindex
+
+import (
+  "fmt"
+  "sort"
+  "sync"
+)
+
+// Match is a vector found by a search with its cosine similarity to the query.
+type Match struct {
+  ID    int64
+  Score float32
+}
+
+// Index finds the vectors most similar to a query. Vectors must be normalized to unit
+// length, so their dot product is their cosine similarity. Implementations are safe for
+// concurrent use.
+type Index interface {
+  // Add stores the vector under the ID, replacing any vector already stored under it.
+  Add(id int64, vector []float32) error
+  Remove(id int64)
+
+  // Search returns up to k matches, most similar first.
+  Search(query []float32, k int) []Match
+  Len() int
+}
+
+// BruteForce is an exact index comparing the query with every stored vector.
+type BruteForce struct {
+  mu         sync.RWMutex
+  dimensions int
+  vectors    map[int64][]float32
+}
+
+// NewBruteForce creates an empty exact index.
+func NewBruteForce() *BruteForce {
+  return &BruteForce{vectors: make(map[int64][]float32)}
+}
+
+// Add stores the vector under the ID.
+func (b *BruteForce) Add(id int64, vector []float32) error {
+  b.mu.Lock()
+  defer b.mu.Unlock()
+
+  if err := checkDimensions(&b.dimensions, vector); err != nil {
+    return err
+  }
+  b.vectors[id] = vector
+  return nil
+}
+
+// Remove deletes the vector stored under the ID, if any.
+func (b *BruteForce) Remove(id int64) {
+  b.mu.Lock()
+  defer b.mu.Unlock()
+
+  delete(b.vectors, id)
+}
+
+// Search scores every stored vector against the query and returns the best k.
+func (b *BruteForce) Search(query []float32, k int) []Match {
+  b.mu.RLock()
+  defer b.mu.RUnlock()
+
+  if k < 1 || len(query) != b.dimensions {
+    return nil
+  }
+
+  matches := make([]Match, 0, len(b.vectors))
+  for id, vector := range b.vectors {
+    matches = append(matches, Match{ID: id, Score: dot(query, vector)})
+  }
+  sortMatches(matches)
+  if len(matches) > k {
+    matches = matches[:k]
+  }
+  return matches
+}
+
+// Len returns the number of stored vectors.
+func (b *BruteForce) Len() int {
+  b.mu.RLock()
+  defer b.mu.RUnlock()
+
+  return len(b.vectors)
+}
+
+// checkDimensions records the vector size of the first vector added to an index and
+// rejects vectors of any other size afterwards.
+func checkDimensions(dimensions *int, vector []float32) error {
+  if len(vector) == 0 {
+    return fmt.Errorf("vector is empty")
+  }
+  if *dimensions == 0 {
+    *dimensions = len(vector)
+  }
+  if len(vector) != *dimensions {
+    return fmt.Errorf("vector has %d dimensions, index has %d", len(vector), *dimensions)
+  }
+  return nil
+}
+
+// sortMatches orders matches by descending score, breaking ties by ID for stable results.
+func sortMatches(matches []Match) {
+  sort.Slice(matches, func(i, j int) bool {
+    if matches[i].Score != matches[j].Score {
+      return matches[i].Score > matches[j].Score
+    }
+    return matches[i].ID < matches[j].ID
+  })
+}
+
+// dot returns the dot product of two vectors of the same length.
+func dot(a, b []float32) float32 {
+  var sum float32
+  for i := range a {
+    sum += a[i] * b[i]
+  }
+  return sum
+}